@@ -6,14 +6,17 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/jonadableite/turbozap-api/internal/infrastructure/database"
 	"github.com/jonadableite/turbozap-api/internal/infrastructure/repository"
+	"github.com/jonadableite/turbozap-api/internal/infrastructure/storage"
 	"github.com/jonadableite/turbozap-api/internal/infrastructure/webhook"
 	"github.com/jonadableite/turbozap-api/internal/infrastructure/whatsapp"
 	"github.com/jonadableite/turbozap-api/internal/interface/http"
 	"github.com/jonadableite/turbozap-api/pkg/config"
 	"github.com/jonadableite/turbozap-api/pkg/logger"
+	"go.uber.org/zap"
 )
 
 func main() {
@@ -63,12 +66,48 @@ func main() {
 	// Initialize webhook dispatcher
 	webhookDispatcher := webhook.NewDispatcher(cfg.Webhook, logrusLogger)
 	webhookDispatcher.SetWebhookRepository(webhookRepo)
+	webhookDispatcher.SetOutboxRepository(repository.NewWebhookOutboxPostgresRepository(db))
+	webhookDispatcher.SetDeliveryLogRepository(repository.NewWebhookDeliveryLogPostgresRepository(db))
 
 	// Initialize message repository
 	messageRepo := repository.NewMessagePostgresRepository(db)
 
 	// Initialize WhatsApp manager
 	waManager := whatsapp.NewManager(cfg, db, logrusLogger, webhookDispatcher, instanceRepo, messageRepo)
+	waManager.SetChatRepositories(repository.NewChatPostgresRepository(db), repository.NewContactPostgresRepository(db))
+	waManager.SetPollRepository(repository.NewPollPostgresRepository(db))
+	waManager.SetConnectionEventRepository(repository.NewConnectionEventPostgresRepository(db))
+
+	// Initialize media storage (optional)
+	if cfg.MinIO.Enabled {
+		zapLogger, err := zap.NewProduction()
+		if err != nil {
+			zapLogger = zap.NewNop()
+		}
+		mediaStorage, err := storage.NewClient(storage.Config{
+			Endpoint:        cfg.MinIO.Endpoint,
+			AccessKeyID:     cfg.MinIO.AccessKeyID,
+			SecretAccessKey: cfg.MinIO.SecretAccessKey,
+			BucketName:      cfg.MinIO.BucketName,
+			UseSSL:          cfg.MinIO.UseSSL,
+			PublicURL:       cfg.MinIO.PublicURL,
+		}, zapLogger)
+		if err != nil {
+			appLogger.Warn("Failed to connect to MinIO, incoming media will not be stored", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			waManager.SetMediaStorage(mediaStorage)
+		}
+	}
+
+	// Share instances with the other API nodes
+	if cfg.Cluster.Enabled {
+		waManager.SetLeaseRepository(repository.NewLeasePostgresRepository(db), cfg.Cluster.NodeID, cfg.Cluster.AdvertiseURL, time.Duration(cfg.Cluster.LeaseTTL)*time.Second)
+		appLogger.Info("Cluster mode enabled", map[string]interface{}{
+			"node": cfg.Cluster.NodeID,
+		})
+	}
 
 	// Restore existing instances and auto-reconnect
 	ctx := context.Background()
@@ -79,6 +118,13 @@ func main() {
 		})
 	}
 
+	// Watch connected instances for dead sockets, keep the instance leases alive and deliver the webhook outbox
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	defer stopBackground()
+	waManager.StartWatchdog(backgroundCtx)
+	waManager.StartLeaseRenewal(backgroundCtx)
+	webhookDispatcher.Start(backgroundCtx)
+
 	// Initialize HTTP router
 	router := http.NewRouter(cfg, logrusLogger, db, instanceRepo, webhookRepo, waManager)
 
@@ -104,6 +150,7 @@ func main() {
 	appLogger.Info("Shutting down TurboZap API...")
 
 	// Graceful shutdown
+	stopBackground()
 	waManager.DisconnectAll()
 	router.Shutdown()
 
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jonadableite/turbozap-api/internal/infrastructure/whatsapp"
+	"github.com/jonadableite/turbozap-api/pkg/config"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// Copia os dispositivos do WhatsApp (sessões, chaves e app-state) de um device store para outro.
+// Por padrão copia do banco principal (DATABASE_URL) para o store configurado em
+// WHATSAPP_STORE_DRIVER / WHATSAPP_STORE_URL.
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("❌ Erro ao carregar configuração: %v\n", err)
+		os.Exit(1)
+	}
+
+	fromDriver := flag.String("from-driver", whatsapp.DeviceStorePostgres, "backend de origem: postgres ou sqlite")
+	from := flag.String("from", cfg.Database.URL, "URL do Postgres ou arquivo SQLite de origem")
+	toDriver := flag.String("to-driver", cfg.WhatsApp.StoreDriver, "backend de destino: postgres ou sqlite")
+	to := flag.String("to", cfg.WhatsApp.StoreURL, "URL do Postgres ou arquivo SQLite de destino")
+	flag.Parse()
+
+	if *from == "" || *to == "" {
+		fmt.Println("❌ Informe a origem (-from) e o destino (-to)")
+		os.Exit(1)
+	}
+	if *fromDriver == *toDriver && *from == *to {
+		fmt.Println("❌ Origem e destino são o mesmo device store")
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	logger := waLog.Stdout("store", "WARN", true)
+
+	src, _, err := whatsapp.OpenDeviceStore(ctx, *fromDriver, *from, logger)
+	if err != nil {
+		fmt.Printf("❌ Erro ao abrir origem: %v\n", err)
+		os.Exit(1)
+	}
+	defer src.Close()
+
+	dst, _, err := whatsapp.OpenDeviceStore(ctx, *toDriver, *to, logger)
+	if err != nil {
+		fmt.Printf("❌ Erro ao abrir destino: %v\n", err)
+		os.Exit(1)
+	}
+	defer dst.Close()
+
+	fmt.Printf("🚀 Copiando dispositivos (%s → %s)...\n", *fromDriver, *toDriver)
+
+	copied, err := whatsapp.CopyDevices(ctx, src, dst)
+	if err != nil {
+		fmt.Printf("❌ Erro após copiar %d dispositivo(s): %v\n", copied, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ %d dispositivo(s) copiado(s)\n", copied)
+	fmt.Println("💡 Reinicie a API com WHATSAPP_STORE_DRIVER / WHATSAPP_STORE_URL apontando para o destino")
+}
@@ -2,14 +2,22 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"time"
+
+	"github.com/jonadableite/turbozap-api/internal/infrastructure/webhook"
 )
 
 func main() {
+	secret := flag.String("secret", os.Getenv("WEBHOOK_SECRET"), "segredo do webhook para verificar as assinaturas (vazio desativa a verificação)")
+	tolerance := flag.Duration("tolerance", webhook.DefaultSignatureTolerance, "idade máxima aceita do timestamp da assinatura")
+	flag.Parse()
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -29,6 +37,17 @@ func main() {
 		fmt.Printf("Timestamp: %s\n", time.Now().Format(time.RFC3339))
 		fmt.Printf("URL: %s\n", r.URL.String())
 
+		if *secret != "" {
+			err := webhook.VerifySignature(*secret, r.Header.Get(webhook.SignatureHeader), r.Header.Get(webhook.TimestampHeader), body, *tolerance, time.Now())
+			if err != nil {
+				fmt.Printf("❌ Assinatura inválida: %v\n", err)
+				fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Println("🔐 Assinatura válida")
+		}
+
 		// Tentar formatar o JSON bonito
 		var prettyJSON map[string]interface{}
 		if err := json.Unmarshal(body, &prettyJSON); err == nil {
@@ -46,6 +65,9 @@ func main() {
 
 	port := ":3001"
 	fmt.Printf("✅ Servidor de Webhook (Go) rodando na porta %s\n", port)
+	if *secret != "" {
+		fmt.Println("   Verificando assinaturas X-TurboZap-Signature")
+	}
 	fmt.Println("   Aguardando requisições...")
 
 	if err := http.ListenAndServe(port, nil); err != nil {
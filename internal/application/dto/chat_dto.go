@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+// ChatMessagesQuery represents the query string of a chat history request
+type ChatMessagesQuery struct {
+	Limit  int    `query:"limit"`
+	Before string `query:"before"`  // Message ID or timestamp, returns older messages
+	After  string `query:"after"`   // Message ID or timestamp, returns newer messages
+	Type   string `query:"type"`    // Comma separated message types
+	FromMe string `query:"from_me"` // "true" or "false"
+	Since  string `query:"since"`   // RFC3339 or unix timestamp
+	Until  string `query:"until"`   // RFC3339 or unix timestamp
+}
+
+// ChatMessagesResponse represents a page of the message history of a chat, newest first
+type ChatMessagesResponse struct {
+	JID          string            `json:"jid"`
+	Messages     []*entity.Message `json:"messages"`
+	Count        int               `json:"count"`
+	HasMore      bool              `json:"has_more"`                // More messages exist in the requested direction
+	BeforeCursor string            `json:"before_cursor,omitempty"` // Pass as "before" to load older messages
+	AfterCursor  string            `json:"after_cursor,omitempty"`  // Pass as "after" to load newer messages
+}
+
+// ListChatsQuery represents the query string of a chat list request
+type ListChatsQuery struct {
+	Limit    int    `query:"limit"`
+	Offset   int    `query:"offset"`
+	Sort     string `query:"sort"`     // last_message (default), unread or name
+	Order    string `query:"order"`    // asc or desc
+	Archived string `query:"archived"` // "true" or "false", both when empty
+	Pinned   string `query:"pinned"`   // "true" or "false", both when empty
+	Unread   string `query:"unread"`   // "true" to list only chats with unread messages
+}
+
+// ChatResponse represents a chat of the chat list
+type ChatResponse struct {
+	*entity.Chat
+	Muted bool `json:"muted"`
+}
+
+// ListChatsResponse represents a page of the chat list
+type ListChatsResponse struct {
+	Chats  []ChatResponse `json:"chats"`
+	Total  int64          `json:"total"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+// ToChatResponse converts an entity to DTO
+func ToChatResponse(chat *entity.Chat) ChatResponse {
+	return ChatResponse{
+		Chat:  chat,
+		Muted: chat.IsMuted(),
+	}
+}
+
+// MuteChatRequest represents the request to mute a chat
+type MuteChatRequest struct {
+	Duration int64 `json:"duration"` // Seconds, mutes forever when zero
+}
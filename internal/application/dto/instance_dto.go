@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,7 +10,8 @@ import (
 
 // CreateInstanceRequest represents the request to create a new instance
 type CreateInstanceRequest struct {
-	Name string `json:"name" validate:"required,min=1,max=100"`
+	Name     string                         `json:"name" validate:"required,min=1,max=100"`
+	Settings *UpdateInstanceSettingsRequest `json:"settings,omitempty"` // Optional initial settings
 }
 
 // CreateInstanceResponse represents the response after creating an instance
@@ -38,6 +40,10 @@ type InstanceStatusResponse struct {
 	PhoneNumber string `json:"phone_number,omitempty"`
 	ProfileName string `json:"profile_name,omitempty"`
 	ProfilePic  string `json:"profile_pic,omitempty"`
+
+	ReconnectAttempt     int        `json:"reconnect_attempt,omitempty"`      // Number of the next reconnection attempt
+	ReconnectMaxAttempts int        `json:"reconnect_max_attempts,omitempty"` // Attempts before giving up (0 = unlimited)
+	NextReconnectAt      *time.Time `json:"next_reconnect_at,omitempty"`      // When the next reconnection attempt happens
 }
 
 // QRCodeResponse represents the QR code response
@@ -48,6 +54,119 @@ type QRCodeResponse struct {
 	Code   string `json:"code,omitempty"`    // Raw QR code string
 }
 
+// PairCodeRequest represents a request to pair an instance by phone number
+type PairCodeRequest struct {
+	Phone string `json:"phone" validate:"required"` // Phone number in international format
+}
+
+// PairCodeResponse represents the pairing code to type on the phone
+type PairCodeResponse struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	PairingCode string `json:"pairing_code,omitempty"`
+}
+
+// SetProxyRequest represents a request to set the proxy of an instance
+type SetProxyRequest struct {
+	ProxyURL string `json:"proxy_url" validate:"required"` // http://, https:// or socks5:// URL, credentials allowed
+}
+
+// TestProxyRequest represents a request to test a proxy, the stored proxy is used when empty
+type TestProxyRequest struct {
+	ProxyURL string `json:"proxy_url,omitempty"`
+}
+
+// ProxyResponse represents the proxy of an instance, with the password redacted
+type ProxyResponse struct {
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`
+	ProxyURL string `json:"proxy_url,omitempty"`
+}
+
+// TestProxyResponse represents the result of a proxy test
+type TestProxyResponse struct {
+	ProxyURL  string `json:"proxy_url"`
+	Reachable bool   `json:"reachable"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ExportSessionRequest represents a request to export the session of an instance
+type ExportSessionRequest struct {
+	Passphrase string `json:"passphrase" validate:"required"` // Encrypts the bundle, required again to import it
+}
+
+// ExportSessionResponse represents an exported session
+type ExportSessionResponse struct {
+	Name      string `json:"name"`
+	DeviceJID string `json:"device_jid"`
+	Bundle    []byte `json:"bundle"` // Encrypted bundle, base64 encoded
+}
+
+// ImportSessionRequest represents a request to restore an exported session on an instance
+type ImportSessionRequest struct {
+	Bundle     []byte `json:"bundle" validate:"required"` // Bundle returned by the export, base64 encoded
+	Passphrase string `json:"passphrase" validate:"required"`
+}
+
+// ImportSessionResponse represents an imported session
+type ImportSessionResponse struct {
+	Name      string `json:"name"`
+	DeviceJID string `json:"device_jid"`
+	Status    string `json:"status"`
+}
+
+// InstanceSettingsResponse represents the settings of an instance
+type InstanceSettingsResponse struct {
+	Name     string                  `json:"name"`
+	Settings entity.InstanceSettings `json:"settings"`
+}
+
+// UpdateInstanceSettingsRequest represents a partial update of instance settings
+type UpdateInstanceSettingsRequest struct {
+	StoreMedia        *bool   `json:"store_media,omitempty"`
+	HistorySyncDays   *int    `json:"history_sync_days,omitempty"`
+	RejectCalls       *bool   `json:"reject_calls,omitempty"`
+	RejectCallMessage *string `json:"reject_call_message,omitempty"`
+	IgnoreGroups      *bool   `json:"ignore_groups,omitempty"`
+	AlwaysOnline      *bool   `json:"always_online,omitempty"`
+	ReadMessages      *bool   `json:"read_messages,omitempty"`
+	IgnoreStatus      *bool   `json:"ignore_status,omitempty"`
+	SyncFullHistory   *bool   `json:"sync_full_history,omitempty"`
+}
+
+// Apply merges the provided fields into the given settings
+func (r UpdateInstanceSettingsRequest) Apply(settings entity.InstanceSettings) entity.InstanceSettings {
+	if r.StoreMedia != nil {
+		settings.StoreMedia = *r.StoreMedia
+	}
+	if r.HistorySyncDays != nil {
+		settings.HistorySyncDays = *r.HistorySyncDays
+	}
+	if r.RejectCalls != nil {
+		settings.RejectCalls = *r.RejectCalls
+	}
+	if r.RejectCallMessage != nil {
+		settings.RejectCallMessage = *r.RejectCallMessage
+	}
+	if r.IgnoreGroups != nil {
+		settings.IgnoreGroups = *r.IgnoreGroups
+	}
+	if r.AlwaysOnline != nil {
+		settings.AlwaysOnline = *r.AlwaysOnline
+	}
+	if r.ReadMessages != nil {
+		settings.ReadMessages = *r.ReadMessages
+	}
+	if r.IgnoreStatus != nil {
+		settings.IgnoreStatus = *r.IgnoreStatus
+	}
+	if r.SyncFullHistory != nil {
+		settings.SyncFullHistory = *r.SyncFullHistory
+	}
+	return settings
+}
+
 // ListInstancesResponse represents the list of instances response
 type ListInstancesResponse struct {
 	Instances []InstanceResponse `json:"instances"`
@@ -99,3 +218,70 @@ func ToListInstancesResponse(instances []*entity.Instance) ListInstancesResponse
 		Total:     len(instances),
 	}
 }
+
+// ConnectionHistoryQuery represents the query parameters of the connection history endpoint
+type ConnectionHistoryQuery struct {
+	Since string `query:"since"` // Unix timestamp or RFC3339, defaults to 24 hours before until
+	Until string `query:"until"` // Unix timestamp or RFC3339, defaults to now
+}
+
+// ConnectionHistoryResponse represents the connection timeline of an instance over a window
+type ConnectionHistoryResponse struct {
+	Name             string                    `json:"name"`
+	Since            time.Time                 `json:"since"`
+	Until            time.Time                 `json:"until"`
+	UptimePercent    float64                   `json:"uptime_percent"`
+	ConnectedSeconds int64                     `json:"connected_seconds"`
+	Events           []*entity.ConnectionEvent `json:"events"`
+}
+
+// ToConnectionHistoryResponse builds the connection history of an instance. The initial event is the
+// last one before the window and gives the state the window starts in.
+func ToConnectionHistoryResponse(name string, initial *entity.ConnectionEvent, events []*entity.ConnectionEvent, since, until time.Time) ConnectionHistoryResponse {
+	if events == nil {
+		events = []*entity.ConnectionEvent{}
+	}
+
+	connected := ConnectionUptime(initial, events, since, until)
+	var percent float64
+	if window := until.Sub(since); window > 0 {
+		percent = math.Round(float64(connected)/float64(window)*10000) / 100
+	}
+
+	return ConnectionHistoryResponse{
+		Name:             name,
+		Since:            since,
+		Until:            until,
+		UptimePercent:    percent,
+		ConnectedSeconds: int64(connected / time.Second),
+		Events:           events,
+	}
+}
+
+// ConnectionUptime returns how long an instance was connected within a window, given the last
+// event before the window and the events within it, oldest first
+func ConnectionUptime(initial *entity.ConnectionEvent, events []*entity.ConnectionEvent, since, until time.Time) time.Duration {
+	connected := initial != nil && initial.Status == entity.InstanceStatusConnected
+	cursor := since
+
+	var uptime time.Duration
+	for _, event := range events {
+		at := event.CreatedAt
+		if at.Before(cursor) {
+			at = cursor
+		}
+		if at.After(until) {
+			break
+		}
+		if connected {
+			uptime += at.Sub(cursor)
+		}
+		connected = event.Status == entity.InstanceStatusConnected
+		cursor = at
+	}
+	if connected && until.After(cursor) {
+		uptime += until.Sub(cursor)
+	}
+
+	return uptime
+}
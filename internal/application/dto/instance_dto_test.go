@@ -0,0 +1,80 @@
+package dto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+func TestConnectionUptime(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(10 * time.Hour)
+	event := func(status entity.InstanceStatus, offset time.Duration) *entity.ConnectionEvent {
+		return &entity.ConnectionEvent{Status: status, CreatedAt: since.Add(offset)}
+	}
+
+	tests := []struct {
+		name    string
+		initial *entity.ConnectionEvent
+		events  []*entity.ConnectionEvent
+		want    time.Duration
+	}{
+		{
+			name: "no history",
+			want: 0,
+		},
+		{
+			name:    "connected the whole window",
+			initial: event(entity.InstanceStatusConnected, -time.Hour),
+			want:    10 * time.Hour,
+		},
+		{
+			name:    "outage in the middle",
+			initial: event(entity.InstanceStatusConnected, -time.Hour),
+			events: []*entity.ConnectionEvent{
+				event(entity.InstanceStatusDisconnected, 2*time.Hour),
+				event(entity.InstanceStatusReconnecting, 2*time.Hour),
+				event(entity.InstanceStatusConnected, 5*time.Hour),
+			},
+			want: 7 * time.Hour,
+		},
+		{
+			name: "connected during the window",
+			events: []*entity.ConnectionEvent{
+				event(entity.InstanceStatusConnected, 4*time.Hour),
+				event(entity.InstanceStatusDisconnected, 6*time.Hour),
+			},
+			want: 2 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ConnectionUptime(tt.initial, tt.events, since, until)
+			if got != tt.want {
+				t.Errorf("ConnectionUptime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToConnectionHistoryResponse(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(4 * time.Hour)
+	initial := &entity.ConnectionEvent{Status: entity.InstanceStatusConnected, CreatedAt: since.Add(-time.Minute)}
+	events := []*entity.ConnectionEvent{
+		{Status: entity.InstanceStatusDisconnected, CreatedAt: since.Add(3 * time.Hour)},
+	}
+
+	resp := ToConnectionHistoryResponse("test", initial, events, since, until)
+	if resp.UptimePercent != 75 {
+		t.Errorf("UptimePercent = %v, want 75", resp.UptimePercent)
+	}
+	if resp.ConnectedSeconds != int64(3*time.Hour/time.Second) {
+		t.Errorf("ConnectedSeconds = %v, want %v", resp.ConnectedSeconds, int64(3*time.Hour/time.Second))
+	}
+	if len(resp.Events) != 1 {
+		t.Errorf("Events = %d, want 1", len(resp.Events))
+	}
+}
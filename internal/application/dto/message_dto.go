@@ -17,29 +17,29 @@ type SendTextRequest struct {
 
 // SendMediaRequest represents a request to send a media message
 type SendMediaRequest struct {
-	To       string `json:"to" validate:"required"`
-	MediaURL string `json:"media_url,omitempty"`
-	Base64   string `json:"base64,omitempty"`
-	MimeType string `json:"mime_type,omitempty"`
-	FileName string `json:"file_name,omitempty"`
-	Caption  string `json:"caption,omitempty"`
-	QuoteID  string `json:"quote_id,omitempty"`
+	To       string `json:"to" form:"to" validate:"required"`
+	MediaURL string `json:"media_url,omitempty" form:"media_url"`
+	Base64   string `json:"base64,omitempty" form:"base64"`
+	MimeType string `json:"mime_type,omitempty" form:"mime_type"`
+	FileName string `json:"file_name,omitempty" form:"file_name"`
+	Caption  string `json:"caption,omitempty" form:"caption"`
+	QuoteID  string `json:"quote_id,omitempty" form:"quote_id"`
 }
 
 // SendAudioRequest represents a request to send an audio message
 type SendAudioRequest struct {
-	To       string `json:"to" validate:"required"`
-	AudioURL string `json:"audio_url,omitempty"`
-	Base64   string `json:"base64,omitempty"`
-	PTT      bool   `json:"ptt"` // Push-to-talk (voice message)
-	QuoteID  string `json:"quote_id,omitempty"`
+	To       string `json:"to" form:"to" validate:"required"`
+	AudioURL string `json:"audio_url,omitempty" form:"audio_url"`
+	Base64   string `json:"base64,omitempty" form:"base64"`
+	PTT      bool   `json:"ptt" form:"ptt"` // Push-to-talk (voice message)
+	QuoteID  string `json:"quote_id,omitempty" form:"quote_id"`
 }
 
 // SendStickerRequest represents a request to send a sticker
 type SendStickerRequest struct {
-	To         string `json:"to" validate:"required"`
-	StickerURL string `json:"sticker_url,omitempty"`
-	Base64     string `json:"base64,omitempty"`
+	To         string `json:"to" form:"to" validate:"required"`
+	StickerURL string `json:"sticker_url,omitempty" form:"sticker_url"`
+	Base64     string `json:"base64,omitempty" form:"base64"`
 }
 
 // SendLocationRequest represents a request to send a location
@@ -174,13 +174,26 @@ type CarouselCardRequest struct {
 
 // SendStoryRequest represents a request to send a story/status
 type SendStoryRequest struct {
-	MediaURL        string `json:"media_url,omitempty"`
-	Base64          string `json:"base64,omitempty"`
-	MimeType        string `json:"mime_type,omitempty"`
-	Caption         string `json:"caption,omitempty"`
-	Text            string `json:"text,omitempty"`
-	BackgroundColor string `json:"background_color,omitempty"`
-	Font            int    `json:"font,omitempty"`
+	MediaURL        string `json:"media_url,omitempty" form:"media_url"`
+	Base64          string `json:"base64,omitempty" form:"base64"`
+	MimeType        string `json:"mime_type,omitempty" form:"mime_type"`
+	Caption         string `json:"caption,omitempty" form:"caption"`
+	Text            string `json:"text,omitempty" form:"text"`
+	BackgroundColor string `json:"background_color,omitempty" form:"background_color"`
+	Font            int    `json:"font,omitempty" form:"font"`
+}
+
+// EditMessageRequest represents a request to edit a previously sent message
+type EditMessageRequest struct {
+	To   string `json:"to,omitempty"` // Optional when the message is stored; defaults to its chat
+	Text string `json:"text" validate:"required,min=1"`
+}
+
+// DeleteMessageRequest represents a request to delete a message
+type DeleteMessageRequest struct {
+	To          string `json:"to,omitempty" query:"to"`                   // Optional when the message is stored; defaults to its chat
+	Participant string `json:"participant,omitempty" query:"participant"` // Sender of the message when revoking others' messages as group admin; defaults to the sender of the stored message
+	ForEveryone *bool  `json:"for_everyone,omitempty" query:"for_everyone"`
 }
 
 // MessageResponse represents the response after sending a message
@@ -203,6 +216,7 @@ type MessageReceivedEvent struct {
 	Content       string    `json:"content,omitempty"`
 	MediaURL      string    `json:"media_url,omitempty"`
 	MediaMimeType string    `json:"media_mime_type,omitempty"`
+	FileName      string    `json:"file_name,omitempty"`
 	Caption       string    `json:"caption,omitempty"`
 	QuotedMsgID   string    `json:"quoted_msg_id,omitempty"`
 	Timestamp     time.Time `json:"timestamp"`
@@ -218,16 +232,21 @@ type MessageAckEvent struct {
 
 // MessageDeleteEvent represents a deleted message
 type MessageDeleteEvent struct {
-	MessageID string    `json:"message_id"`
-	Chat      string    `json:"chat"`
-	Actor     string    `json:"actor,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
+	MessageID   string    `json:"message_id"`
+	Chat        string    `json:"chat"`
+	Actor       string    `json:"actor,omitempty"`
+	ForEveryone bool      `json:"for_everyone"`
+	Timestamp   time.Time `json:"timestamp"`
 }
 
 // MessageUpdateEvent represents updated message metadata
 type MessageUpdateEvent struct {
 	MessageID string    `json:"message_id"`
 	Chat      string    `json:"chat"`
+	Event     string    `json:"event,omitempty"`
+	Content   string    `json:"content,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	FromMe    bool      `json:"fromMe"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
@@ -259,6 +278,19 @@ type ContactUpdateEvent struct {
 	Event    string `json:"event"`
 }
 
+// ChatUpdateEvent represents a change of the archive, pin, mute or read state of a chat
+type ChatUpdateEvent struct {
+	JID        string     `json:"jid"`
+	Event      string     `json:"event"`  // archive, pin, mute or read
+	Source     string     `json:"source"` // api when changed through this API, device when changed on a linked device
+	Archived   *bool      `json:"archived,omitempty"`
+	Pinned     *bool      `json:"pinned,omitempty"`
+	Muted      *bool      `json:"muted,omitempty"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	Read       *bool      `json:"read,omitempty"`
+	Timestamp  time.Time  `json:"timestamp"`
+}
+
 // GroupMetadataEvent represents detailed group updates
 type GroupMetadataEvent struct {
 	GroupJID  string    `json:"group_jid"`
@@ -282,3 +314,53 @@ func ToMessageResponse(msg *entity.Message, waMessageID string) MessageResponse
 		Timestamp: msg.Timestamp,
 	}
 }
+
+// PollOptionResult represents the votes of a single poll option
+type PollOptionResult struct {
+	Name   string   `json:"name"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters"`
+}
+
+// PollResultsResponse represents the aggregated results of a poll
+type PollResultsResponse struct {
+	MessageID       string             `json:"message_id"`
+	Chat            string             `json:"chat"`
+	Question        string             `json:"question"`
+	SelectableCount int                `json:"selectable_count"`
+	Options         []PollOptionResult `json:"options"`
+	TotalVoters     int                `json:"total_voters"`
+}
+
+// ToPollResultsResponse aggregates the current votes of a poll per option.
+// Voters who removed their vote are not counted.
+func ToPollResultsResponse(poll *entity.Poll, votes []*entity.PollVote) PollResultsResponse {
+	results := PollResultsResponse{
+		MessageID:       poll.MessageID,
+		Chat:            poll.ChatJID,
+		Question:        poll.Question,
+		SelectableCount: poll.SelectableCount,
+		Options:         make([]PollOptionResult, len(poll.Options)),
+	}
+
+	index := make(map[string]int, len(poll.Options))
+	for i, name := range poll.Options {
+		results.Options[i] = PollOptionResult{Name: name, Voters: []string{}}
+		index[name] = i
+	}
+
+	for _, vote := range votes {
+		if len(vote.SelectedOptions) == 0 {
+			continue
+		}
+		results.TotalVoters++
+		for _, name := range vote.SelectedOptions {
+			if i, ok := index[name]; ok {
+				results.Options[i].Votes++
+				results.Options[i].Voters = append(results.Options[i].Voters, vote.VoterJID)
+			}
+		}
+	}
+
+	return results
+}
@@ -224,3 +224,27 @@ func TestMessageHeaderRequest_Types(t *testing.T) {
 	}
 }
 
+
+func TestToPollResultsResponse(t *testing.T) {
+	poll := entity.NewPoll(uuid.New(), "POLL1", "5511999999999@s.whatsapp.net", "Lunch?", []string{"Pizza", "Sushi", "Salad"}, 2)
+	votes := []*entity.PollVote{
+		{VoterJID: "a@s.whatsapp.net", SelectedOptions: []string{"Pizza", "Sushi"}, VotedAt: time.Now()},
+		{VoterJID: "b@s.whatsapp.net", SelectedOptions: []string{"Sushi"}, VotedAt: time.Now()},
+		{VoterJID: "c@s.whatsapp.net", SelectedOptions: []string{}, VotedAt: time.Now()},
+	}
+
+	results := ToPollResultsResponse(poll, votes)
+
+	if results.TotalVoters != 2 {
+		t.Errorf("TotalVoters = %d, want 2", results.TotalVoters)
+	}
+	want := map[string]int{"Pizza": 1, "Sushi": 2, "Salad": 0}
+	for _, option := range results.Options {
+		if option.Votes != want[option.Name] {
+			t.Errorf("option %q has %d votes, want %d", option.Name, option.Votes, want[option.Name])
+		}
+		if len(option.Voters) != option.Votes {
+			t.Errorf("option %q lists %d voters for %d votes", option.Name, len(option.Voters), option.Votes)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package dto
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/jonadableite/turbozap-api/internal/domain/entity"
 )
 
@@ -12,16 +15,83 @@ type SetWebhookRequest struct {
 	Enabled  *bool                 `json:"enabled,omitempty"`
 	ByEvents *bool                 `json:"webhook_by_events,omitempty"`
 	Base64   *bool                 `json:"webhook_base64,omitempty"`
+	Secret   *string               `json:"secret,omitempty"` // Empty string disables signing
+}
+
+// UpdateWebhookRequest represents a request to change a webhook, omitted fields keep their current value
+type UpdateWebhookRequest struct {
+	URL      *string               `json:"url,omitempty"`
+	Events   []entity.WebhookEvent `json:"events,omitempty"`
+	Headers  map[string]string     `json:"headers,omitempty"`
+	Enabled  *bool                 `json:"enabled,omitempty"`
+	ByEvents *bool                 `json:"webhook_by_events,omitempty"`
+	Base64   *bool                 `json:"webhook_base64,omitempty"`
+	Secret   *string               `json:"secret,omitempty"` // Empty string disables signing
 }
 
 // GetWebhookResponse represents the webhook configuration response
 type GetWebhookResponse struct {
+	ID              string                `json:"id"`
 	URL             string                `json:"url"`
 	Events          []entity.WebhookEvent `json:"events"`
 	Headers         map[string]string     `json:"headers,omitempty"`
 	Enabled         bool                  `json:"enabled"`
 	WebhookByEvents bool                  `json:"webhook_by_events"`
 	WebhookBase64   bool                  `json:"webhook_base64"`
+	Signed          bool                  `json:"signed"`
+	SecretRotating  bool                  `json:"secret_rotating"` // A previous secret still signs deliveries
+}
+
+// WebhookSecretResponse represents a newly generated webhook secret
+type WebhookSecretResponse struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveriesQuery represents the query string of a webhook delivery list request
+type WebhookDeliveriesQuery struct {
+	Limit     int    `query:"limit"`
+	Offset    int    `query:"offset"`
+	Status    string `query:"status"` // pending, delivered or dead_letter
+	Event     string `query:"event"`
+	WebhookID string `query:"webhook_id"`
+	Since     string `query:"since"` // RFC3339 or unix timestamp
+	Until     string `query:"until"` // RFC3339 or unix timestamp
+}
+
+// WebhookDeliveryResponse represents an event delivered, or waiting to be delivered, to a webhook
+type WebhookDeliveryResponse struct {
+	ID            string     `json:"id"`
+	EventID       string     `json:"event_id"`
+	WebhookID     string     `json:"webhook_id"`
+	Event         string     `json:"event"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"` // Only while pending
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveryDetailResponse represents a delivery with its payload and every attempt made at it
+type WebhookDeliveryDetailResponse struct {
+	WebhookDeliveryResponse
+	Payload    json.RawMessage              `json:"payload"`
+	AttemptLog []*entity.WebhookDeliveryLog `json:"attempt_log"`
+}
+
+// ListWebhookDeliveriesResponse represents a page of the deliveries of an instance
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Total      int64                     `json:"total"`
+	Limit      int                       `json:"limit"`
+	Offset     int                       `json:"offset"`
+}
+
+// RedeliverWebhookDeliveriesRequest represents a request to redeliver the dead-letter deliveries
+type RedeliverWebhookDeliveriesRequest struct {
+	Since     string `json:"since"`                // RFC3339 or unix timestamp
+	WebhookID string `json:"webhook_id,omitempty"` // All webhooks of the instance when empty
 }
 
 // WebhookEventPayload represents the payload sent to webhooks
@@ -39,12 +109,23 @@ type ConnectionUpdateData struct {
 	PhoneNumber string `json:"phone_number,omitempty"`
 	ProfileName string `json:"profile_name,omitempty"`
 	ProfilePic  string `json:"profile_pic,omitempty"`
+	Reason      string `json:"reason,omitempty"` // Why the connection changed, when known
+}
+
+// WebhookErrorData represents errors event data
+type WebhookErrorData struct {
+	Type      string `json:"type"` // webhook_disabled
+	Message   string `json:"message"`
+	WebhookID string `json:"webhook_id,omitempty"`
+	URL       string `json:"url,omitempty"`
+	LastError string `json:"last_error,omitempty"`
 }
 
 // QRCodeUpdateData represents QR code update event data
 type QRCodeUpdateData struct {
-	QRCode string `json:"qr_code"` // Base64 encoded QR code image
-	Code   string `json:"code"`    // Raw QR code string
+	QRCode      string `json:"qr_code"`                // Base64 encoded QR code image
+	Code        string `json:"code"`                   // Raw QR code string
+	PairingCode string `json:"pairing_code,omitempty"` // Code typed on the phone when pairing by phone number
 }
 
 // GroupParticipantsUpdateData represents group participants update event data
@@ -65,16 +146,22 @@ type PresenceUpdateData struct {
 type CallEventData struct {
 	CallID    string `json:"call_id"`
 	From      string `json:"from"`
+	Group     string `json:"group,omitempty"` // Group JID for group calls
 	Timestamp int64  `json:"timestamp"`
 	IsVideo   bool   `json:"is_video"`
-	Status    string `json:"status"` // ringing, missed, answered, rejected
+	Status    string `json:"status"`           // ringing, missed, answered, rejected
+	Reason    string `json:"reason,omitempty"` // Why the call ended, as reported by WhatsApp
 }
 
 // PollVoteData represents poll vote event data
 type PollVoteData struct {
-	MessageID       string `json:"message_id"`
-	Voter           string `json:"voter"`
-	SelectedOptions []int  `json:"selected_options"`
+	MessageID       string    `json:"message_id"` // ID of the poll message
+	Chat            string    `json:"chat"`
+	Voter           string    `json:"voter"`
+	Question        string    `json:"question,omitempty"`
+	SelectedOptions []string  `json:"selected_options"` // Empty when the voter removed their vote
+	SelectedIndexes []int     `json:"selected_indexes"`
+	Timestamp       time.Time `json:"timestamp"`
 }
 
 // ButtonResponseData represents button response event data
@@ -97,11 +184,57 @@ type ListResponseData struct {
 // ToGetWebhookResponse converts an entity to response DTO
 func ToGetWebhookResponse(webhook *entity.Webhook) GetWebhookResponse {
 	return GetWebhookResponse{
+		ID:              webhook.ID.String(),
 		URL:             webhook.URL,
 		Events:          webhook.Events,
 		Headers:         webhook.Headers,
 		Enabled:         webhook.Enabled,
 		WebhookByEvents: webhook.WebhookByEvents,
 		WebhookBase64:   webhook.UseBase64,
+		Signed:          webhook.Secret != "",
+		SecretRotating:  webhook.PreviousSecret != "",
+	}
+}
+
+// ToListWebhooksResponse converts a list of webhook entities to response DTOs
+func ToListWebhooksResponse(webhooks []*entity.Webhook) []GetWebhookResponse {
+	result := make([]GetWebhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		result[i] = ToGetWebhookResponse(webhook)
+	}
+	return result
+}
+
+// ToWebhookDeliveryResponse converts an entity to response DTO
+func ToWebhookDeliveryResponse(delivery *entity.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:          delivery.ID.String(),
+		EventID:     delivery.EventID.String(),
+		Event:       string(delivery.Event),
+		Status:      string(delivery.Status),
+		Attempts:    delivery.Attempts,
+		LastError:   delivery.LastError,
+		CreatedAt:   delivery.CreatedAt,
+		DeliveredAt: delivery.DeliveredAt,
+	}
+	if delivery.WebhookID != nil {
+		resp.WebhookID = delivery.WebhookID.String()
+	}
+	if delivery.Status == entity.WebhookDeliveryPending {
+		next := delivery.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
+
+// ToWebhookDeliveryDetailResponse converts a delivery and its attempts to response DTO
+func ToWebhookDeliveryDetailResponse(delivery *entity.WebhookDelivery, logs []*entity.WebhookDeliveryLog) WebhookDeliveryDetailResponse {
+	if logs == nil {
+		logs = []*entity.WebhookDeliveryLog{}
+	}
+	return WebhookDeliveryDetailResponse{
+		WebhookDeliveryResponse: ToWebhookDeliveryResponse(delivery),
+		Payload:                 json.RawMessage(delivery.Payload),
+		AttemptLog:              logs,
 	}
 }
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Chat represents a WhatsApp conversation of an instance
+type Chat struct {
+	ID                 uuid.UUID   `json:"id"`
+	InstanceID         uuid.UUID   `json:"instance_id"`
+	JID                string      `json:"jid"`
+	Name               string      `json:"name,omitempty"`
+	IsGroup            bool        `json:"is_group"`
+	UnreadCount        int         `json:"unread_count"`
+	Archived           bool        `json:"archived"`
+	Pinned             bool        `json:"pinned"`
+	MutedUntil         *time.Time  `json:"muted_until,omitempty"`
+	LastMessageAt      *time.Time  `json:"last_message_at,omitempty"`
+	LastMessageID      string      `json:"last_message_id,omitempty"`
+	LastMessageType    MessageType `json:"last_message_type,omitempty"`
+	LastMessagePreview string      `json:"last_message_preview,omitempty"`
+	LastMessageFromMe  bool        `json:"last_message_from_me"`
+	CreatedAt          time.Time   `json:"created_at"`
+	UpdatedAt          time.Time   `json:"updated_at"`
+}
+
+// NewChat creates a new chat entity
+func NewChat(instanceID uuid.UUID, jid string) *Chat {
+	now := time.Now()
+	return &Chat{
+		ID:         uuid.New(),
+		InstanceID: instanceID,
+		JID:        jid,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// IsMuted returns true if notifications of the chat are muted
+func (c *Chat) IsMuted() bool {
+	return c.MutedUntil != nil && c.MutedUntil.After(time.Now())
+}
+
+// chatPreviewLength is the maximum number of characters kept as last message preview
+const chatPreviewLength = 100
+
+// SetLastMessage records the given message as the latest one of the chat
+func (c *Chat) SetLastMessage(messageID string, msgType MessageType, text string, fromMe bool, at time.Time) {
+	c.LastMessageID = messageID
+	c.LastMessageType = msgType
+	c.LastMessagePreview = truncatePreview(text)
+	c.LastMessageFromMe = fromMe
+	c.LastMessageAt = &at
+}
+
+// truncatePreview shortens a message text to the preview length without splitting characters
+func truncatePreview(text string) string {
+	if utf8.RuneCountInString(text) <= chatPreviewLength {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:chatPreviewLength]) + "…"
+}
@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConnectionEvent represents a connection state transition of an instance
+type ConnectionEvent struct {
+	ID         int64          `json:"id"`
+	InstanceID uuid.UUID      `json:"instance_id"`
+	Status     InstanceStatus `json:"status"`
+	Reason     string         `json:"reason,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// NewConnectionEvent creates a new connection event happening now
+func NewConnectionEvent(instanceID uuid.UUID, status InstanceStatus, reason string) *ConnectionEvent {
+	return &ConnectionEvent{
+		InstanceID: instanceID,
+		Status:     status,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	}
+}
@@ -13,24 +13,40 @@ const (
 	InstanceStatusDisconnected InstanceStatus = "disconnected"
 	InstanceStatusConnecting   InstanceStatus = "connecting"
 	InstanceStatusConnected    InstanceStatus = "connected"
+	InstanceStatusReconnecting InstanceStatus = "reconnecting"
 	InstanceStatusQRCode       InstanceStatus = "qrcode"
 	InstanceStatusError        InstanceStatus = "error"
 )
 
 // Instance represents a WhatsApp instance/session
 type Instance struct {
-	ID          uuid.UUID      `json:"id"`
-	Name        string         `json:"name"`
-	APIKey      string         `json:"api_key"`
-	UserID      string         `json:"user_id,omitempty"`
-	Status      InstanceStatus `json:"status"`
-	PhoneNumber string         `json:"phone_number,omitempty"`
-	ProfileName string         `json:"profile_name,omitempty"`
-	ProfilePic  string         `json:"profile_pic,omitempty"`
-	QRCode      string         `json:"qr_code,omitempty"`
-	DeviceJID   string         `json:"device_jid,omitempty"` // WhatsApp device JID for session persistence
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	ID          uuid.UUID        `json:"id"`
+	Name        string           `json:"name"`
+	APIKey      string           `json:"api_key"`
+	UserID      string           `json:"user_id,omitempty"`
+	Status      InstanceStatus   `json:"status"`
+	PhoneNumber string           `json:"phone_number,omitempty"`
+	ProfileName string           `json:"profile_name,omitempty"`
+	ProfilePic  string           `json:"profile_pic,omitempty"`
+	QRCode      string           `json:"qr_code,omitempty"`
+	DeviceJID   string           `json:"device_jid,omitempty"` // WhatsApp device JID for session persistence
+	Settings    InstanceSettings `json:"settings"`
+	ProxyURL    string           `json:"-"` // Outbound HTTP or SOCKS5 proxy, may hold credentials
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+// InstanceSettings holds per-instance behaviour toggles
+type InstanceSettings struct {
+	StoreMedia        bool   `json:"store_media"`                   // Download incoming media and keep it in object storage
+	HistorySyncDays   int    `json:"history_sync_days"`             // Only persist synced history newer than this many days (0 = everything)
+	RejectCalls       bool   `json:"reject_calls"`                  // Automatically reject incoming calls
+	RejectCallMessage string `json:"reject_call_message,omitempty"` // Text sent to the caller after a call is rejected automatically
+	IgnoreGroups      bool   `json:"ignore_groups"`                 // Drop messages received in groups
+	AlwaysOnline      bool   `json:"always_online"`                 // Keep the account shown as online while connected
+	ReadMessages      bool   `json:"read_messages"`                 // Mark incoming messages as read as soon as they arrive
+	IgnoreStatus      bool   `json:"ignore_status"`                 // Drop status broadcasts
+	SyncFullHistory   bool   `json:"sync_full_history"`             // Request the full message history when pairing a new device
 }
 
 // NewInstance creates a new instance with default values
@@ -92,6 +108,12 @@ func (i *Instance) SetConnecting() {
 	i.UpdatedAt = time.Now()
 }
 
+// SetReconnecting updates the instance status to reconnecting
+func (i *Instance) SetReconnecting() {
+	i.Status = InstanceStatusReconnecting
+	i.UpdatedAt = time.Now()
+}
+
 // SetError updates the instance status to error
 func (i *Instance) SetError() {
 	i.Status = InstanceStatusError
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InstanceLease records which API node owns the WhatsApp session of an instance
+type InstanceLease struct {
+	InstanceID  uuid.UUID `json:"instance_id"`
+	NodeID      string    `json:"node_id"`
+	NodeAddress string    `json:"node_address,omitempty"` // Base URL requests for the instance are forwarded to
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// IsExpired returns true if the owner stopped renewing the lease
+func (l *InstanceLease) IsExpired() bool {
+	return time.Now().After(l.ExpiresAt)
+}
@@ -34,6 +34,7 @@ const (
 	MessageStatusSent      MessageStatus = "sent"
 	MessageStatusDelivered MessageStatus = "delivered"
 	MessageStatusRead      MessageStatus = "read"
+	MessageStatusPlayed    MessageStatus = "played"
 	MessageStatusFailed    MessageStatus = "failed"
 )
 
@@ -42,7 +43,8 @@ type Message struct {
 	ID            uuid.UUID     `json:"id"`
 	InstanceID    uuid.UUID     `json:"instance_id"`
 	MessageID     string        `json:"message_id"`
-	RemoteJID     string        `json:"remote_jid"`
+	RemoteJID     string        `json:"remote_jid"`           // Chat the message belongs to
+	SenderJID     string        `json:"sender_jid,omitempty"` // Author of the message, relevant in groups
 	FromMe        bool          `json:"from_me"`
 	Type          MessageType   `json:"type"`
 	Status        MessageStatus `json:"status"`
@@ -50,11 +52,66 @@ type Message struct {
 	MediaURL      string        `json:"media_url,omitempty"`
 	MediaMimeType string        `json:"media_mime_type,omitempty"`
 	MediaCaption  string        `json:"media_caption,omitempty"`
+	MediaFileName string        `json:"media_file_name,omitempty"`
+	MediaKeys     *MediaKeys    `json:"-"`
 	QuotedMsgID   string        `json:"quoted_msg_id,omitempty"`
 	Timestamp     time.Time     `json:"timestamp"`
+	DeliveredAt   *time.Time    `json:"delivered_at,omitempty"`
+	ReadAt        *time.Time    `json:"read_at,omitempty"`
+	PlayedAt      *time.Time    `json:"played_at,omitempty"`
+	EditedAt      *time.Time    `json:"edited_at,omitempty"`
+	RevokedAt     *time.Time    `json:"revoked_at,omitempty"`
+	RevokedBy     string        `json:"revoked_by,omitempty"`
 	CreatedAt     time.Time     `json:"created_at"`
 }
 
+// MediaKeys holds what is needed to download and decrypt a media attachment from WhatsApp servers
+type MediaKeys struct {
+	DirectPath    string
+	MediaKey      []byte
+	FileSHA256    []byte
+	FileEncSHA256 []byte
+	FileLength    int64
+	MediaType     string // whatsmeow media type used to derive the decryption keys
+}
+
+// MessageEdit represents one entry in the edit history of a message
+type MessageEdit struct {
+	ID              uuid.UUID `json:"id"`
+	InstanceID      uuid.UUID `json:"instance_id"`
+	MessageID       string    `json:"message_id"`
+	PreviousContent string    `json:"previous_content"`
+	NewContent      string    `json:"new_content"`
+	EditedBy        string    `json:"edited_by,omitempty"`
+	EditedAt        time.Time `json:"edited_at"`
+}
+
+// NewMessageEdit creates a new edit history entry
+func NewMessageEdit(instanceID uuid.UUID, messageID, newContent, editedBy string) *MessageEdit {
+	return &MessageEdit{
+		ID:         uuid.New(),
+		InstanceID: instanceID,
+		MessageID:  messageID,
+		NewContent: newContent,
+		EditedBy:   editedBy,
+		EditedAt:   time.Now(),
+	}
+}
+
+// IsMedia returns true if the message carries a media attachment
+func (m *Message) IsMedia() bool {
+	switch m.Type {
+	case MessageTypeImage, MessageTypeVideo, MessageTypeAudio, MessageTypeDocument, MessageTypeSticker:
+		return true
+	}
+	return false
+}
+
+// IsRevoked returns true if the message was deleted for everyone
+func (m *Message) IsRevoked() bool {
+	return m.RevokedAt != nil
+}
+
 // NewMessage creates a new message entity
 func NewMessage(instanceID uuid.UUID, remoteJID string, msgType MessageType) *Message {
 	now := time.Now()
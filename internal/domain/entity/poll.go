@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Poll represents a poll sent or received by an instance
+type Poll struct {
+	ID              uuid.UUID `json:"id"`
+	InstanceID      uuid.UUID `json:"instance_id"`
+	MessageID       string    `json:"message_id"`
+	ChatJID         string    `json:"chat_jid"`
+	CreatorJID      string    `json:"creator_jid,omitempty"`
+	Question        string    `json:"question"`
+	Options         []string  `json:"options"`
+	SelectableCount int       `json:"selectable_count"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// PollVote represents the current choice of a voter in a poll.
+// WhatsApp sends the full selection on every change, so a new vote replaces the previous one.
+type PollVote struct {
+	InstanceID      uuid.UUID `json:"instance_id"`
+	PollMessageID   string    `json:"poll_message_id"`
+	VoterJID        string    `json:"voter_jid"`
+	SelectedOptions []string  `json:"selected_options"`
+	VotedAt         time.Time `json:"voted_at"`
+}
+
+// NewPoll creates a new poll entity
+func NewPoll(instanceID uuid.UUID, messageID, chatJID, question string, options []string, selectableCount int) *Poll {
+	return &Poll{
+		ID:              uuid.New(),
+		InstanceID:      instanceID,
+		MessageID:       messageID,
+		ChatJID:         chatJID,
+		Question:        question,
+		Options:         options,
+		SelectableCount: selectableCount,
+		CreatedAt:       time.Now(),
+	}
+}
@@ -111,6 +111,8 @@ type Webhook struct {
 	Headers         map[string]string `json:"headers,omitempty"`
 	WebhookByEvents bool              `json:"webhook_by_events"`
 	UseBase64       bool              `json:"webhook_base64"`
+	Secret          string            `json:"-"` // Signs deliveries, empty disables signing
+	PreviousSecret  string            `json:"-"` // Replaced secret still signing deliveries until it is revoked
 	CreatedAt       time.Time         `json:"created_at"`
 	UpdatedAt       time.Time         `json:"updated_at"`
 }
@@ -150,6 +152,31 @@ func (w *Webhook) ShouldTrigger(event WebhookEvent) bool {
 	return false
 }
 
+// SetSecret replaces the signing secret, keeping the current one active as the previous secret
+// so receivers can be updated without rejecting deliveries. An empty secret disables signing.
+func (w *Webhook) SetSecret(secret string) {
+	switch {
+	case secret == "":
+		w.Secret = ""
+		w.PreviousSecret = ""
+	case secret != w.Secret:
+		w.PreviousSecret = w.Secret
+		w.Secret = secret
+	}
+}
+
+// Secrets returns the secrets deliveries are signed with, the current one first
+func (w *Webhook) Secrets() []string {
+	var secrets []string
+	if w.Secret != "" {
+		secrets = append(secrets, w.Secret)
+	}
+	if w.PreviousSecret != "" {
+		secrets = append(secrets, w.PreviousSecret)
+	}
+	return secrets
+}
+
 // Slug returns the kebab-case version used in URLs/config
 func (e WebhookEvent) Slug() string {
 	return strings.ReplaceAll(string(e), ".", "-")
@@ -164,6 +191,7 @@ func EventFromSlug(slug string) (WebhookEvent, bool) {
 
 // WebhookPayload represents the payload sent to webhooks
 type WebhookPayload struct {
+	EventID    string       `json:"event_id"` // Stable across redeliveries, receivers deduplicate on it
 	Event      WebhookEvent `json:"event"`
 	InstanceID string       `json:"instance_id"`
 	Instance   string       `json:"instance"`
@@ -186,16 +214,16 @@ type GetWebhookResponse struct {
 	Enabled bool           `json:"enabled"`
 }
 
-// WebhookDeliveryLog represents a log of webhook delivery attempts
+// WebhookDeliveryLog represents one attempt at sending a webhook delivery
 type WebhookDeliveryLog struct {
-	ID           uuid.UUID    `json:"id"`
-	WebhookID    uuid.UUID    `json:"webhook_id"`
-	Event        WebhookEvent `json:"event"`
-	Payload      string       `json:"payload"`
-	StatusCode   int          `json:"status_code"`
-	Response     string       `json:"response"`
-	Success      bool         `json:"success"`
-	Attempts     int          `json:"attempts"`
-	ErrorMessage string       `json:"error_message,omitempty"`
-	CreatedAt    time.Time    `json:"created_at"`
+	ID           int64     `json:"id"`
+	DeliveryID   uuid.UUID `json:"delivery_id"`
+	Attempt      int       `json:"attempt"`
+	URL          string    `json:"url"`
+	StatusCode   int       `json:"status_code,omitempty"` // 0 when no response was received
+	Response     string    `json:"response,omitempty"`    // Truncated response body
+	Success      bool      `json:"success"`
+	LatencyMs    int64     `json:"latency_ms"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
 }
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookDeliveryStatus represents the state of a webhook delivery in the outbox
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryDeadLetter is set once the retries are exhausted or the webhook can't receive
+	// the delivery anymore, it stays there until it is redelivered
+	WebhookDeliveryDeadLetter WebhookDeliveryStatus = "dead_letter"
+)
+
+// WebhookDelivery represents an event waiting in the outbox to be delivered to one webhook
+type WebhookDelivery struct {
+	ID            uuid.UUID             `json:"id"`
+	EventID       uuid.UUID             `json:"event_id"` // Shared by the deliveries of the same event
+	InstanceID    uuid.UUID             `json:"instance_id"`
+	WebhookID     *uuid.UUID            `json:"webhook_id,omitempty"` // Nil for the global webhook
+	Event         WebhookEvent          `json:"event"`
+	Payload       string                `json:"payload"` // JSON encoded WebhookPayload
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+	LastError     string                `json:"last_error,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+}
+
+// NewWebhookDelivery creates a delivery of an event due right away
+func NewWebhookDelivery(eventID, instanceID uuid.UUID, webhookID *uuid.UUID, event WebhookEvent, payload string) *WebhookDelivery {
+	now := time.Now()
+	return &WebhookDelivery{
+		ID:            uuid.New(),
+		EventID:       eventID,
+		InstanceID:    instanceID,
+		WebhookID:     webhookID,
+		Event:         event,
+		Payload:       payload,
+		Status:        WebhookDeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
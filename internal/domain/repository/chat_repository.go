@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+// ChatRepository defines the interface for chat data access
+type ChatRepository interface {
+	// Upsert creates or updates a chat by instance and JID. The unread count, archive, pin and mute
+	// state of an existing chat are kept.
+	Upsert(ctx context.Context, chat *entity.Chat) error
+
+	// UpsertBatch creates or updates several chats at once
+	UpsertBatch(ctx context.Context, chats []*entity.Chat) error
+
+	// RecordMessage sets the last message of a chat, creating the chat if needed.
+	// Incoming messages increase the unread count, outgoing ones clear it.
+	RecordMessage(ctx context.Context, chat *entity.Chat) error
+
+	// GetByJID retrieves a chat by its JID
+	GetByJID(ctx context.Context, instanceID uuid.UUID, jid string) (*entity.Chat, error)
+
+	// List retrieves the chats of an instance
+	List(ctx context.Context, instanceID uuid.UUID, filter ChatFilter) ([]*entity.Chat, error)
+
+	// Count counts the chats of an instance matching the filter
+	Count(ctx context.Context, instanceID uuid.UUID, filter ChatFilter) (int64, error)
+
+	// UpdateName updates the name of a chat
+	UpdateName(ctx context.Context, instanceID uuid.UUID, jid, name string) error
+
+	// MarkRead clears the unread count of a chat
+	MarkRead(ctx context.Context, instanceID uuid.UUID, jid string) error
+
+	// MarkUnread flags a chat as having unread messages, creating the chat if needed
+	MarkUnread(ctx context.Context, instanceID uuid.UUID, jid string) error
+
+	// SetArchived archives or unarchives a chat, creating the chat if needed. Archived chats are unpinned.
+	SetArchived(ctx context.Context, instanceID uuid.UUID, jid string, archived bool) error
+
+	// SetPinned pins or unpins a chat, creating the chat if needed
+	SetPinned(ctx context.Context, instanceID uuid.UUID, jid string, pinned bool) error
+
+	// SetMutedUntil mutes a chat until the given time, or unmutes it when nil, creating the chat if needed
+	SetMutedUntil(ctx context.Context, instanceID uuid.UUID, jid string, mutedUntil *time.Time) error
+
+	// DeleteByInstance deletes all chats for an instance
+	DeleteByInstance(ctx context.Context, instanceID uuid.UUID) error
+}
+
+// ChatSort is the field chats are sorted by
+type ChatSort string
+
+const (
+	ChatSortLastMessage ChatSort = "last_message"
+	ChatSortUnread      ChatSort = "unread"
+	ChatSortName        ChatSort = "name"
+)
+
+// ChatFilter narrows, sorts and paginates the chats of an instance
+type ChatFilter struct {
+	Archived   *bool
+	Pinned     *bool
+	UnreadOnly bool
+	Sort       ChatSort
+	Ascending  bool
+	Limit      int
+	Offset     int
+}
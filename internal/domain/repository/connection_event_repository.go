@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+// ConnectionEventRepository defines the interface for instance connection history data access
+type ConnectionEventRepository interface {
+	// Create stores a connection event
+	Create(ctx context.Context, event *entity.ConnectionEvent) error
+
+	// List retrieves the connection events of an instance within a time range, oldest first
+	List(ctx context.Context, instanceID uuid.UUID, since, until time.Time) ([]*entity.ConnectionEvent, error)
+
+	// GetLastBefore retrieves the last connection event of an instance before the given time
+	GetLastBefore(ctx context.Context, instanceID uuid.UUID, before time.Time) (*entity.ConnectionEvent, error)
+}
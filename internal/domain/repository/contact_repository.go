@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+// ContactRepository defines the interface for contact data access
+type ContactRepository interface {
+	// Upsert creates or updates a contact by instance and JID
+	Upsert(ctx context.Context, contact *entity.Contact) error
+
+	// UpsertBatch creates or updates several contacts at once
+	UpsertBatch(ctx context.Context, contacts []*entity.Contact) error
+
+	// GetByJID retrieves a contact by its JID
+	GetByJID(ctx context.Context, instanceID uuid.UUID, jid string) (*entity.Contact, error)
+
+	// DeleteByInstance deletes all contacts for an instance
+	DeleteByInstance(ctx context.Context, instanceID uuid.UUID) error
+}
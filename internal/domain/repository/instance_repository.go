@@ -33,6 +33,12 @@ type InstanceRepository interface {
 	// UpdateStatus updates only the status of an instance
 	UpdateStatus(ctx context.Context, id uuid.UUID, status entity.InstanceStatus) error
 
+	// UpdateSettings updates only the settings of an instance
+	UpdateSettings(ctx context.Context, id uuid.UUID, settings entity.InstanceSettings) error
+
+	// UpdateProxy updates only the proxy of an instance, an empty URL clears it
+	UpdateProxy(ctx context.Context, id uuid.UUID, proxyURL string) error
+
 	// Delete deletes an instance
 	Delete(ctx context.Context, id uuid.UUID) error
 
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+// LeaseRepository defines the interface for instance ownership leases between API nodes
+type LeaseRepository interface {
+	// Acquire takes or renews the lease of an instance for a node. It returns the current
+	// lease and whether the node holds it.
+	Acquire(ctx context.Context, instanceID uuid.UUID, nodeID, nodeAddress string, ttl time.Duration) (*entity.InstanceLease, bool, error)
+
+	// Get retrieves the lease of an instance
+	Get(ctx context.Context, instanceID uuid.UUID) (*entity.InstanceLease, error)
+
+	// RenewAll extends every lease held by a node and returns the instances it still holds
+	RenewAll(ctx context.Context, nodeID string, ttl time.Duration) ([]uuid.UUID, error)
+
+	// Release gives up the lease of an instance held by a node
+	Release(ctx context.Context, instanceID uuid.UUID, nodeID string) error
+
+	// ReleaseAll gives up every lease held by a node
+	ReleaseAll(ctx context.Context, nodeID string) error
+
+	// ListOrphaned lists the paired instances that were online and whose lease expired or never existed
+	ListOrphaned(ctx context.Context) ([]uuid.UUID, error)
+}
@@ -13,6 +13,9 @@ type MessageRepository interface {
 	// Create creates a new message record
 	Create(ctx context.Context, message *entity.Message) error
 
+	// CreateBatch creates the given messages, skipping those already stored, and returns how many were inserted
+	CreateBatch(ctx context.Context, instanceID uuid.UUID, messages []*entity.Message) (int, error)
+
 	// GetByID retrieves a message by ID
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.Message, error)
 
@@ -22,8 +25,8 @@ type MessageRepository interface {
 	// GetByInstance retrieves all messages for an instance
 	GetByInstance(ctx context.Context, instanceID uuid.UUID, limit, offset int) ([]*entity.Message, error)
 
-	// GetByRemoteJID retrieves messages for a specific chat
-	GetByRemoteJID(ctx context.Context, instanceID uuid.UUID, remoteJID string, limit, offset int) ([]*entity.Message, error)
+	// GetByRemoteJID retrieves a page of messages for a specific chat, newest first
+	GetByRemoteJID(ctx context.Context, instanceID uuid.UUID, remoteJID string, filter MessageFilter) ([]*entity.Message, error)
 
 	// GetByDateRange retrieves messages within a date range
 	GetByDateRange(ctx context.Context, instanceID uuid.UUID, start, end time.Time) ([]*entity.Message, error)
@@ -34,6 +37,21 @@ type MessageRepository interface {
 	// UpdateStatusByMessageID updates the status using WhatsApp message ID
 	UpdateStatusByMessageID(ctx context.Context, instanceID uuid.UUID, messageID string, status entity.MessageStatus) error
 
+	// ApplyReceipt advances the status of the given messages and records when the receipt arrived
+	ApplyReceipt(ctx context.Context, instanceID uuid.UUID, messageIDs []string, status entity.MessageStatus, at time.Time) error
+
+	// RecordEdit updates the content of a message and appends the change to its edit history
+	RecordEdit(ctx context.Context, edit *entity.MessageEdit) error
+
+	// GetEditHistory retrieves the edit history of a message, oldest first
+	GetEditHistory(ctx context.Context, instanceID uuid.UUID, messageID string) ([]*entity.MessageEdit, error)
+
+	// MarkRevoked flags a message as deleted for everyone
+	MarkRevoked(ctx context.Context, instanceID uuid.UUID, messageID, revokedBy string, revokedAt time.Time) error
+
+	// DeleteByMessageID deletes a message using WhatsApp message ID
+	DeleteByMessageID(ctx context.Context, instanceID uuid.UUID, messageID string) error
+
 	// Delete deletes a message
 	Delete(ctx context.Context, id uuid.UUID) error
 
@@ -55,3 +73,20 @@ type MessageRepository interface {
 	// CountByDateRange counts messages within a date range
 	CountByDateRange(ctx context.Context, instanceID *uuid.UUID, start, end time.Time) (int64, error)
 }
+
+// MessageFilter narrows and paginates the messages of a chat
+type MessageFilter struct {
+	Types  []entity.MessageType
+	FromMe *bool
+	Since  *time.Time
+	Until  *time.Time
+	Before *MessageCursor // Only messages older than the cursor
+	After  *MessageCursor // Only messages newer than the cursor
+	Limit  int
+}
+
+// MessageCursor points at a position in the timeline of a chat
+type MessageCursor struct {
+	Timestamp time.Time
+	MessageID string // Breaks ties between messages sharing a timestamp, empty for a plain timestamp cursor
+}
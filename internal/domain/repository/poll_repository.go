@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+// PollRepository defines the interface for poll data access
+type PollRepository interface {
+	// Create stores a poll, ignoring polls already stored
+	Create(ctx context.Context, poll *entity.Poll) error
+
+	// GetByMessageID retrieves a poll by the WhatsApp ID of its message
+	GetByMessageID(ctx context.Context, instanceID uuid.UUID, messageID string) (*entity.Poll, error)
+
+	// SaveVote stores the vote of a voter, replacing their previous one
+	SaveVote(ctx context.Context, vote *entity.PollVote) error
+
+	// GetVotes retrieves the current votes of a poll
+	GetVotes(ctx context.Context, instanceID uuid.UUID, pollMessageID string) ([]*entity.PollVote, error)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+// WebhookDeliveryLogRepository defines the interface for the attempts made at webhook deliveries
+type WebhookDeliveryLogRepository interface {
+	// Create stores a delivery attempt
+	Create(ctx context.Context, log *entity.WebhookDeliveryLog) error
+
+	// ListByDelivery retrieves the attempts made at a delivery, oldest first
+	ListByDelivery(ctx context.Context, deliveryID uuid.UUID) ([]*entity.WebhookDeliveryLog, error)
+}
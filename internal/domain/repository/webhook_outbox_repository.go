@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+)
+
+// WebhookOutboxRepository defines the interface for the outbox of pending webhook deliveries
+type WebhookOutboxRepository interface {
+	// Enqueue stores deliveries to be sent by the workers
+	Enqueue(ctx context.Context, deliveries []*entity.WebhookDelivery) error
+
+	// Claim leases the next due delivery to a worker, counting it as an attempt. Deliveries whose
+	// lease expired are claimed again. It returns nil when nothing is due.
+	Claim(ctx context.Context, workerID string, lease time.Duration) (*entity.WebhookDelivery, error)
+
+	// MarkDelivered records the successful delivery of a claimed delivery
+	MarkDelivered(ctx context.Context, id uuid.UUID, workerID string) error
+
+	// Retry releases a claimed delivery to be attempted again after the given delay
+	Retry(ctx context.Context, id uuid.UUID, workerID string, delay time.Duration, lastError string) error
+
+	// Postpone releases a claimed delivery to be attempted after the given delay, without counting
+	// the claim as an attempt
+	Postpone(ctx context.Context, id uuid.UUID, workerID string, delay time.Duration) error
+
+	// MarkDeadLetter gives up a claimed delivery, moving it to the dead-letter state
+	MarkDeadLetter(ctx context.Context, id uuid.UUID, workerID string, lastError string) error
+
+	// DeleteDelivered removes the deliveries delivered longer than the given duration ago
+	DeleteDelivered(ctx context.Context, olderThan time.Duration) (int64, error)
+
+	// DeleteDeadLetters removes the dead-lettered deliveries created longer than the given duration ago
+	DeleteDeadLetters(ctx context.Context, olderThan time.Duration) (int64, error)
+
+	// GetByID retrieves a delivery by ID
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.WebhookDelivery, error)
+
+	// List retrieves the deliveries to the webhooks of an instance matching the filter, newest first.
+	// Deliveries to the global webhook are left out, it belongs to the server operator.
+	List(ctx context.Context, instanceID uuid.UUID, filter WebhookDeliveryFilter) ([]*entity.WebhookDelivery, error)
+
+	// Count counts the deliveries to the webhooks of an instance matching the filter
+	Count(ctx context.Context, instanceID uuid.UUID, filter WebhookDeliveryFilter) (int64, error)
+
+	// Redeliver queues a delivery that is not pending to be sent again right away, keeping its
+	// event ID. It returns false if the delivery is already pending.
+	Redeliver(ctx context.Context, id uuid.UUID) (bool, error)
+
+	// RedeliverDeadLetters queues the dead-letter deliveries to the webhooks of an instance created
+	// since the given time to be sent again, optionally only those of one webhook. It returns how many
+	// were queued.
+	RedeliverDeadLetters(ctx context.Context, instanceID uuid.UUID, since time.Time, webhookID *uuid.UUID) (int64, error)
+}
+
+// WebhookDeliveryFilter narrows and paginates the deliveries of an instance
+type WebhookDeliveryFilter struct {
+	Status    entity.WebhookDeliveryStatus // All statuses when empty
+	Event     entity.WebhookEvent          // All events when empty
+	WebhookID *uuid.UUID
+	Since     time.Time // Zero for no lower bound
+	Until     time.Time // Zero for no upper bound
+	Limit     int
+	Offset    int
+}
@@ -15,19 +15,22 @@ type WebhookRepository interface {
 	// GetByID retrieves a webhook by ID
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.Webhook, error)
 
-	// GetByInstance retrieves webhook configuration for an instance
+	// GetByInstance retrieves the first webhook configured for an instance
 	GetByInstance(ctx context.Context, instanceID uuid.UUID) (*entity.Webhook, error)
 
+	// ListByInstance retrieves all webhooks of an instance, oldest first
+	ListByInstance(ctx context.Context, instanceID uuid.UUID) ([]*entity.Webhook, error)
+
 	// Update updates a webhook configuration
 	Update(ctx context.Context, webhook *entity.Webhook) error
 
-	// Upsert creates or updates a webhook configuration
+	// Upsert creates or updates the first webhook of an instance
 	Upsert(ctx context.Context, webhook *entity.Webhook) error
 
 	// Delete deletes a webhook configuration
 	Delete(ctx context.Context, id uuid.UUID) error
 
-	// DeleteByInstance deletes webhook configuration for an instance
+	// DeleteByInstance deletes all webhooks of an instance
 	DeleteByInstance(ctx context.Context, instanceID uuid.UUID) error
 
 	// SetEnabled enables or disables a webhook
@@ -67,6 +67,24 @@ func RunMigrations(pool *pgxpool.Pool) error {
 		{5, migrationV5AddDeviceJID},
 		{6, migrationV6AddWebhookOptions},
 		{7, migrationV7InitAuth},
+		{8, migrationV8MessageEdits},
+		{9, migrationV9MessageRevokes},
+		{10, migrationV10InstanceSettings},
+		{11, migrationV11MessageMediaKeys},
+		{12, migrationV12ChatsAndContacts},
+		{13, migrationV13MessageReceipts},
+		{14, migrationV14MessageChatTimeline},
+		{15, migrationV15ChatLastMessage},
+		{16, migrationV16Polls},
+		{17, migrationV17InstanceProxy},
+		{18, migrationV18ConnectionEvents},
+		{19, migrationV19InstanceLeases},
+		{20, migrationV20MultipleWebhooks},
+		{21, migrationV21WebhookSecrets},
+		{22, migrationV22WebhookOutbox},
+		{23, migrationV23WebhookDeliveryLogs},
+		{24, migrationV24UniqueMessageIDs},
+		{25, migrationV25WebhookOutboxInstances},
 	}
 
 	for _, m := range migrations {
@@ -350,3 +368,227 @@ EXCEPTION
     WHEN duplicate_object THEN null;
 END $$;
 `
+
+const migrationV8MessageEdits = `
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS message_edits (
+	id UUID PRIMARY KEY,
+	instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+	message_id VARCHAR(100) NOT NULL,
+	previous_content TEXT,
+	new_content TEXT NOT NULL,
+	edited_by VARCHAR(100),
+	edited_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_edits_message_id ON message_edits(instance_id, message_id);
+`
+
+const migrationV9MessageRevokes = `
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS revoked_by VARCHAR(100);
+`
+
+const migrationV10InstanceSettings = `
+ALTER TABLE instances
+ADD COLUMN IF NOT EXISTS settings JSONB NOT NULL DEFAULT '{}'::jsonb;
+`
+
+const migrationV11MessageMediaKeys = `
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS media_file_name TEXT,
+ADD COLUMN IF NOT EXISTS media_direct_path TEXT,
+ADD COLUMN IF NOT EXISTS media_key BYTEA,
+ADD COLUMN IF NOT EXISTS media_file_sha256 BYTEA,
+ADD COLUMN IF NOT EXISTS media_file_enc_sha256 BYTEA,
+ADD COLUMN IF NOT EXISTS media_file_length BIGINT,
+ADD COLUMN IF NOT EXISTS media_type VARCHAR(50);
+`
+
+const migrationV12ChatsAndContacts = `
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS sender_jid VARCHAR(100);
+
+CREATE TABLE IF NOT EXISTS chats (
+	id UUID PRIMARY KEY,
+	instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+	jid VARCHAR(100) NOT NULL,
+	name VARCHAR(255),
+	is_group BOOLEAN NOT NULL DEFAULT false,
+	unread_count INTEGER NOT NULL DEFAULT 0,
+	archived BOOLEAN NOT NULL DEFAULT false,
+	pinned BOOLEAN NOT NULL DEFAULT false,
+	muted_until TIMESTAMP,
+	last_message_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW(),
+	UNIQUE(instance_id, jid)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chats_last_message_at ON chats(instance_id, last_message_at DESC);
+
+CREATE TABLE IF NOT EXISTS contacts (
+	id UUID PRIMARY KEY,
+	instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+	jid VARCHAR(100) NOT NULL,
+	phone_number VARCHAR(20),
+	name VARCHAR(255),
+	push_name VARCHAR(255),
+	business_name VARCHAR(255),
+	profile_pic TEXT,
+	is_blocked BOOLEAN NOT NULL DEFAULT false,
+	is_business BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW(),
+	UNIQUE(instance_id, jid)
+);
+`
+
+const migrationV13MessageReceipts = `
+ALTER TABLE messages
+ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS read_at TIMESTAMP,
+ADD COLUMN IF NOT EXISTS played_at TIMESTAMP;
+`
+
+const migrationV14MessageChatTimeline = `
+CREATE INDEX IF NOT EXISTS idx_messages_chat_timeline ON messages(instance_id, remote_jid, timestamp DESC, message_id DESC);
+`
+
+const migrationV15ChatLastMessage = `
+ALTER TABLE chats
+ADD COLUMN IF NOT EXISTS last_message_id VARCHAR(100),
+ADD COLUMN IF NOT EXISTS last_message_type VARCHAR(50),
+ADD COLUMN IF NOT EXISTS last_message_preview TEXT,
+ADD COLUMN IF NOT EXISTS last_message_from_me BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_chats_unread ON chats(instance_id) WHERE unread_count > 0;
+`
+
+const migrationV16Polls = `
+CREATE TABLE IF NOT EXISTS polls (
+	id UUID PRIMARY KEY,
+	instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+	message_id VARCHAR(100) NOT NULL,
+	chat_jid VARCHAR(100) NOT NULL,
+	creator_jid VARCHAR(100),
+	question TEXT NOT NULL,
+	options TEXT[] NOT NULL,
+	selectable_count INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT NOW(),
+	UNIQUE(instance_id, message_id)
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+	instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+	poll_message_id VARCHAR(100) NOT NULL,
+	voter_jid VARCHAR(100) NOT NULL,
+	selected_options TEXT[] NOT NULL,
+	voted_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (instance_id, poll_message_id, voter_jid)
+);
+`
+
+const migrationV17InstanceProxy = `
+ALTER TABLE instances
+ADD COLUMN IF NOT EXISTS proxy_url TEXT;
+`
+
+const migrationV18ConnectionEvents = `
+CREATE TABLE IF NOT EXISTS instance_connection_events (
+	id BIGSERIAL PRIMARY KEY,
+	instance_id UUID NOT NULL REFERENCES instances(id) ON DELETE CASCADE,
+	status VARCHAR(20) NOT NULL,
+	reason TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_instance_connection_events_timeline ON instance_connection_events(instance_id, created_at);
+`
+
+const migrationV19InstanceLeases = `
+CREATE TABLE IF NOT EXISTS instance_leases (
+	instance_id UUID PRIMARY KEY REFERENCES instances(id) ON DELETE CASCADE,
+	node_id VARCHAR(255) NOT NULL,
+	node_address TEXT,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_instance_leases_node ON instance_leases(node_id);
+`
+
+const migrationV20MultipleWebhooks = `
+-- Instances may have several webhooks, the oldest one is managed by the single webhook endpoints
+ALTER TABLE webhooks DROP CONSTRAINT IF EXISTS webhooks_instance_id_key;
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_instance_created ON webhooks(instance_id, created_at);
+`
+
+const migrationV21WebhookSecrets = `
+ALTER TABLE webhooks
+ADD COLUMN IF NOT EXISTS secret TEXT,
+ADD COLUMN IF NOT EXISTS previous_secret TEXT;
+`
+
+const migrationV22WebhookOutbox = `
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+	id UUID PRIMARY KEY,
+	event_id UUID NOT NULL,
+	instance_id UUID NOT NULL,
+	webhook_id UUID REFERENCES webhooks(id) ON DELETE CASCADE,
+	event VARCHAR(100) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	locked_by VARCHAR(255),
+	locked_until TIMESTAMP,
+	last_error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_due ON webhook_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_delivered ON webhook_outbox(delivered_at) WHERE status = 'delivered';
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_instance ON webhook_outbox(instance_id, created_at);
+`
+
+const migrationV23WebhookDeliveryLogs = `
+UPDATE webhook_outbox SET status = 'dead_letter' WHERE status = 'failed';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_dead_letter ON webhook_outbox(instance_id, created_at) WHERE status = 'dead_letter';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_logs (
+	id BIGSERIAL PRIMARY KEY,
+	delivery_id UUID NOT NULL REFERENCES webhook_outbox(id) ON DELETE CASCADE,
+	attempt INTEGER NOT NULL,
+	url TEXT NOT NULL,
+	status_code INTEGER,
+	response TEXT,
+	success BOOLEAN NOT NULL,
+	latency_ms INTEGER NOT NULL,
+	error TEXT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_logs_delivery ON webhook_delivery_logs(delivery_id, id);
+`
+
+const migrationV24UniqueMessageIDs = `
+DELETE FROM messages a USING messages b
+WHERE a.instance_id = b.instance_id AND a.message_id = b.message_id
+	AND (a.created_at, a.id) > (b.created_at, b.id);
+
+DROP INDEX IF EXISTS idx_messages_message_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_message_id ON messages(instance_id, message_id);
+`
+
+const migrationV25WebhookOutboxInstances = `
+DELETE FROM webhook_outbox o WHERE NOT EXISTS (SELECT 1 FROM instances i WHERE i.id = o.instance_id);
+
+ALTER TABLE webhook_outbox
+	ADD CONSTRAINT webhook_outbox_instance_id_fkey FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE;
+`
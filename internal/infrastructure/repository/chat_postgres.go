@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+)
+
+// chatPostgresRepository implements ChatRepository using PostgreSQL
+type chatPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewChatPostgresRepository creates a new PostgreSQL-based chat repository
+func NewChatPostgresRepository(pool *pgxpool.Pool) repository.ChatRepository {
+	return &chatPostgresRepository{pool: pool}
+}
+
+// chatColumns lists the columns selected for every chat query.
+// Chats without a name of their own fall back to the name of the contact.
+const chatColumns = `chats.id, chats.instance_id, chats.jid,
+	COALESCE(NULLIF(chats.name, ''), NULLIF(contacts.name, ''), NULLIF(contacts.push_name, ''), NULLIF(contacts.business_name, '')),
+	chats.is_group, chats.unread_count, chats.archived, chats.pinned, chats.muted_until, chats.last_message_at,
+	chats.last_message_id, chats.last_message_type, chats.last_message_preview, chats.last_message_from_me,
+	chats.created_at, chats.updated_at`
+
+// chatFrom joins the contact of each chat to resolve its name
+const chatFrom = ` FROM chats LEFT JOIN contacts ON contacts.instance_id = chats.instance_id AND contacts.jid = chats.jid`
+
+// chatIsNewer is true when the inserted row carries a message at least as recent as the stored one
+const chatIsNewer = `(chats.last_message_at IS NULL OR EXCLUDED.last_message_at >= chats.last_message_at)`
+
+// upsertChatQuery keeps the existing name when the update carries none. The unread count, archive,
+// pin and mute state only fill new chats: history sync can arrive after the state was changed
+// through app state or the API, and must not undo it.
+const upsertChatQuery = `
+	INSERT INTO chats (id, instance_id, jid, name, is_group, unread_count, archived, pinned, muted_until, last_message_at,
+		last_message_id, last_message_type, last_message_preview, last_message_from_me, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	ON CONFLICT (instance_id, jid) DO UPDATE SET
+		name = COALESCE(NULLIF(EXCLUDED.name, ''), chats.name),
+		is_group = EXCLUDED.is_group,
+		last_message_id = CASE WHEN ` + chatIsNewer + ` AND EXCLUDED.last_message_id <> '' THEN EXCLUDED.last_message_id ELSE chats.last_message_id END,
+		last_message_type = CASE WHEN ` + chatIsNewer + ` AND EXCLUDED.last_message_id <> '' THEN EXCLUDED.last_message_type ELSE chats.last_message_type END,
+		last_message_preview = CASE WHEN ` + chatIsNewer + ` AND EXCLUDED.last_message_id <> '' THEN EXCLUDED.last_message_preview ELSE chats.last_message_preview END,
+		last_message_from_me = CASE WHEN ` + chatIsNewer + ` AND EXCLUDED.last_message_id <> '' THEN EXCLUDED.last_message_from_me ELSE chats.last_message_from_me END,
+		last_message_at = GREATEST(chats.last_message_at, EXCLUDED.last_message_at),
+		updated_at = EXCLUDED.updated_at
+`
+
+// Upsert creates or updates a chat by instance and JID
+func (r *chatPostgresRepository) Upsert(ctx context.Context, chat *entity.Chat) error {
+	if _, err := r.pool.Exec(ctx, upsertChatQuery, chatUpsertArgs(chat)...); err != nil {
+		return fmt.Errorf("failed to upsert chat: %w", err)
+	}
+	return nil
+}
+
+// UpsertBatch creates or updates several chats at once
+func (r *chatPostgresRepository) UpsertBatch(ctx context.Context, chats []*entity.Chat) error {
+	if len(chats) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, chat := range chats {
+		batch.Queue(upsertChatQuery, chatUpsertArgs(chat)...)
+	}
+
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to upsert chats: %w", err)
+	}
+	return nil
+}
+
+// RecordMessage sets the last message of a chat, creating the chat if needed
+func (r *chatPostgresRepository) RecordMessage(ctx context.Context, chat *entity.Chat) error {
+	query := `
+		INSERT INTO chats (id, instance_id, jid, name, is_group, unread_count, archived, pinned, muted_until, last_message_at,
+			last_message_id, last_message_type, last_message_preview, last_message_from_me, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (instance_id, jid) DO UPDATE SET
+			name = COALESCE(NULLIF(chats.name, ''), NULLIF(EXCLUDED.name, '')),
+			unread_count = CASE WHEN EXCLUDED.last_message_from_me THEN 0 ELSE chats.unread_count + 1 END,
+			last_message_id = CASE WHEN ` + chatIsNewer + ` THEN EXCLUDED.last_message_id ELSE chats.last_message_id END,
+			last_message_type = CASE WHEN ` + chatIsNewer + ` THEN EXCLUDED.last_message_type ELSE chats.last_message_type END,
+			last_message_preview = CASE WHEN ` + chatIsNewer + ` THEN EXCLUDED.last_message_preview ELSE chats.last_message_preview END,
+			last_message_from_me = CASE WHEN ` + chatIsNewer + ` THEN EXCLUDED.last_message_from_me ELSE chats.last_message_from_me END,
+			last_message_at = GREATEST(chats.last_message_at, EXCLUDED.last_message_at),
+			updated_at = EXCLUDED.updated_at
+	`
+	// A new chat starts with the message itself as unread, unless we sent it
+	unread := 1
+	if chat.LastMessageFromMe {
+		unread = 0
+	}
+	args := chatUpsertArgs(chat)
+	args[5] = unread
+
+	if _, err := r.pool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record chat message: %w", err)
+	}
+	return nil
+}
+
+// GetByJID retrieves a chat by its JID
+func (r *chatPostgresRepository) GetByJID(ctx context.Context, instanceID uuid.UUID, jid string) (*entity.Chat, error) {
+	query := `SELECT ` + chatColumns + chatFrom + ` WHERE chats.instance_id = $1 AND chats.jid = $2`
+	chat, err := scanChatRow(r.pool.QueryRow(ctx, query, instanceID, jid))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to scan chat: %w", err)
+	}
+	return chat, nil
+}
+
+// List retrieves the chats of an instance, pinned chats first
+func (r *chatPostgresRepository) List(ctx context.Context, instanceID uuid.UUID, filter repository.ChatFilter) ([]*entity.Chat, error) {
+	where, args := chatFilterConditions(instanceID, filter)
+
+	direction := "DESC"
+	if filter.Ascending {
+		direction = "ASC"
+	}
+
+	var order string
+	switch filter.Sort {
+	case repository.ChatSortUnread:
+		order = "chats.unread_count " + direction + ", chats.last_message_at DESC NULLS LAST"
+	case repository.ChatSortName:
+		order = "LOWER(COALESCE(NULLIF(chats.name, ''), NULLIF(contacts.name, ''), NULLIF(contacts.push_name, ''), chats.jid)) " + direction
+	default:
+		order = "chats.last_message_at " + direction + " NULLS LAST"
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `SELECT ` + chatColumns + chatFrom + ` WHERE ` + where +
+		` ORDER BY chats.pinned DESC, ` + order + `, chats.jid` +
+		fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chats: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []*entity.Chat
+	for rows.Next() {
+		chat, err := scanChatRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat row: %w", err)
+		}
+		chats = append(chats, chat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chats: %w", err)
+	}
+
+	return chats, nil
+}
+
+// Count counts the chats of an instance matching the filter
+func (r *chatPostgresRepository) Count(ctx context.Context, instanceID uuid.UUID, filter repository.ChatFilter) (int64, error) {
+	where, args := chatFilterConditions(instanceID, filter)
+
+	var count int64
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM chats WHERE `+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count chats: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateName updates the name of a chat
+func (r *chatPostgresRepository) UpdateName(ctx context.Context, instanceID uuid.UUID, jid, name string) error {
+	query := `UPDATE chats SET name = $3, updated_at = NOW() WHERE instance_id = $1 AND jid = $2`
+	_, err := r.pool.Exec(ctx, query, instanceID, jid, name)
+	if err != nil {
+		return fmt.Errorf("failed to update chat name: %w", err)
+	}
+	return nil
+}
+
+// MarkRead clears the unread count of a chat
+func (r *chatPostgresRepository) MarkRead(ctx context.Context, instanceID uuid.UUID, jid string) error {
+	query := `UPDATE chats SET unread_count = 0, updated_at = NOW() WHERE instance_id = $1 AND jid = $2 AND unread_count <> 0`
+	_, err := r.pool.Exec(ctx, query, instanceID, jid)
+	if err != nil {
+		return fmt.Errorf("failed to mark chat as read: %w", err)
+	}
+	return nil
+}
+
+// MarkUnread flags a chat as having unread messages, creating the chat if needed
+func (r *chatPostgresRepository) MarkUnread(ctx context.Context, instanceID uuid.UUID, jid string) error {
+	if err := r.setState(ctx, instanceID, jid, "unread_count", 1, "GREATEST(chats.unread_count, 1)"); err != nil {
+		return fmt.Errorf("failed to mark chat as unread: %w", err)
+	}
+	return nil
+}
+
+// SetArchived archives or unarchives a chat, creating the chat if needed. Archived chats are unpinned.
+func (r *chatPostgresRepository) SetArchived(ctx context.Context, instanceID uuid.UUID, jid string, archived bool) error {
+	set := "EXCLUDED.archived, pinned = chats.pinned AND NOT EXCLUDED.archived"
+	if err := r.setState(ctx, instanceID, jid, "archived", archived, set); err != nil {
+		return fmt.Errorf("failed to update chat archive state: %w", err)
+	}
+	return nil
+}
+
+// SetPinned pins or unpins a chat, creating the chat if needed
+func (r *chatPostgresRepository) SetPinned(ctx context.Context, instanceID uuid.UUID, jid string, pinned bool) error {
+	if err := r.setState(ctx, instanceID, jid, "pinned", pinned, "EXCLUDED.pinned"); err != nil {
+		return fmt.Errorf("failed to update chat pin state: %w", err)
+	}
+	return nil
+}
+
+// SetMutedUntil mutes a chat until the given time, or unmutes it when nil, creating the chat if needed
+func (r *chatPostgresRepository) SetMutedUntil(ctx context.Context, instanceID uuid.UUID, jid string, mutedUntil *time.Time) error {
+	if err := r.setState(ctx, instanceID, jid, "muted_until", mutedUntil, "EXCLUDED.muted_until"); err != nil {
+		return fmt.Errorf("failed to update chat mute state: %w", err)
+	}
+	return nil
+}
+
+// setState writes one state column of a chat, creating the chat if it is not known yet.
+// The update expression decides how the new value is merged into an existing row.
+func (r *chatPostgresRepository) setState(ctx context.Context, instanceID uuid.UUID, jid, column string, value interface{}, update string) error {
+	query := `
+		INSERT INTO chats (id, instance_id, jid, ` + column + `, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (instance_id, jid) DO UPDATE SET ` + column + ` = ` + update + `, updated_at = NOW()
+	`
+	_, err := r.pool.Exec(ctx, query, uuid.New(), instanceID, jid, value)
+	return err
+}
+
+// DeleteByInstance deletes all chats for an instance
+func (r *chatPostgresRepository) DeleteByInstance(ctx context.Context, instanceID uuid.UUID) error {
+	query := `DELETE FROM chats WHERE instance_id = $1`
+	_, err := r.pool.Exec(ctx, query, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete chats: %w", err)
+	}
+	return nil
+}
+
+// chatFilterConditions builds the WHERE clause selecting the chats matching a filter
+func chatFilterConditions(instanceID uuid.UUID, filter repository.ChatFilter) (string, []interface{}) {
+	conditions := []string{"chats.instance_id = $1"}
+	args := []interface{}{instanceID}
+
+	if filter.Archived != nil {
+		args = append(args, *filter.Archived)
+		conditions = append(conditions, fmt.Sprintf("chats.archived = $%d", len(args)))
+	}
+	if filter.Pinned != nil {
+		args = append(args, *filter.Pinned)
+		conditions = append(conditions, fmt.Sprintf("chats.pinned = $%d", len(args)))
+	}
+	if filter.UnreadOnly {
+		conditions = append(conditions, "chats.unread_count > 0")
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// chatUpsertArgs returns the arguments of upsertChatQuery for a chat
+func chatUpsertArgs(chat *entity.Chat) []interface{} {
+	return []interface{}{
+		chat.ID,
+		chat.InstanceID,
+		chat.JID,
+		chat.Name,
+		chat.IsGroup,
+		chat.UnreadCount,
+		chat.Archived,
+		chat.Pinned,
+		chat.MutedUntil,
+		chat.LastMessageAt,
+		chat.LastMessageID,
+		string(chat.LastMessageType),
+		chat.LastMessagePreview,
+		chat.LastMessageFromMe,
+		chat.CreatedAt,
+		chat.UpdatedAt,
+	}
+}
+
+// scanChatRow scans the columns listed in chatColumns into a chat entity
+func scanChatRow(row pgx.Row) (*entity.Chat, error) {
+	var chat entity.Chat
+	var name, lastMessageID, lastMessageType, lastMessagePreview *string
+
+	err := row.Scan(
+		&chat.ID,
+		&chat.InstanceID,
+		&chat.JID,
+		&name,
+		&chat.IsGroup,
+		&chat.UnreadCount,
+		&chat.Archived,
+		&chat.Pinned,
+		&chat.MutedUntil,
+		&chat.LastMessageAt,
+		&lastMessageID,
+		&lastMessageType,
+		&lastMessagePreview,
+		&chat.LastMessageFromMe,
+		&chat.CreatedAt,
+		&chat.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != nil {
+		chat.Name = *name
+	}
+	if lastMessageID != nil {
+		chat.LastMessageID = *lastMessageID
+	}
+	if lastMessageType != nil {
+		chat.LastMessageType = entity.MessageType(*lastMessageType)
+	}
+	if lastMessagePreview != nil {
+		chat.LastMessagePreview = *lastMessagePreview
+	}
+
+	return &chat, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+)
+
+// connectionEventPostgresRepository implements ConnectionEventRepository using PostgreSQL
+type connectionEventPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewConnectionEventPostgresRepository creates a new PostgreSQL-based connection event repository
+func NewConnectionEventPostgresRepository(pool *pgxpool.Pool) repository.ConnectionEventRepository {
+	return &connectionEventPostgresRepository{pool: pool}
+}
+
+// Create stores a connection event
+func (r *connectionEventPostgresRepository) Create(ctx context.Context, event *entity.ConnectionEvent) error {
+	query := `
+		INSERT INTO instance_connection_events (instance_id, status, reason, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING id
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		event.InstanceID,
+		string(event.Status),
+		event.Reason,
+		event.CreatedAt,
+	).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create connection event: %w", err)
+	}
+	return nil
+}
+
+// List retrieves the connection events of an instance within a time range, oldest first
+func (r *connectionEventPostgresRepository) List(ctx context.Context, instanceID uuid.UUID, since, until time.Time) ([]*entity.ConnectionEvent, error) {
+	query := `
+		SELECT id, instance_id, status, reason, created_at
+		FROM instance_connection_events
+		WHERE instance_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, instanceID, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list connection events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.ConnectionEvent
+	for rows.Next() {
+		event, err := scanConnectionEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list connection events: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetLastBefore retrieves the last connection event of an instance before the given time
+func (r *connectionEventPostgresRepository) GetLastBefore(ctx context.Context, instanceID uuid.UUID, before time.Time) (*entity.ConnectionEvent, error) {
+	query := `
+		SELECT id, instance_id, status, reason, created_at
+		FROM instance_connection_events
+		WHERE instance_id = $1 AND created_at < $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+
+	event, err := scanConnectionEvent(r.pool.QueryRow(ctx, query, instanceID, before))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// scanConnectionEvent scans a connection event row
+func scanConnectionEvent(row pgx.Row) (*entity.ConnectionEvent, error) {
+	var event entity.ConnectionEvent
+	var status string
+	var reason *string
+
+	if err := row.Scan(&event.ID, &event.InstanceID, &status, &reason, &event.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan connection event: %w", err)
+	}
+
+	event.Status = entity.InstanceStatus(status)
+	if reason != nil {
+		event.Reason = *reason
+	}
+	return &event, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+)
+
+// contactPostgresRepository implements ContactRepository using PostgreSQL
+type contactPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewContactPostgresRepository creates a new PostgreSQL-based contact repository
+func NewContactPostgresRepository(pool *pgxpool.Pool) repository.ContactRepository {
+	return &contactPostgresRepository{pool: pool}
+}
+
+// upsertContactQuery only overwrites the names that the update actually carries
+const upsertContactQuery = `
+	INSERT INTO contacts (id, instance_id, jid, phone_number, name, push_name, business_name, is_business, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (instance_id, jid) DO UPDATE SET
+		phone_number = COALESCE(NULLIF(EXCLUDED.phone_number, ''), contacts.phone_number),
+		name = COALESCE(NULLIF(EXCLUDED.name, ''), contacts.name),
+		push_name = COALESCE(NULLIF(EXCLUDED.push_name, ''), contacts.push_name),
+		business_name = COALESCE(NULLIF(EXCLUDED.business_name, ''), contacts.business_name),
+		is_business = contacts.is_business OR EXCLUDED.is_business,
+		updated_at = EXCLUDED.updated_at
+`
+
+// Upsert creates or updates a contact by instance and JID
+func (r *contactPostgresRepository) Upsert(ctx context.Context, contact *entity.Contact) error {
+	if _, err := r.pool.Exec(ctx, upsertContactQuery, contactUpsertArgs(contact)...); err != nil {
+		return fmt.Errorf("failed to upsert contact: %w", err)
+	}
+	return nil
+}
+
+// UpsertBatch creates or updates several contacts at once
+func (r *contactPostgresRepository) UpsertBatch(ctx context.Context, contacts []*entity.Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, contact := range contacts {
+		batch.Queue(upsertContactQuery, contactUpsertArgs(contact)...)
+	}
+
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to upsert contacts: %w", err)
+	}
+	return nil
+}
+
+// GetByJID retrieves a contact by its JID
+func (r *contactPostgresRepository) GetByJID(ctx context.Context, instanceID uuid.UUID, jid string) (*entity.Contact, error) {
+	query := `
+		SELECT id, instance_id, jid, phone_number, name, push_name, business_name, profile_pic, is_blocked, is_business, created_at, updated_at
+		FROM contacts WHERE instance_id = $1 AND jid = $2
+	`
+
+	var contact entity.Contact
+	var phoneNumber, name, pushName, businessName, profilePic *string
+
+	err := r.pool.QueryRow(ctx, query, instanceID, jid).Scan(
+		&contact.ID,
+		&contact.InstanceID,
+		&contact.JID,
+		&phoneNumber,
+		&name,
+		&pushName,
+		&businessName,
+		&profilePic,
+		&contact.IsBlocked,
+		&contact.IsBusiness,
+		&contact.CreatedAt,
+		&contact.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to scan contact: %w", err)
+	}
+
+	if phoneNumber != nil {
+		contact.PhoneNumber = *phoneNumber
+	}
+	if name != nil {
+		contact.Name = *name
+	}
+	if pushName != nil {
+		contact.PushName = *pushName
+	}
+	if businessName != nil {
+		contact.BusinessName = *businessName
+	}
+	if profilePic != nil {
+		contact.ProfilePic = *profilePic
+	}
+
+	return &contact, nil
+}
+
+// DeleteByInstance deletes all contacts for an instance
+func (r *contactPostgresRepository) DeleteByInstance(ctx context.Context, instanceID uuid.UUID) error {
+	query := `DELETE FROM contacts WHERE instance_id = $1`
+	_, err := r.pool.Exec(ctx, query, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete contacts: %w", err)
+	}
+	return nil
+}
+
+// contactUpsertArgs returns the arguments of upsertContactQuery for a contact
+func contactUpsertArgs(contact *entity.Contact) []interface{} {
+	return []interface{}{
+		contact.ID,
+		contact.InstanceID,
+		contact.JID,
+		contact.PhoneNumber,
+		contact.Name,
+		contact.PushName,
+		contact.BusinessName,
+		contact.IsBusiness,
+		contact.CreatedAt,
+		contact.UpdatedAt,
+	}
+}
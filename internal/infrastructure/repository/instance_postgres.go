@@ -25,8 +25,8 @@ func NewInstancePostgresRepository(pool *pgxpool.Pool) repository.InstanceReposi
 // Create creates a new instance
 func (r *instancePostgresRepository) Create(ctx context.Context, instance *entity.Instance) error {
 	query := `
-		INSERT INTO instances (id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO instances (id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, settings, proxy_url, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), $13, $14)
 	`
 	var userID *string
 	if instance.UserID != "" {
@@ -44,6 +44,8 @@ func (r *instancePostgresRepository) Create(ctx context.Context, instance *entit
 		instance.ProfilePic,
 		instance.QRCode,
 		instance.DeviceJID,
+		instance.Settings,
+		instance.ProxyURL,
 		instance.CreatedAt,
 		instance.UpdatedAt,
 	)
@@ -56,7 +58,7 @@ func (r *instancePostgresRepository) Create(ctx context.Context, instance *entit
 // GetByID retrieves an instance by ID
 func (r *instancePostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Instance, error) {
 	query := `
-		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, created_at, updated_at
+		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, settings, proxy_url, created_at, updated_at
 		FROM instances WHERE id = $1
 	`
 	return r.scanInstance(ctx, query, id)
@@ -65,7 +67,7 @@ func (r *instancePostgresRepository) GetByID(ctx context.Context, id uuid.UUID)
 // GetByName retrieves an instance by name
 func (r *instancePostgresRepository) GetByName(ctx context.Context, name string) (*entity.Instance, error) {
 	query := `
-		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, created_at, updated_at
+		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, settings, proxy_url, created_at, updated_at
 		FROM instances WHERE name = $1
 	`
 	return r.scanInstance(ctx, query, name)
@@ -74,7 +76,7 @@ func (r *instancePostgresRepository) GetByName(ctx context.Context, name string)
 // GetByAPIKey retrieves an instance by API key
 func (r *instancePostgresRepository) GetByAPIKey(ctx context.Context, apiKey string) (*entity.Instance, error) {
 	query := `
-		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, created_at, updated_at
+		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, settings, proxy_url, created_at, updated_at
 		FROM instances WHERE api_key = $1
 	`
 	return r.scanInstance(ctx, query, apiKey)
@@ -83,7 +85,7 @@ func (r *instancePostgresRepository) GetByAPIKey(ctx context.Context, apiKey str
 // GetAll retrieves all instances
 func (r *instancePostgresRepository) GetAll(ctx context.Context) ([]*entity.Instance, error) {
 	query := `
-		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, created_at, updated_at
+		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, settings, proxy_url, created_at, updated_at
 		FROM instances ORDER BY created_at DESC
 	`
 	rows, err := r.pool.Query(ctx, query)
@@ -111,7 +113,7 @@ func (r *instancePostgresRepository) GetAll(ctx context.Context) ([]*entity.Inst
 // GetByUserID retrieves instances owned by a specific user.
 func (r *instancePostgresRepository) GetByUserID(ctx context.Context, userID string) ([]*entity.Instance, error) {
 	query := `
-		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, created_at, updated_at
+		SELECT id, name, api_key, user_id, status, phone_number, profile_name, profile_pic, qr_code, device_jid, settings, proxy_url, created_at, updated_at
 		FROM instances
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -173,6 +175,26 @@ func (r *instancePostgresRepository) UpdateStatus(ctx context.Context, id uuid.U
 	return nil
 }
 
+// UpdateSettings updates only the settings of an instance
+func (r *instancePostgresRepository) UpdateSettings(ctx context.Context, id uuid.UUID, settings entity.InstanceSettings) error {
+	query := `UPDATE instances SET settings = $2, updated_at = $3 WHERE id = $1`
+	_, err := r.pool.Exec(ctx, query, id, settings, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update instance settings: %w", err)
+	}
+	return nil
+}
+
+// UpdateProxy updates only the proxy of an instance, an empty URL clears it
+func (r *instancePostgresRepository) UpdateProxy(ctx context.Context, id uuid.UUID, proxyURL string) error {
+	query := `UPDATE instances SET proxy_url = NULLIF($2, ''), updated_at = $3 WHERE id = $1`
+	_, err := r.pool.Exec(ctx, query, id, proxyURL, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update instance proxy: %w", err)
+	}
+	return nil
+}
+
 // Delete deletes an instance
 func (r *instancePostgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM instances WHERE id = $1`
@@ -200,7 +222,7 @@ func (r *instancePostgresRepository) scanInstance(ctx context.Context, query str
 
 	var instance entity.Instance
 	var status string
-	var phoneNumber, profileName, profilePic, qrCode, deviceJID, userID *string
+	var phoneNumber, profileName, profilePic, qrCode, deviceJID, userID, proxyURL *string
 
 	err := row.Scan(
 		&instance.ID,
@@ -213,6 +235,8 @@ func (r *instancePostgresRepository) scanInstance(ctx context.Context, query str
 		&profilePic,
 		&qrCode,
 		&deviceJID,
+		&instance.Settings,
+		&proxyURL,
 		&instance.CreatedAt,
 		&instance.UpdatedAt,
 	)
@@ -242,6 +266,9 @@ func (r *instancePostgresRepository) scanInstance(ctx context.Context, query str
 	if deviceJID != nil {
 		instance.DeviceJID = *deviceJID
 	}
+	if proxyURL != nil {
+		instance.ProxyURL = *proxyURL
+	}
 
 	return &instance, nil
 }
@@ -250,7 +277,7 @@ func (r *instancePostgresRepository) scanInstance(ctx context.Context, query str
 func (r *instancePostgresRepository) scanInstanceRow(rows pgx.Rows) (*entity.Instance, error) {
 	var instance entity.Instance
 	var status string
-	var phoneNumber, profileName, profilePic, qrCode, deviceJID, userID *string
+	var phoneNumber, profileName, profilePic, qrCode, deviceJID, userID, proxyURL *string
 
 	err := rows.Scan(
 		&instance.ID,
@@ -263,6 +290,8 @@ func (r *instancePostgresRepository) scanInstanceRow(rows pgx.Rows) (*entity.Ins
 		&profilePic,
 		&qrCode,
 		&deviceJID,
+		&instance.Settings,
+		&proxyURL,
 		&instance.CreatedAt,
 		&instance.UpdatedAt,
 	)
@@ -289,6 +318,9 @@ func (r *instancePostgresRepository) scanInstanceRow(rows pgx.Rows) (*entity.Ins
 	if deviceJID != nil {
 		instance.DeviceJID = *deviceJID
 	}
+	if proxyURL != nil {
+		instance.ProxyURL = *proxyURL
+	}
 
 	return &instance, nil
 }
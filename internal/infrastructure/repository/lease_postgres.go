@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+)
+
+// leasePostgresRepository implements LeaseRepository using PostgreSQL
+type leasePostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewLeasePostgresRepository creates a new PostgreSQL-based lease repository
+func NewLeasePostgresRepository(pool *pgxpool.Pool) repository.LeaseRepository {
+	return &leasePostgresRepository{pool: pool}
+}
+
+// Acquire takes or renews the lease of an instance for a node
+func (r *leasePostgresRepository) Acquire(ctx context.Context, instanceID uuid.UUID, nodeID, nodeAddress string, ttl time.Duration) (*entity.InstanceLease, bool, error) {
+	// The update only applies when the node already holds the lease or the holder let it expire
+	query := `
+		INSERT INTO instance_leases (instance_id, node_id, node_address, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), NOW() + $4 * INTERVAL '1 millisecond')
+		ON CONFLICT (instance_id) DO UPDATE SET
+			node_id = EXCLUDED.node_id,
+			node_address = EXCLUDED.node_address,
+			expires_at = EXCLUDED.expires_at
+		WHERE instance_leases.node_id = EXCLUDED.node_id OR instance_leases.expires_at < NOW()
+		RETURNING instance_id, node_id, node_address, expires_at
+	`
+
+	lease, err := scanLease(r.pool.QueryRow(ctx, query, instanceID, nodeID, nodeAddress, ttl.Milliseconds()))
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	if lease != nil {
+		return lease, true, nil
+	}
+
+	// Held by another node
+	lease, err = r.Get(ctx, instanceID)
+	if err != nil {
+		return nil, false, err
+	}
+	return lease, false, nil
+}
+
+// Get retrieves the lease of an instance
+func (r *leasePostgresRepository) Get(ctx context.Context, instanceID uuid.UUID) (*entity.InstanceLease, error) {
+	query := `SELECT instance_id, node_id, node_address, expires_at FROM instance_leases WHERE instance_id = $1`
+
+	lease, err := scanLease(r.pool.QueryRow(ctx, query, instanceID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get lease: %w", err)
+	}
+	return lease, nil
+}
+
+// RenewAll extends every lease held by a node and returns the instances it still holds
+func (r *leasePostgresRepository) RenewAll(ctx context.Context, nodeID string, ttl time.Duration) ([]uuid.UUID, error) {
+	query := `
+		UPDATE instance_leases SET expires_at = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE node_id = $1
+		RETURNING instance_id
+	`
+
+	rows, err := r.pool.Query(ctx, query, nodeID, ttl.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to renew leases: %w", err)
+	}
+	defer rows.Close()
+
+	var instanceIDs []uuid.UUID
+	for rows.Next() {
+		var instanceID uuid.UUID
+		if err := rows.Scan(&instanceID); err != nil {
+			return nil, fmt.Errorf("failed to scan lease: %w", err)
+		}
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to renew leases: %w", err)
+	}
+
+	return instanceIDs, nil
+}
+
+// Release gives up the lease of an instance held by a node
+func (r *leasePostgresRepository) Release(ctx context.Context, instanceID uuid.UUID, nodeID string) error {
+	query := `DELETE FROM instance_leases WHERE instance_id = $1 AND node_id = $2`
+	if _, err := r.pool.Exec(ctx, query, instanceID, nodeID); err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
+
+// ReleaseAll gives up every lease held by a node
+func (r *leasePostgresRepository) ReleaseAll(ctx context.Context, nodeID string) error {
+	query := `DELETE FROM instance_leases WHERE node_id = $1`
+	if _, err := r.pool.Exec(ctx, query, nodeID); err != nil {
+		return fmt.Errorf("failed to release leases: %w", err)
+	}
+	return nil
+}
+
+// ListOrphaned lists the paired instances that were online and whose lease expired or never existed
+func (r *leasePostgresRepository) ListOrphaned(ctx context.Context) ([]uuid.UUID, error) {
+	query := `
+		SELECT instances.id
+		FROM instances
+		LEFT JOIN instance_leases ON instance_leases.instance_id = instances.id
+		WHERE instances.device_jid IS NOT NULL AND instances.device_jid <> ''
+			AND instances.status IN ('connected', 'connecting', 'reconnecting')
+			AND (instance_leases.instance_id IS NULL OR instance_leases.expires_at < NOW())
+	`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orphaned instances: %w", err)
+	}
+	defer rows.Close()
+
+	var instanceIDs []uuid.UUID
+	for rows.Next() {
+		var instanceID uuid.UUID
+		if err := rows.Scan(&instanceID); err != nil {
+			return nil, fmt.Errorf("failed to scan orphaned instance: %w", err)
+		}
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list orphaned instances: %w", err)
+	}
+
+	return instanceIDs, nil
+}
+
+// scanLease scans a lease row
+func scanLease(row pgx.Row) (*entity.InstanceLease, error) {
+	var lease entity.InstanceLease
+	var nodeAddress *string
+
+	if err := row.Scan(&lease.InstanceID, &lease.NodeID, &nodeAddress, &lease.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if nodeAddress != nil {
+		lease.NodeAddress = *nodeAddress
+	}
+	return &lease, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,13 +23,72 @@ func NewMessagePostgresRepository(pool *pgxpool.Pool) repository.MessageReposito
 	return &messagePostgresRepository{pool: pool}
 }
 
+// messageColumns lists the columns selected for every message query
+const messageColumns = `id, instance_id, message_id, remote_jid, sender_jid, from_me, type, status, content, media_url, media_mime_type, media_caption, media_file_name, media_direct_path, media_key, media_file_sha256, media_file_enc_sha256, media_file_length, media_type, quoted_msg_id, timestamp, delivered_at, read_at, played_at, edited_at, revoked_at, revoked_by, created_at`
+
+// insertMessageQuery inserts a message using the arguments built by messageInsertArgs, skipping it
+// when the instance already stored a message with the same ID
+const insertMessageQuery = `
+	INSERT INTO messages (id, instance_id, message_id, remote_jid, from_me, type, status, content, media_url, media_mime_type, media_caption, quoted_msg_id, timestamp, created_at,
+		media_file_name, media_direct_path, media_key, media_file_sha256, media_file_enc_sha256, media_file_length, media_type, sender_jid)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+	ON CONFLICT (instance_id, message_id) DO NOTHING
+`
+
 // Create creates a new message record
 func (r *messagePostgresRepository) Create(ctx context.Context, message *entity.Message) error {
-	query := `
-		INSERT INTO messages (id, instance_id, message_id, remote_jid, from_me, type, status, content, media_url, media_mime_type, media_caption, quoted_msg_id, timestamp, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
-	`
-	_, err := r.pool.Exec(ctx, query,
+	_, err := r.pool.Exec(ctx, insertMessageQuery, messageInsertArgs(message)...)
+	if err != nil {
+		return fmt.Errorf("failed to create message: %w", err)
+	}
+	return nil
+}
+
+// CreateBatch creates the given messages, skipping those already stored
+func (r *messagePostgresRepository) CreateBatch(ctx context.Context, instanceID uuid.UUID, messages []*entity.Message) (int, error) {
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, message := range messages {
+		batch.Queue(insertMessageQuery, messageInsertArgs(message)...)
+	}
+
+	results := r.pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	inserted := 0
+	for range messages {
+		result, err := results.Exec()
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert messages: %w", err)
+		}
+		inserted += int(result.RowsAffected())
+	}
+
+	if err := results.Close(); err != nil {
+		return 0, fmt.Errorf("failed to insert messages: %w", err)
+	}
+
+	return inserted, nil
+}
+
+// messageInsertArgs returns the arguments of insertMessageQuery for a message
+func messageInsertArgs(message *entity.Message) []interface{} {
+	var directPath, mediaType *string
+	var mediaKey, fileSHA256, fileEncSHA256 []byte
+	var fileLength *int64
+	if keys := message.MediaKeys; keys != nil {
+		directPath = &keys.DirectPath
+		mediaType = &keys.MediaType
+		mediaKey = keys.MediaKey
+		fileSHA256 = keys.FileSHA256
+		fileEncSHA256 = keys.FileEncSHA256
+		fileLength = &keys.FileLength
+	}
+
+	return []interface{}{
 		message.ID,
 		message.InstanceID,
 		message.MessageID,
@@ -43,55 +103,103 @@ func (r *messagePostgresRepository) Create(ctx context.Context, message *entity.
 		message.QuotedMsgID,
 		message.Timestamp,
 		message.CreatedAt,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create message: %w", err)
+		message.MediaFileName,
+		directPath,
+		mediaKey,
+		fileSHA256,
+		fileEncSHA256,
+		fileLength,
+		mediaType,
+		message.SenderJID,
 	}
-	return nil
 }
 
 // GetByID retrieves a message by ID
 func (r *messagePostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Message, error) {
-	query := `
-		SELECT id, instance_id, message_id, remote_jid, from_me, type, status, content, media_url, media_mime_type, media_caption, quoted_msg_id, timestamp, created_at
-		FROM messages WHERE id = $1
-	`
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE id = $1`
 	return r.scanMessage(ctx, query, id)
 }
 
 // GetByMessageID retrieves a message by WhatsApp message ID
 func (r *messagePostgresRepository) GetByMessageID(ctx context.Context, instanceID uuid.UUID, messageID string) (*entity.Message, error) {
-	query := `
-		SELECT id, instance_id, message_id, remote_jid, from_me, type, status, content, media_url, media_mime_type, media_caption, quoted_msg_id, timestamp, created_at
-		FROM messages WHERE instance_id = $1 AND message_id = $2
-	`
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE instance_id = $1 AND message_id = $2`
 	return r.scanMessage(ctx, query, instanceID, messageID)
 }
 
 // GetByInstance retrieves all messages for an instance
 func (r *messagePostgresRepository) GetByInstance(ctx context.Context, instanceID uuid.UUID, limit, offset int) ([]*entity.Message, error) {
-	query := `
-		SELECT id, instance_id, message_id, remote_jid, from_me, type, status, content, media_url, media_mime_type, media_caption, quoted_msg_id, timestamp, created_at
-		FROM messages WHERE instance_id = $1 ORDER BY timestamp DESC LIMIT $2 OFFSET $3
-	`
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE instance_id = $1 ORDER BY timestamp DESC LIMIT $2 OFFSET $3`
 	return r.scanMessages(ctx, query, instanceID, limit, offset)
 }
 
-// GetByRemoteJID retrieves messages for a specific chat
-func (r *messagePostgresRepository) GetByRemoteJID(ctx context.Context, instanceID uuid.UUID, remoteJID string, limit, offset int) ([]*entity.Message, error) {
-	query := `
-		SELECT id, instance_id, message_id, remote_jid, from_me, type, status, content, media_url, media_mime_type, media_caption, quoted_msg_id, timestamp, created_at
-		FROM messages WHERE instance_id = $1 AND remote_jid = $2 ORDER BY timestamp DESC LIMIT $3 OFFSET $4
-	`
-	return r.scanMessages(ctx, query, instanceID, remoteJID, limit, offset)
+// GetByRemoteJID retrieves a page of messages for a specific chat, newest first.
+// Pages are keyset-paginated on (timestamp, message_id) so rows arriving meanwhile never shift them.
+func (r *messagePostgresRepository) GetByRemoteJID(ctx context.Context, instanceID uuid.UUID, remoteJID string, filter repository.MessageFilter) ([]*entity.Message, error) {
+	conditions := []string{"instance_id = $1", "remote_jid = $2"}
+	args := []interface{}{instanceID, remoteJID}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Types) > 0 {
+		types := make([]string, len(filter.Types))
+		for i, msgType := range filter.Types {
+			types[i] = string(msgType)
+		}
+		conditions = append(conditions, "type = ANY("+addArg(types)+")")
+	}
+	if filter.FromMe != nil {
+		conditions = append(conditions, "from_me = "+addArg(*filter.FromMe))
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "timestamp >= "+addArg(*filter.Since))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "timestamp <= "+addArg(*filter.Until))
+	}
+	if filter.Before != nil {
+		conditions = append(conditions, cursorCondition("<", filter.Before, addArg))
+	}
+	if filter.After != nil {
+		conditions = append(conditions, cursorCondition(">", filter.After, addArg))
+	}
+
+	// Paging forward walks the timeline upwards, the page is flipped back afterwards
+	ascending := filter.After != nil && filter.Before == nil
+	order := "DESC"
+	if ascending {
+		order = "ASC"
+	}
+
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE ` + strings.Join(conditions, " AND ") +
+		` ORDER BY timestamp ` + order + `, message_id ` + order + ` LIMIT ` + addArg(filter.Limit)
+
+	messages, err := r.scanMessages(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if ascending {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	return messages, nil
+}
+
+// cursorCondition builds the keyset condition selecting the messages on one side of a cursor
+func cursorCondition(op string, cursor *repository.MessageCursor, addArg func(interface{}) string) string {
+	if cursor.MessageID == "" {
+		return "timestamp " + op + " " + addArg(cursor.Timestamp)
+	}
+	return "(timestamp, message_id) " + op + " (" + addArg(cursor.Timestamp) + ", " + addArg(cursor.MessageID) + ")"
 }
 
 // GetByDateRange retrieves messages within a date range
 func (r *messagePostgresRepository) GetByDateRange(ctx context.Context, instanceID uuid.UUID, start, end time.Time) ([]*entity.Message, error) {
-	query := `
-		SELECT id, instance_id, message_id, remote_jid, from_me, type, status, content, media_url, media_mime_type, media_caption, quoted_msg_id, timestamp, created_at
-		FROM messages WHERE instance_id = $1 AND timestamp BETWEEN $2 AND $3 ORDER BY timestamp DESC
-	`
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE instance_id = $1 AND timestamp BETWEEN $2 AND $3 ORDER BY timestamp DESC`
 	return r.scanMessages(ctx, query, instanceID, start, end)
 }
 
@@ -115,6 +223,149 @@ func (r *messagePostgresRepository) UpdateStatusByMessageID(ctx context.Context,
 	return nil
 }
 
+// ApplyReceipt advances the status of the given messages and records when the receipt arrived.
+// Receipts can arrive out of order, so the status never moves backwards and timestamps are only set once.
+func (r *messagePostgresRepository) ApplyReceipt(ctx context.Context, instanceID uuid.UUID, messageIDs []string, status entity.MessageStatus, at time.Time) error {
+	query := `
+		UPDATE messages SET
+			status = CASE
+				WHEN COALESCE(array_position(ARRAY['pending', 'sent', 'delivered', 'read', 'played'], status::text), 0)
+					< array_position(ARRAY['pending', 'sent', 'delivered', 'read', 'played'], $3::text)
+				THEN $3 ELSE status END,
+			delivered_at = COALESCE(delivered_at, $4),
+			read_at = CASE WHEN $3 IN ('read', 'played') THEN COALESCE(read_at, $4) ELSE read_at END,
+			played_at = CASE WHEN $3 = 'played' THEN COALESCE(played_at, $4) ELSE played_at END
+		WHERE instance_id = $1 AND message_id = ANY($2)
+	`
+	_, err := r.pool.Exec(ctx, query, instanceID, messageIDs, string(status), at)
+	if err != nil {
+		return fmt.Errorf("failed to apply message receipt: %w", err)
+	}
+	return nil
+}
+
+// RecordEdit updates the content of a message and appends the change to its edit history.
+// Media messages have their caption replaced; every other type has its text content replaced.
+func (r *messagePostgresRepository) RecordEdit(ctx context.Context, edit *entity.MessageEdit) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var msgType string
+	var content, mediaCaption *string
+	err = tx.QueryRow(ctx,
+		`SELECT type, content, media_caption FROM messages WHERE instance_id = $1 AND message_id = $2 FOR UPDATE`,
+		edit.InstanceID, edit.MessageID,
+	).Scan(&msgType, &content, &mediaCaption)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to load message for edit: %w", err)
+	}
+
+	if err == nil {
+		message := entity.Message{Type: entity.MessageType(msgType)}
+		column := "content"
+		previous := content
+		if message.IsMedia() {
+			column = "media_caption"
+			previous = mediaCaption
+		}
+		if previous != nil {
+			edit.PreviousContent = *previous
+		}
+
+		query := `UPDATE messages SET ` + column + ` = $3, edited_at = $4 WHERE instance_id = $1 AND message_id = $2`
+		if _, err := tx.Exec(ctx, query, edit.InstanceID, edit.MessageID, edit.NewContent, edit.EditedAt); err != nil {
+			return fmt.Errorf("failed to update edited message: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO message_edits (id, instance_id, message_id, previous_content, new_content, edited_by, edited_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`,
+		edit.ID,
+		edit.InstanceID,
+		edit.MessageID,
+		edit.PreviousContent,
+		edit.NewContent,
+		edit.EditedBy,
+		edit.EditedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record message edit: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit message edit: %w", err)
+	}
+	return nil
+}
+
+// GetEditHistory retrieves the edit history of a message, oldest first
+func (r *messagePostgresRepository) GetEditHistory(ctx context.Context, instanceID uuid.UUID, messageID string) ([]*entity.MessageEdit, error) {
+	query := `
+		SELECT id, instance_id, message_id, previous_content, new_content, edited_by, edited_at
+		FROM message_edits WHERE instance_id = $1 AND message_id = $2 ORDER BY edited_at ASC
+	`
+	rows, err := r.pool.Query(ctx, query, instanceID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message edits: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []*entity.MessageEdit
+	for rows.Next() {
+		var edit entity.MessageEdit
+		var previousContent, editedBy *string
+		if err := rows.Scan(
+			&edit.ID,
+			&edit.InstanceID,
+			&edit.MessageID,
+			&previousContent,
+			&edit.NewContent,
+			&editedBy,
+			&edit.EditedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan message edit row: %w", err)
+		}
+		if previousContent != nil {
+			edit.PreviousContent = *previousContent
+		}
+		if editedBy != nil {
+			edit.EditedBy = *editedBy
+		}
+		edits = append(edits, &edit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message edits: %w", err)
+	}
+
+	return edits, nil
+}
+
+// MarkRevoked flags a message as deleted for everyone
+func (r *messagePostgresRepository) MarkRevoked(ctx context.Context, instanceID uuid.UUID, messageID, revokedBy string, revokedAt time.Time) error {
+	query := `UPDATE messages SET revoked_at = $3, revoked_by = $4 WHERE instance_id = $1 AND message_id = $2`
+	_, err := r.pool.Exec(ctx, query, instanceID, messageID, revokedAt, revokedBy)
+	if err != nil {
+		return fmt.Errorf("failed to mark message as revoked: %w", err)
+	}
+	return nil
+}
+
+// DeleteByMessageID deletes a message using WhatsApp message ID
+func (r *messagePostgresRepository) DeleteByMessageID(ctx context.Context, instanceID uuid.UUID, messageID string) error {
+	query := `DELETE FROM messages WHERE instance_id = $1 AND message_id = $2`
+	_, err := r.pool.Exec(ctx, query, instanceID, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	return nil
+}
+
 // Delete deletes a message
 func (r *messagePostgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM messages WHERE id = $1`
@@ -139,15 +390,56 @@ func (r *messagePostgresRepository) DeleteByInstance(ctx context.Context, instan
 func (r *messagePostgresRepository) scanMessage(ctx context.Context, query string, args ...interface{}) (*entity.Message, error) {
 	row := r.pool.QueryRow(ctx, query, args...)
 
+	message, err := scanMessageRow(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to scan message: %w", err)
+	}
+
+	return message, nil
+}
+
+// Helper function to scan multiple messages
+func (r *messagePostgresRepository) scanMessages(ctx context.Context, query string, args ...interface{}) ([]*entity.Message, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*entity.Message
+	for rows.Next() {
+		message, err := scanMessageRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message row: %w", err)
+		}
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// scanMessageRow scans the columns listed in messageColumns into a message entity
+func scanMessageRow(row pgx.Row) (*entity.Message, error) {
 	var message entity.Message
 	var msgType, status string
-	var content, mediaURL, mediaMimeType, mediaCaption, quotedMsgID, messageID *string
+	var content, mediaURL, mediaMimeType, mediaCaption, quotedMsgID, messageID, revokedBy *string
+	var mediaFileName, directPath, mediaType, senderJID *string
+	var mediaKey, fileSHA256, fileEncSHA256 []byte
+	var fileLength *int64
 
 	err := row.Scan(
 		&message.ID,
 		&message.InstanceID,
 		&messageID,
 		&message.RemoteJID,
+		&senderJID,
 		&message.FromMe,
 		&msgType,
 		&status,
@@ -155,15 +447,25 @@ func (r *messagePostgresRepository) scanMessage(ctx context.Context, query strin
 		&mediaURL,
 		&mediaMimeType,
 		&mediaCaption,
+		&mediaFileName,
+		&directPath,
+		&mediaKey,
+		&fileSHA256,
+		&fileEncSHA256,
+		&fileLength,
+		&mediaType,
 		&quotedMsgID,
 		&message.Timestamp,
+		&message.DeliveredAt,
+		&message.ReadAt,
+		&message.PlayedAt,
+		&message.EditedAt,
+		&message.RevokedAt,
+		&revokedBy,
 		&message.CreatedAt,
 	)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to scan message: %w", err)
+		return nil, err
 	}
 
 	message.Type = entity.MessageType(msgType)
@@ -186,73 +488,31 @@ func (r *messagePostgresRepository) scanMessage(ctx context.Context, query strin
 	if quotedMsgID != nil {
 		message.QuotedMsgID = *quotedMsgID
 	}
-
-	return &message, nil
-}
-
-// Helper function to scan multiple messages
-func (r *messagePostgresRepository) scanMessages(ctx context.Context, query string, args ...interface{}) ([]*entity.Message, error) {
-	rows, err := r.pool.Query(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query messages: %w", err)
+	if revokedBy != nil {
+		message.RevokedBy = *revokedBy
 	}
-	defer rows.Close()
-
-	var messages []*entity.Message
-	for rows.Next() {
-		var message entity.Message
-		var msgType, status string
-		var content, mediaURL, mediaMimeType, mediaCaption, quotedMsgID, messageID *string
-
-		err := rows.Scan(
-			&message.ID,
-			&message.InstanceID,
-			&messageID,
-			&message.RemoteJID,
-			&message.FromMe,
-			&msgType,
-			&status,
-			&content,
-			&mediaURL,
-			&mediaMimeType,
-			&mediaCaption,
-			&quotedMsgID,
-			&message.Timestamp,
-			&message.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan message row: %w", err)
-		}
-
-		message.Type = entity.MessageType(msgType)
-		message.Status = entity.MessageStatus(status)
-		if messageID != nil {
-			message.MessageID = *messageID
-		}
-		if content != nil {
-			message.Content = *content
-		}
-		if mediaURL != nil {
-			message.MediaURL = *mediaURL
-		}
-		if mediaMimeType != nil {
-			message.MediaMimeType = *mediaMimeType
+	if senderJID != nil {
+		message.SenderJID = *senderJID
+	}
+	if mediaFileName != nil {
+		message.MediaFileName = *mediaFileName
+	}
+	if directPath != nil && *directPath != "" {
+		message.MediaKeys = &entity.MediaKeys{
+			DirectPath:    *directPath,
+			MediaKey:      mediaKey,
+			FileSHA256:    fileSHA256,
+			FileEncSHA256: fileEncSHA256,
 		}
-		if mediaCaption != nil {
-			message.MediaCaption = *mediaCaption
+		if fileLength != nil {
+			message.MediaKeys.FileLength = *fileLength
 		}
-		if quotedMsgID != nil {
-			message.QuotedMsgID = *quotedMsgID
+		if mediaType != nil {
+			message.MediaKeys.MediaType = *mediaType
 		}
-
-		messages = append(messages, &message)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating messages: %w", err)
-	}
-
-	return messages, nil
+	return &message, nil
 }
 
 // CountToday counts messages sent/received today
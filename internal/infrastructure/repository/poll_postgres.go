@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+)
+
+// pollPostgresRepository implements PollRepository using PostgreSQL
+type pollPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPollPostgresRepository creates a new PostgreSQL-based poll repository
+func NewPollPostgresRepository(pool *pgxpool.Pool) repository.PollRepository {
+	return &pollPostgresRepository{pool: pool}
+}
+
+// Create stores a poll, ignoring polls already stored
+func (r *pollPostgresRepository) Create(ctx context.Context, poll *entity.Poll) error {
+	query := `
+		INSERT INTO polls (id, instance_id, message_id, chat_jid, creator_jid, question, options, selectable_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (instance_id, message_id) DO NOTHING
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		poll.ID,
+		poll.InstanceID,
+		poll.MessageID,
+		poll.ChatJID,
+		poll.CreatorJID,
+		poll.Question,
+		poll.Options,
+		poll.SelectableCount,
+		poll.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create poll: %w", err)
+	}
+	return nil
+}
+
+// GetByMessageID retrieves a poll by the WhatsApp ID of its message
+func (r *pollPostgresRepository) GetByMessageID(ctx context.Context, instanceID uuid.UUID, messageID string) (*entity.Poll, error) {
+	query := `
+		SELECT id, instance_id, message_id, chat_jid, creator_jid, question, options, selectable_count, created_at
+		FROM polls WHERE instance_id = $1 AND message_id = $2
+	`
+
+	var poll entity.Poll
+	var creatorJID *string
+
+	err := r.pool.QueryRow(ctx, query, instanceID, messageID).Scan(
+		&poll.ID,
+		&poll.InstanceID,
+		&poll.MessageID,
+		&poll.ChatJID,
+		&creatorJID,
+		&poll.Question,
+		&poll.Options,
+		&poll.SelectableCount,
+		&poll.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to scan poll: %w", err)
+	}
+
+	if creatorJID != nil {
+		poll.CreatorJID = *creatorJID
+	}
+
+	return &poll, nil
+}
+
+// SaveVote stores the vote of a voter, replacing their previous one unless it is newer
+func (r *pollPostgresRepository) SaveVote(ctx context.Context, vote *entity.PollVote) error {
+	query := `
+		INSERT INTO poll_votes (instance_id, poll_message_id, voter_jid, selected_options, voted_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (instance_id, poll_message_id, voter_jid) DO UPDATE SET
+			selected_options = EXCLUDED.selected_options,
+			voted_at = EXCLUDED.voted_at
+		WHERE poll_votes.voted_at <= EXCLUDED.voted_at
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		vote.InstanceID,
+		vote.PollMessageID,
+		vote.VoterJID,
+		vote.SelectedOptions,
+		vote.VotedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save poll vote: %w", err)
+	}
+	return nil
+}
+
+// GetVotes retrieves the current votes of a poll
+func (r *pollPostgresRepository) GetVotes(ctx context.Context, instanceID uuid.UUID, pollMessageID string) ([]*entity.PollVote, error) {
+	query := `
+		SELECT instance_id, poll_message_id, voter_jid, selected_options, voted_at
+		FROM poll_votes WHERE instance_id = $1 AND poll_message_id = $2
+		ORDER BY voted_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, instanceID, pollMessageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poll votes: %w", err)
+	}
+	defer rows.Close()
+
+	var votes []*entity.PollVote
+	for rows.Next() {
+		var vote entity.PollVote
+		if err := rows.Scan(&vote.InstanceID, &vote.PollMessageID, &vote.VoterJID, &vote.SelectedOptions, &vote.VotedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan poll vote: %w", err)
+		}
+		votes = append(votes, &vote)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating poll votes: %w", err)
+	}
+
+	return votes, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+)
+
+// webhookDeliveryLogPostgresRepository implements WebhookDeliveryLogRepository using PostgreSQL
+type webhookDeliveryLogPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewWebhookDeliveryLogPostgresRepository creates a new PostgreSQL-based webhook delivery log repository
+func NewWebhookDeliveryLogPostgresRepository(pool *pgxpool.Pool) repository.WebhookDeliveryLogRepository {
+	return &webhookDeliveryLogPostgresRepository{pool: pool}
+}
+
+// Create stores a delivery attempt
+func (r *webhookDeliveryLogPostgresRepository) Create(ctx context.Context, log *entity.WebhookDeliveryLog) error {
+	query := `
+		INSERT INTO webhook_delivery_logs (delivery_id, attempt, url, status_code, response, success, latency_ms, error, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, ''), $6, $7, NULLIF($8, ''), $9)
+		RETURNING id
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		log.DeliveryID,
+		log.Attempt,
+		log.URL,
+		log.StatusCode,
+		log.Response,
+		log.Success,
+		log.LatencyMs,
+		log.ErrorMessage,
+		log.CreatedAt,
+	).Scan(&log.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook delivery log: %w", err)
+	}
+	return nil
+}
+
+// ListByDelivery retrieves the attempts made at a delivery, oldest first
+func (r *webhookDeliveryLogPostgresRepository) ListByDelivery(ctx context.Context, deliveryID uuid.UUID) ([]*entity.WebhookDeliveryLog, error) {
+	query := `
+		SELECT id, delivery_id, attempt, url, status_code, response, success, latency_ms, error, created_at
+		FROM webhook_delivery_logs
+		WHERE delivery_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.pool.Query(ctx, query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*entity.WebhookDeliveryLog
+	for rows.Next() {
+		log, err := scanWebhookDeliveryLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery log: %w", err)
+		}
+		logs = append(logs, log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery logs: %w", err)
+	}
+	return logs, nil
+}
+
+// scanWebhookDeliveryLog scans a webhook delivery log row
+func scanWebhookDeliveryLog(row pgx.Row) (*entity.WebhookDeliveryLog, error) {
+	var log entity.WebhookDeliveryLog
+	var statusCode *int
+	var response, errorMessage *string
+
+	err := row.Scan(
+		&log.ID,
+		&log.DeliveryID,
+		&log.Attempt,
+		&log.URL,
+		&statusCode,
+		&response,
+		&log.Success,
+		&log.LatencyMs,
+		&errorMessage,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != nil {
+		log.StatusCode = *statusCode
+	}
+	if response != nil {
+		log.Response = *response
+	}
+	if errorMessage != nil {
+		log.ErrorMessage = *errorMessage
+	}
+	return &log, nil
+}
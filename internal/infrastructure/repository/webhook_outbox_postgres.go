@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+)
+
+// webhookOutboxPostgresRepository implements WebhookOutboxRepository using PostgreSQL
+type webhookOutboxPostgresRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewWebhookOutboxPostgresRepository creates a new PostgreSQL-based webhook outbox repository
+func NewWebhookOutboxPostgresRepository(pool *pgxpool.Pool) repository.WebhookOutboxRepository {
+	return &webhookOutboxPostgresRepository{pool: pool}
+}
+
+// webhookDeliveryColumns are the columns scanned by scanWebhookDelivery
+const webhookDeliveryColumns = `id, event_id, instance_id, webhook_id, event, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+// Enqueue stores deliveries to be sent by the workers
+func (r *webhookOutboxPostgresRepository) Enqueue(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO webhook_outbox (id, event_id, instance_id, webhook_id, event, payload, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), $8)
+	`
+
+	batch := &pgx.Batch{}
+	for _, delivery := range deliveries {
+		batch.Queue(query,
+			delivery.ID,
+			delivery.EventID,
+			delivery.InstanceID,
+			delivery.WebhookID,
+			string(delivery.Event),
+			delivery.Payload,
+			string(delivery.Status),
+			delivery.CreatedAt,
+		)
+	}
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// Claim leases the next due delivery to a worker, counting it as an attempt
+func (r *webhookOutboxPostgresRepository) Claim(ctx context.Context, workerID string, lease time.Duration) (*entity.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_outbox
+		SET locked_by = $1, locked_until = NOW() + $2 * INTERVAL '1 millisecond', attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM webhook_outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+				AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+
+	delivery, err := scanWebhookDelivery(r.pool.QueryRow(ctx, query, workerID, lease.Milliseconds()))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// MarkDelivered records the successful delivery of a claimed delivery
+func (r *webhookOutboxPostgresRepository) MarkDelivered(ctx context.Context, id uuid.UUID, workerID string) error {
+	query := `
+		UPDATE webhook_outbox
+		SET status = 'delivered', delivered_at = NOW(), locked_by = NULL, locked_until = NULL, last_error = NULL
+		WHERE id = $1 AND locked_by = $2
+	`
+	if _, err := r.pool.Exec(ctx, query, id, workerID); err != nil {
+		return fmt.Errorf("failed to mark webhook delivery as delivered: %w", err)
+	}
+	return nil
+}
+
+// Retry releases a claimed delivery to be attempted again after the given delay
+func (r *webhookOutboxPostgresRepository) Retry(ctx context.Context, id uuid.UUID, workerID string, delay time.Duration, lastError string) error {
+	query := `
+		UPDATE webhook_outbox
+		SET next_attempt_at = NOW() + $3 * INTERVAL '1 millisecond', locked_by = NULL, locked_until = NULL, last_error = NULLIF($4, '')
+		WHERE id = $1 AND locked_by = $2
+	`
+	if _, err := r.pool.Exec(ctx, query, id, workerID, delay.Milliseconds(), lastError); err != nil {
+		return fmt.Errorf("failed to reschedule webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// Postpone releases a claimed delivery to be attempted after the delay, without counting the claim as an attempt
+func (r *webhookOutboxPostgresRepository) Postpone(ctx context.Context, id uuid.UUID, workerID string, delay time.Duration) error {
+	query := `
+		UPDATE webhook_outbox
+		SET attempts = GREATEST(attempts - 1, 0), next_attempt_at = NOW() + $3 * INTERVAL '1 millisecond',
+			locked_by = NULL, locked_until = NULL
+		WHERE id = $1 AND locked_by = $2
+	`
+	if _, err := r.pool.Exec(ctx, query, id, workerID, delay.Milliseconds()); err != nil {
+		return fmt.Errorf("failed to postpone webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// MarkDeadLetter gives up a claimed delivery, moving it to the dead-letter state
+func (r *webhookOutboxPostgresRepository) MarkDeadLetter(ctx context.Context, id uuid.UUID, workerID string, lastError string) error {
+	query := `
+		UPDATE webhook_outbox
+		SET status = 'dead_letter', locked_by = NULL, locked_until = NULL, last_error = NULLIF($3, '')
+		WHERE id = $1 AND locked_by = $2
+	`
+	if _, err := r.pool.Exec(ctx, query, id, workerID, lastError); err != nil {
+		return fmt.Errorf("failed to move webhook delivery to dead-letter: %w", err)
+	}
+	return nil
+}
+
+// DeleteDelivered removes the deliveries delivered longer than the given duration ago
+func (r *webhookOutboxPostgresRepository) DeleteDelivered(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `
+		DELETE FROM webhook_outbox
+		WHERE status = 'delivered' AND delivered_at < NOW() - $1 * INTERVAL '1 millisecond'
+	`
+	tag, err := r.pool.Exec(ctx, query, olderThan.Milliseconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete delivered webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteDeadLetters removes the dead-lettered deliveries created longer than the given duration ago
+func (r *webhookOutboxPostgresRepository) DeleteDeadLetters(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `
+		DELETE FROM webhook_outbox
+		WHERE status = 'dead_letter' AND created_at < NOW() - $1 * INTERVAL '1 millisecond'
+	`
+	tag, err := r.pool.Exec(ctx, query, olderThan.Milliseconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete dead-lettered webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetByID retrieves a delivery by ID
+func (r *webhookOutboxPostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_outbox WHERE id = $1`
+
+	delivery, err := scanWebhookDelivery(r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// List retrieves the deliveries to the webhooks of an instance matching the filter, newest first
+func (r *webhookOutboxPostgresRepository) List(ctx context.Context, instanceID uuid.UUID, filter repository.WebhookDeliveryFilter) ([]*entity.WebhookDelivery, error) {
+	where, args := webhookDeliveryFilterConditions(instanceID, filter)
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_outbox WHERE ` + where +
+		` ORDER BY created_at DESC, id` +
+		fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*entity.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Count counts the deliveries to the webhooks of an instance matching the filter
+func (r *webhookOutboxPostgresRepository) Count(ctx context.Context, instanceID uuid.UUID, filter repository.WebhookDeliveryFilter) (int64, error) {
+	where, args := webhookDeliveryFilterConditions(instanceID, filter)
+
+	var count int64
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_outbox WHERE `+where, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+	return count, nil
+}
+
+// Redeliver queues a delivery that is not pending to be sent again right away, keeping its event ID
+func (r *webhookOutboxPostgresRepository) Redeliver(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		UPDATE webhook_outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_by = NULL, locked_until = NULL,
+			last_error = NULL, delivered_at = NULL
+		WHERE id = $1 AND status <> 'pending'
+	`
+	tag, err := r.pool.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RedeliverDeadLetters queues the dead-letter deliveries to the webhooks of an instance created since the given time
+// to be sent again
+func (r *webhookOutboxPostgresRepository) RedeliverDeadLetters(ctx context.Context, instanceID uuid.UUID, since time.Time, webhookID *uuid.UUID) (int64, error) {
+	query := `
+		UPDATE webhook_outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_by = NULL, locked_until = NULL,
+			last_error = NULL
+		WHERE instance_id = $1 AND status = 'dead_letter' AND created_at >= $2
+			AND webhook_id = COALESCE($3::uuid, webhook_id) -- never matches the global webhook, its webhook_id is NULL
+	`
+	tag, err := r.pool.Exec(ctx, query, instanceID, since, webhookID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redeliver dead-letter webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// webhookDeliveryFilterConditions builds the WHERE clause and arguments of a delivery filter,
+// leaving out the deliveries to the global webhook
+func webhookDeliveryFilterConditions(instanceID uuid.UUID, filter repository.WebhookDeliveryFilter) (string, []interface{}) {
+	conditions := []string{"instance_id = $1", "webhook_id IS NOT NULL"}
+	args := []interface{}{instanceID}
+
+	if filter.Status != "" {
+		args = append(args, string(filter.Status))
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.Event != "" {
+		args = append(args, string(filter.Event))
+		conditions = append(conditions, fmt.Sprintf("event = $%d", len(args)))
+	}
+	if filter.WebhookID != nil {
+		args = append(args, *filter.WebhookID)
+		conditions = append(conditions, fmt.Sprintf("webhook_id = $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, filter.Until)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// scanWebhookDelivery scans a webhook delivery row
+func scanWebhookDelivery(row pgx.Row) (*entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	var event, status string
+	var lastError *string
+
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EventID,
+		&delivery.InstanceID,
+		&delivery.WebhookID,
+		&event,
+		&delivery.Payload,
+		&status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&lastError,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Event = entity.WebhookEvent(event)
+	delivery.Status = entity.WebhookDeliveryStatus(status)
+	if lastError != nil {
+		delivery.LastError = *lastError
+	}
+	return &delivery, nil
+}
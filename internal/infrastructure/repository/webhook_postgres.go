@@ -40,8 +40,8 @@ func (r *webhookPostgresRepository) Create(ctx context.Context, webhook *entity.
 	}
 
 	query := `
-		INSERT INTO webhooks (id, instance_id, url, events, headers, enabled, webhook_by_events, webhook_base64, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO webhooks (id, instance_id, url, events, headers, enabled, webhook_by_events, webhook_base64, secret, previous_secret, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11, $12)
 	`
 	_, err = r.pool.Exec(ctx, query,
 		webhook.ID,
@@ -52,6 +52,8 @@ func (r *webhookPostgresRepository) Create(ctx context.Context, webhook *entity.
 		webhook.Enabled,
 		webhook.WebhookByEvents,
 		webhook.UseBase64,
+		webhook.Secret,
+		webhook.PreviousSecret,
 		webhook.CreatedAt,
 		webhook.UpdatedAt,
 	)
@@ -64,21 +66,51 @@ func (r *webhookPostgresRepository) Create(ctx context.Context, webhook *entity.
 // GetByID retrieves a webhook by ID
 func (r *webhookPostgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Webhook, error) {
 	query := `
-		SELECT id, instance_id, url, events, headers, enabled, webhook_by_events, webhook_base64, created_at, updated_at
+		SELECT id, instance_id, url, events, headers, enabled, webhook_by_events, webhook_base64, secret, previous_secret, created_at, updated_at
 		FROM webhooks WHERE id = $1
 	`
 	return r.scanWebhook(ctx, query, id)
 }
 
-// GetByInstance retrieves webhook configuration for an instance
+// GetByInstance retrieves the first webhook configured for an instance
 func (r *webhookPostgresRepository) GetByInstance(ctx context.Context, instanceID uuid.UUID) (*entity.Webhook, error) {
 	query := `
-		SELECT id, instance_id, url, events, headers, enabled, webhook_by_events, webhook_base64, created_at, updated_at
+		SELECT id, instance_id, url, events, headers, enabled, webhook_by_events, webhook_base64, secret, previous_secret, created_at, updated_at
 		FROM webhooks WHERE instance_id = $1
+		ORDER BY created_at, id
+		LIMIT 1
 	`
 	return r.scanWebhook(ctx, query, instanceID)
 }
 
+// ListByInstance retrieves all webhooks of an instance, oldest first
+func (r *webhookPostgresRepository) ListByInstance(ctx context.Context, instanceID uuid.UUID) ([]*entity.Webhook, error) {
+	query := `
+		SELECT id, instance_id, url, events, headers, enabled, webhook_by_events, webhook_base64, secret, previous_secret, created_at, updated_at
+		FROM webhooks WHERE instance_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.pool.Query(ctx, query, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var webhooks []*entity.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhookRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
 // Update updates a webhook configuration
 func (r *webhookPostgresRepository) Update(ctx context.Context, webhook *entity.Webhook) error {
 	if webhook.Headers == nil {
@@ -97,7 +129,8 @@ func (r *webhookPostgresRepository) Update(ctx context.Context, webhook *entity.
 
 	query := `
 		UPDATE webhooks 
-		SET url = $2, events = $3, headers = $4, enabled = $5, webhook_by_events = $6, webhook_base64 = $7, updated_at = $8
+		SET url = $2, events = $3, headers = $4, enabled = $5, webhook_by_events = $6, webhook_base64 = $7,
+			secret = NULLIF($8, ''), previous_secret = NULLIF($9, ''), updated_at = $10
 		WHERE id = $1
 	`
 	webhook.UpdatedAt = time.Now()
@@ -109,6 +142,8 @@ func (r *webhookPostgresRepository) Update(ctx context.Context, webhook *entity.
 		webhook.Enabled,
 		webhook.WebhookByEvents,
 		webhook.UseBase64,
+		webhook.Secret,
+		webhook.PreviousSecret,
 		webhook.UpdatedAt,
 	)
 	if err != nil {
@@ -117,7 +152,7 @@ func (r *webhookPostgresRepository) Update(ctx context.Context, webhook *entity.
 	return nil
 }
 
-// Upsert creates or updates a webhook configuration
+// Upsert creates or updates the first webhook of an instance
 func (r *webhookPostgresRepository) Upsert(ctx context.Context, webhook *entity.Webhook) error {
 	if webhook.Headers == nil {
 		webhook.Headers = make(map[string]string)
@@ -133,23 +168,16 @@ func (r *webhookPostgresRepository) Upsert(ctx context.Context, webhook *entity.
 		events[i] = string(e)
 	}
 
-	now := time.Now()
-	webhook.UpdatedAt = now
+	webhook.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO webhooks (id, instance_id, url, events, headers, enabled, webhook_by_events, webhook_base64, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		ON CONFLICT (instance_id) DO UPDATE SET
-			url = EXCLUDED.url,
-			events = EXCLUDED.events,
-			headers = EXCLUDED.headers,
-			enabled = EXCLUDED.enabled,
-			webhook_by_events = EXCLUDED.webhook_by_events,
-			webhook_base64 = EXCLUDED.webhook_base64,
-			updated_at = EXCLUDED.updated_at
+		UPDATE webhooks
+		SET url = $2, events = $3, headers = $4, enabled = $5, webhook_by_events = $6, webhook_base64 = $7,
+			secret = NULLIF($8, ''), previous_secret = NULLIF($9, ''), updated_at = $10
+		WHERE id = (SELECT id FROM webhooks WHERE instance_id = $1 ORDER BY created_at, id LIMIT 1)
+		RETURNING id, created_at
 	`
-	_, err = r.pool.Exec(ctx, query,
-		webhook.ID,
+	err = r.pool.QueryRow(ctx, query,
 		webhook.InstanceID,
 		webhook.URL,
 		events,
@@ -157,9 +185,13 @@ func (r *webhookPostgresRepository) Upsert(ctx context.Context, webhook *entity.
 		webhook.Enabled,
 		webhook.WebhookByEvents,
 		webhook.UseBase64,
-		webhook.CreatedAt,
+		webhook.Secret,
+		webhook.PreviousSecret,
 		webhook.UpdatedAt,
-	)
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return r.Create(ctx, webhook)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to upsert webhook: %w", err)
 	}
@@ -198,11 +230,22 @@ func (r *webhookPostgresRepository) SetEnabled(ctx context.Context, id uuid.UUID
 
 // Helper function to scan a single webhook
 func (r *webhookPostgresRepository) scanWebhook(ctx context.Context, query string, args ...interface{}) (*entity.Webhook, error) {
-	row := r.pool.QueryRow(ctx, query, args...)
+	webhook, err := scanWebhookRow(r.pool.QueryRow(ctx, query, args...))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to scan webhook: %w", err)
+	}
+	return webhook, nil
+}
 
+// scanWebhookRow scans a webhook row
+func scanWebhookRow(row pgx.Row) (*entity.Webhook, error) {
 	var webhook entity.Webhook
 	var events []string
 	var headersJSON []byte
+	var secret, previousSecret *string
 
 	err := row.Scan(
 		&webhook.ID,
@@ -213,14 +256,20 @@ func (r *webhookPostgresRepository) scanWebhook(ctx context.Context, query strin
 		&webhook.Enabled,
 		&webhook.WebhookByEvents,
 		&webhook.UseBase64,
+		&secret,
+		&previousSecret,
 		&webhook.CreatedAt,
 		&webhook.UpdatedAt,
 	)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		return nil, err
+	}
+
+	if secret != nil {
+		webhook.Secret = *secret
+	}
+	if previousSecret != nil {
+		webhook.PreviousSecret = *previousSecret
 	}
 
 	// Convert string events to WebhookEvent
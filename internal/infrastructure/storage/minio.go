@@ -5,9 +5,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -110,7 +112,7 @@ type UploadResult struct {
 // Upload uploads data to MinIO
 func (c *Client) Upload(ctx context.Context, data []byte, contentType, folder string) (*UploadResult, error) {
 	// Generate unique filename
-	ext := getExtensionFromMimeType(contentType)
+	ext := ExtensionFromMimeType(contentType)
 	key := path.Join(folder, fmt.Sprintf("%s%s", uuid.New().String(), ext))
 
 	reader := bytes.NewReader(data)
@@ -182,6 +184,23 @@ func (c *Client) Download(ctx context.Context, key string) ([]byte, string, erro
 	return data, info.ContentType, nil
 }
 
+// Open opens a file from MinIO to be streamed, returning its size and content type. The caller
+// must close the reader.
+func (c *Client) Open(ctx context.Context, key string) (io.ReadCloser, int64, string, error) {
+	obj, err := c.mc.GetObject(ctx, c.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("failed to get object: %w", err)
+	}
+
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, 0, "", fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	return obj, info.Size, info.ContentType, nil
+}
+
 // Delete removes a file from MinIO
 func (c *Client) Delete(ctx context.Context, key string) error {
 	if err := c.mc.RemoveObject(ctx, c.bucket, key, minio.RemoveObjectOptions{}); err != nil {
@@ -210,6 +229,15 @@ func (c *Client) GetPublicURL(key string) string {
 	return fmt.Sprintf("%s/%s/%s", c.publicURL, c.bucket, key)
 }
 
+// KeyFromURL returns the object key of a public URL generated by this client
+func (c *Client) KeyFromURL(fileURL string) (string, bool) {
+	prefix := fmt.Sprintf("%s/%s/", c.publicURL, c.bucket)
+	if !strings.HasPrefix(fileURL, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(fileURL, prefix), true
+}
+
 // ListFiles lists files in a folder
 func (c *Client) ListFiles(ctx context.Context, prefix string, limit int) ([]FileInfo, error) {
 	var files []FileInfo
@@ -278,8 +306,12 @@ type BucketStats struct {
 	TotalCount int64  `json:"total_count"`
 }
 
-// getExtensionFromMimeType returns file extension from mime type
-func getExtensionFromMimeType(mimeType string) string {
+// ExtensionFromMimeType returns file extension from mime type, ignoring parameters such as codecs
+func ExtensionFromMimeType(mimeType string) string {
+	if idx := strings.Index(mimeType, ";"); idx != -1 {
+		mimeType = strings.TrimSpace(mimeType[:idx])
+	}
+
 	extensions := map[string]string{
 		"image/jpeg":      ".jpg",
 		"image/png":       ".png",
@@ -297,6 +329,9 @@ func getExtensionFromMimeType(mimeType string) string {
 	if ext, ok := extensions[mimeType]; ok {
 		return ext
 	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
 	return ""
 }
 
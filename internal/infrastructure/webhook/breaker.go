@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonadableite/turbozap-api/internal/application/dto"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/sirupsen/logrus"
+)
+
+// circuit tracks the consecutive failures of the deliveries to a URL
+type circuit struct {
+	failures  int
+	open      bool
+	openedAt  time.Time // When the circuit opened, zero while closed
+	nextProbe time.Time // When an open circuit lets the next delivery through
+	probing   bool      // A probe delivery is in flight
+}
+
+// circuitBreaker stops sending to the URLs that keep failing. Once a URL fails threshold times in a
+// row its circuit opens: deliveries to it are held back, except for one probe every probeInterval,
+// until a delivery succeeds again.
+type circuitBreaker struct {
+	threshold     int
+	probeInterval time.Duration
+	mu            sync.Mutex
+	circuits      map[string]*circuit
+}
+
+// newCircuitBreaker creates a circuit breaker, a threshold of 0 or less never opens a circuit
+func newCircuitBreaker(threshold int, probeInterval time.Duration) *circuitBreaker {
+	if probeInterval <= 0 {
+		probeInterval = outboxRetryBase
+	}
+	return &circuitBreaker{
+		threshold:     threshold,
+		probeInterval: probeInterval,
+		circuits:      make(map[string]*circuit),
+	}
+}
+
+// allow returns true if a delivery can be sent to the URL now, otherwise when to try again. While
+// the circuit is open only one probe is let through at a time.
+func (b *circuitBreaker) allow(url string, now time.Time) (bool, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, exists := b.circuits[url]
+	if !exists || !c.open {
+		return true, time.Time{}
+	}
+	if c.probing || now.Before(c.nextProbe) {
+		if c.nextProbe.After(now) {
+			return false, c.nextProbe
+		}
+		return false, now.Add(b.probeInterval)
+	}
+
+	c.probing = true
+	return true, time.Time{}
+}
+
+// success closes the circuit of the URL
+func (b *circuitBreaker) success(url string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.circuits, url)
+}
+
+// failure records a failed delivery to the URL, returning true if it opened the circuit
+func (b *circuitBreaker) failure(url string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, exists := b.circuits[url]
+	if !exists {
+		c = &circuit{}
+		b.circuits[url] = c
+	}
+	c.failures++
+
+	if c.open {
+		c.probing = false
+		c.nextProbe = now.Add(b.probeInterval)
+		return false
+	}
+	if b.threshold <= 0 || c.failures < b.threshold {
+		return false
+	}
+
+	c.open = true
+	c.openedAt = now
+	c.nextProbe = now.Add(b.probeInterval)
+	return true
+}
+
+// release lets another probe through when the one in flight ended without a verdict
+func (b *circuitBreaker) release(url string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, exists := b.circuits[url]; exists {
+		c.probing = false
+	}
+}
+
+// openedAt returns when the circuit of the URL opened, zero if it is closed
+func (b *circuitBreaker) openedAt(url string) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, exists := b.circuits[url]; exists {
+		return c.openedAt
+	}
+	return time.Time{}
+}
+
+// recordOutcome updates the circuit of a target with the outcome of a delivery to it
+func (d *Dispatcher) recordOutcome(ctx context.Context, target webhookTarget, sendErr error) {
+	switch {
+	case sendErr == nil:
+		d.breaker.success(target.URL)
+	case ctx.Err() != nil:
+		// Interrupted by a shutdown, which says nothing about the receiver
+		d.breaker.release(target.URL)
+	default:
+		if d.breaker.failure(target.URL, time.Now()) {
+			d.logger.WithFields(logrus.Fields{
+				"url":    target.URL,
+				"target": target.Label,
+			}).Warn("Circuit opened, holding back webhook deliveries")
+		}
+	}
+}
+
+// disableIfFailing is called after a delivery to a target failed. If the circuit of its URL has been
+// open for longer than the configured window, it disables the webhook and reports it with an errors
+// event. The window starts over when the webhook is changed, so re-enabling it gives the receiver a
+// new chance.
+func (d *Dispatcher) disableIfFailing(ctx context.Context, delivery *entity.WebhookDelivery, target webhookTarget, sendErr error, now time.Time) bool {
+	if d.config.DisableAfter <= 0 || target.WebhookID == nil || d.webhookRepo == nil {
+		return false
+	}
+
+	since := d.breaker.openedAt(target.URL)
+	if since.IsZero() {
+		return false
+	}
+	if target.UpdatedAt.After(since) {
+		since = target.UpdatedAt
+	}
+	failingFor := now.Sub(since)
+	if failingFor < time.Duration(d.config.DisableAfter)*time.Minute {
+		return false
+	}
+
+	if err := d.webhookRepo.SetEnabled(ctx, *target.WebhookID, false); err != nil {
+		d.logger.WithError(err).WithField("webhook_id", target.WebhookID.String()).Error("Failed to disable failing webhook")
+		return false
+	}
+
+	failingFor = failingFor.Round(time.Second)
+	d.logger.WithFields(logrus.Fields{
+		"webhook_id":  target.WebhookID.String(),
+		"url":         target.URL,
+		"failing_for": failingFor.String(),
+	}).Warn("Disabled webhook failing for too long")
+
+	d.Dispatch(delivery.InstanceID, entity.WebhookEventErrors, dto.WebhookErrorData{
+		Type:      "webhook_disabled",
+		Message:   fmt.Sprintf("Webhook disabled after its deliveries failed for %s", failingFor),
+		WebhookID: target.WebhookID.String(),
+		URL:       target.URL,
+		LastError: sendErr.Error(),
+	})
+	return true
+}
@@ -0,0 +1,329 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+const testURL = "https://receiver.example.com/hook"
+
+func TestCircuitBreaker(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	interval := 30 * time.Second
+
+	type step struct {
+		at          time.Duration // Since start
+		op          string        // allow, failure, success or release
+		wantAllowed bool          // allow: whether the delivery goes through
+		wantRetryAt time.Duration // allow: when to try again if held back
+		wantOpened  bool          // failure: whether it opened the circuit
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []step
+	}{
+		{
+			name:      "stays closed below the threshold",
+			threshold: 3,
+			steps: []step{
+				{op: "failure"},
+				{op: "failure"},
+				{op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "a success resets the consecutive failures",
+			threshold: 2,
+			steps: []step{
+				{op: "failure"},
+				{op: "success"},
+				{op: "failure"},
+				{op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "opens at the threshold and holds deliveries until the probe",
+			threshold: 2,
+			steps: []step{
+				{op: "failure"},
+				{op: "failure", wantOpened: true},
+				{op: "allow", wantRetryAt: interval},
+				{at: interval - time.Second, op: "allow", wantRetryAt: interval},
+				{at: interval, op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "lets one probe through at a time",
+			threshold: 1,
+			steps: []step{
+				{op: "failure", wantOpened: true},
+				{at: interval, op: "allow", wantAllowed: true},
+				{at: interval, op: "allow", wantRetryAt: 2 * interval},
+				{at: interval + time.Second, op: "allow", wantRetryAt: 2*interval + time.Second},
+			},
+		},
+		{
+			name:      "a failed probe waits for the next interval",
+			threshold: 1,
+			steps: []step{
+				{op: "failure", wantOpened: true},
+				{at: interval, op: "allow", wantAllowed: true},
+				{at: interval + 5*time.Second, op: "failure"},
+				{at: interval + 10*time.Second, op: "allow", wantRetryAt: 2*interval + 5*time.Second},
+				{at: 2*interval + 5*time.Second, op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "a successful probe closes the circuit",
+			threshold: 1,
+			steps: []step{
+				{op: "failure", wantOpened: true},
+				{at: interval, op: "allow", wantAllowed: true},
+				{at: interval, op: "success"},
+				{at: interval, op: "allow", wantAllowed: true},
+				{at: interval, op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "a released probe lets another one through",
+			threshold: 1,
+			steps: []step{
+				{op: "failure", wantOpened: true},
+				{at: interval, op: "allow", wantAllowed: true},
+				{at: interval, op: "release"},
+				{at: interval, op: "allow", wantAllowed: true},
+			},
+		},
+		{
+			name:      "never opens without a threshold",
+			threshold: 0,
+			steps: []step{
+				{op: "failure"},
+				{op: "failure"},
+				{op: "failure"},
+				{op: "allow", wantAllowed: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(tt.threshold, interval)
+
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				switch s.op {
+				case "allow":
+					allowed, retryAt := b.allow(testURL, now)
+					if allowed != s.wantAllowed {
+						t.Fatalf("step %d: allowed = %v, want %v", i, allowed, s.wantAllowed)
+					}
+					if !allowed && !retryAt.Equal(start.Add(s.wantRetryAt)) {
+						t.Fatalf("step %d: retry at %s, want %s", i, retryAt.Sub(start), s.wantRetryAt)
+					}
+				case "failure":
+					if opened := b.failure(testURL, now); opened != s.wantOpened {
+						t.Fatalf("step %d: opened = %v, want %v", i, opened, s.wantOpened)
+					}
+				case "success":
+					b.success(testURL)
+				case "release":
+					b.release(testURL)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerOpenedAt(t *testing.T) {
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(2, time.Minute)
+
+	b.failure(testURL, start)
+	if opened := b.openedAt(testURL); !opened.IsZero() {
+		t.Fatalf("openedAt = %s below the threshold, want zero", opened)
+	}
+
+	b.failure(testURL, start.Add(time.Minute))
+	b.failure(testURL, start.Add(2*time.Minute))
+	if opened := b.openedAt(testURL); !opened.Equal(start.Add(time.Minute)) {
+		t.Fatalf("openedAt = %s, want the time the circuit opened", opened)
+	}
+
+	b.success(testURL)
+	if opened := b.openedAt(testURL); !opened.IsZero() {
+		t.Fatalf("openedAt = %s after a success, want zero", opened)
+	}
+}
+
+func TestDisableIfFailing(t *testing.T) {
+	opened := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	window := time.Hour
+
+	tests := []struct {
+		name        string
+		open        bool
+		updatedAt   time.Time
+		now         time.Time
+		wantDisable bool
+	}{
+		{
+			name:        "circuit closed",
+			open:        false,
+			now:         opened.Add(2 * window),
+			wantDisable: false,
+		},
+		{
+			name:        "open for less than the window",
+			open:        true,
+			now:         opened.Add(window - time.Second),
+			wantDisable: false,
+		},
+		{
+			name:        "open for the whole window",
+			open:        true,
+			now:         opened.Add(window),
+			wantDisable: true,
+		},
+		{
+			name:        "webhook changed while the circuit was open",
+			open:        true,
+			updatedAt:   opened.Add(30 * time.Minute),
+			now:         opened.Add(window),
+			wantDisable: false,
+		},
+		{
+			name:        "webhook changed a whole window ago",
+			open:        true,
+			updatedAt:   opened.Add(30 * time.Minute),
+			now:         opened.Add(30*time.Minute + window),
+			wantDisable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeWebhookRepository()
+			d := newTestDispatcher(config.WebhookConfig{
+				BreakerThreshold:     1,
+				BreakerProbeInterval: 30,
+				DisableAfter:         int(window / time.Minute),
+			}, repo)
+
+			if tt.open {
+				d.breaker.failure(testURL, opened)
+			}
+
+			webhookID := uuid.New()
+			target := webhookTarget{WebhookID: &webhookID, URL: testURL, UpdatedAt: tt.updatedAt}
+			delivery := entity.NewWebhookDelivery(uuid.New(), uuid.New(), &webhookID, entity.WebhookEventMessagesUpsert, "{}")
+
+			disabled := d.disableIfFailing(context.Background(), delivery, target, errors.New("unexpected status code: 503"), tt.now)
+			if disabled != tt.wantDisable {
+				t.Fatalf("disabled = %v, want %v", disabled, tt.wantDisable)
+			}
+
+			enabled, set := repo.enabled[webhookID]
+			if tt.wantDisable != (set && !enabled) {
+				t.Fatalf("webhook disabled in the repository = %v, want %v", set && !enabled, tt.wantDisable)
+			}
+
+			// The errors event looks the webhooks of the instance up to be dispatched
+			select {
+			case instanceID := <-repo.listed:
+				if !tt.wantDisable {
+					t.Fatal("errors event dispatched without disabling the webhook")
+				}
+				if instanceID != delivery.InstanceID {
+					t.Fatalf("errors event dispatched to %s, want %s", instanceID, delivery.InstanceID)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.wantDisable {
+					t.Fatal("no errors event dispatched")
+				}
+			}
+		})
+	}
+}
+
+func TestDisableIfFailingGlobalWebhook(t *testing.T) {
+	opened := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	d := newTestDispatcher(config.WebhookConfig{BreakerThreshold: 1, DisableAfter: 1}, newFakeWebhookRepository())
+	d.breaker.failure(testURL, opened)
+
+	delivery := entity.NewWebhookDelivery(uuid.New(), uuid.New(), nil, entity.WebhookEventMessagesUpsert, "{}")
+	if d.disableIfFailing(context.Background(), delivery, webhookTarget{URL: testURL}, errors.New("timeout"), opened.Add(time.Hour)) {
+		t.Fatal("the global webhook can't be disabled")
+	}
+}
+
+func newTestDispatcher(cfg config.WebhookConfig, repo *fakeWebhookRepository) *Dispatcher {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	d := NewDispatcher(cfg, logger)
+	d.SetWebhookRepository(repo)
+	return d
+}
+
+// fakeWebhookRepository records the webhooks enabled or disabled and the instances whose webhooks
+// were listed
+type fakeWebhookRepository struct {
+	enabled map[uuid.UUID]bool
+	listed  chan uuid.UUID
+}
+
+func newFakeWebhookRepository() *fakeWebhookRepository {
+	return &fakeWebhookRepository{
+		enabled: make(map[uuid.UUID]bool),
+		listed:  make(chan uuid.UUID, 1),
+	}
+}
+
+func (r *fakeWebhookRepository) Create(ctx context.Context, webhook *entity.Webhook) error {
+	return nil
+}
+
+func (r *fakeWebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Webhook, error) {
+	return nil, nil
+}
+
+func (r *fakeWebhookRepository) GetByInstance(ctx context.Context, instanceID uuid.UUID) (*entity.Webhook, error) {
+	return nil, nil
+}
+
+func (r *fakeWebhookRepository) ListByInstance(ctx context.Context, instanceID uuid.UUID) ([]*entity.Webhook, error) {
+	r.listed <- instanceID
+	return nil, nil
+}
+
+func (r *fakeWebhookRepository) Update(ctx context.Context, webhook *entity.Webhook) error {
+	return nil
+}
+
+func (r *fakeWebhookRepository) Upsert(ctx context.Context, webhook *entity.Webhook) error {
+	return nil
+}
+
+func (r *fakeWebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (r *fakeWebhookRepository) DeleteByInstance(ctx context.Context, instanceID uuid.UUID) error {
+	return nil
+}
+
+func (r *fakeWebhookRepository) SetEnabled(ctx context.Context, id uuid.UUID, enabled bool) error {
+	r.enabled[id] = enabled
+	return nil
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -23,9 +25,15 @@ type Dispatcher struct {
 	config      config.WebhookConfig
 	logger      *logrus.Logger
 	webhookRepo repository.WebhookRepository
+	outboxRepo  repository.WebhookOutboxRepository
+	logRepo     repository.WebhookDeliveryLogRepository
 	instanceMap map[uuid.UUID]string // maps instance ID to instance name
 	httpClient  *http.Client
 	mu          sync.RWMutex
+
+	workerID string          // Identifies the outbox workers of this process
+	wake     chan struct{}   // Wakes a worker up when deliveries are enqueued
+	breaker  *circuitBreaker // Holds back deliveries to the URLs that keep failing
 }
 
 // NewDispatcher creates a new webhook dispatcher
@@ -37,6 +45,9 @@ func NewDispatcher(cfg config.WebhookConfig, logger *logrus.Logger) *Dispatcher
 		httpClient: &http.Client{
 			Timeout: time.Duration(cfg.Timeout) * time.Second,
 		},
+		workerID: uuid.NewString(),
+		wake:     make(chan struct{}, 1),
+		breaker:  newCircuitBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerProbeInterval)*time.Second),
 	}
 }
 
@@ -45,6 +56,17 @@ func (d *Dispatcher) SetWebhookRepository(repo repository.WebhookRepository) {
 	d.webhookRepo = repo
 }
 
+// SetOutboxRepository makes events go through the durable outbox, delivered by the workers
+// started with Start. Without it events are delivered right away with in-memory retries.
+func (d *Dispatcher) SetOutboxRepository(repo repository.WebhookOutboxRepository) {
+	d.outboxRepo = repo
+}
+
+// SetDeliveryLogRepository makes the outbox workers record every delivery attempt
+func (d *Dispatcher) SetDeliveryLogRepository(repo repository.WebhookDeliveryLogRepository) {
+	d.logRepo = repo
+}
+
 // RegisterInstance registers an instance for webhook dispatching
 func (d *Dispatcher) RegisterInstance(instanceID uuid.UUID, instanceName string) {
 	d.mu.Lock()
@@ -59,7 +81,7 @@ func (d *Dispatcher) UnregisterInstance(instanceID uuid.UUID) {
 	delete(d.instanceMap, instanceID)
 }
 
-// Dispatch sends an event to the configured webhook
+// Dispatch sends an event to the configured webhooks
 func (d *Dispatcher) Dispatch(instanceID uuid.UUID, event entity.WebhookEvent, data interface{}) {
 	d.logger.WithFields(logrus.Fields{
 		"event":       string(event),
@@ -76,7 +98,9 @@ func (d *Dispatcher) dispatchAsync(instanceID uuid.UUID, event entity.WebhookEve
 	instanceName := d.instanceMap[instanceID]
 	d.mu.RUnlock()
 
+	eventID := uuid.New()
 	payload := entity.WebhookPayload{
+		EventID:    eventID.String(),
 		Event:      event,
 		InstanceID: instanceID.String(),
 		Instance:   instanceName,
@@ -84,88 +108,114 @@ func (d *Dispatcher) dispatchAsync(instanceID uuid.UUID, event entity.WebhookEve
 		Data:       data,
 	}
 
-	d.dispatchInstanceWebhook(ctx, instanceID, payload)
-	d.dispatchGlobalWebhook(ctx, payload)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.logger.WithError(err).WithField("event", string(event)).Error("Failed to marshal webhook payload")
+		return
+	}
+
+	targets := d.instanceTargets(ctx, instanceID, event)
+	if d.globalSubscribed(event) {
+		targets = append(targets, d.globalTarget())
+	}
+
+	deliveries := make([]*entity.WebhookDelivery, len(targets))
+	for i, target := range targets {
+		deliveries[i] = entity.NewWebhookDelivery(eventID, instanceID, target.WebhookID, event, string(body))
+	}
+
+	if d.outboxRepo != nil {
+		err := d.outboxRepo.Enqueue(ctx, deliveries)
+		if err == nil {
+			d.wakeWorker()
+			return
+		}
+		d.logger.WithError(err).WithField("event", string(event)).Error("Failed to enqueue webhook deliveries, delivering them right away")
+	}
+
+	// Deliveries sent from memory may wait for an open circuit, up to the longest retry delay
+	fallbackCtx, cancelFallback := context.WithTimeout(context.Background(), outboxRetryMax)
+	defer cancelFallback()
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(target webhookTarget, delivery *entity.WebhookDelivery) {
+			defer wg.Done()
+			d.sendWithRetry(fallbackCtx, target, delivery)
+		}(targets[i], deliveries[i])
+	}
+	wg.Wait()
 }
 
-func (d *Dispatcher) dispatchInstanceWebhook(ctx context.Context, instanceID uuid.UUID, payload entity.WebhookPayload) {
+// instanceTargets returns the webhooks of the instance subscribed to the event
+func (d *Dispatcher) instanceTargets(ctx context.Context, instanceID uuid.UUID, event entity.WebhookEvent) []webhookTarget {
 	if d.webhookRepo == nil {
-		return
+		return nil
 	}
 
-	webhook, err := d.webhookRepo.GetByInstance(ctx, instanceID)
+	webhooks, err := d.webhookRepo.ListByInstance(ctx, instanceID)
 	if err != nil {
 		d.logger.WithError(err).WithFields(logrus.Fields{
 			"instance_id": instanceID.String(),
 		}).Error("Failed to get webhook config")
-		return
+		return nil
 	}
 
-	if webhook == nil {
+	if len(webhooks) == 0 {
 		d.logger.WithFields(logrus.Fields{
 			"instance_id": instanceID.String(),
 		}).Warn("No webhook configured for instance")
-		return
+		return nil
 	}
 
-	if !webhook.Enabled {
-		d.logger.WithFields(logrus.Fields{
-			"instance_id": instanceID.String(),
-		}).Info("Webhook disabled for instance")
-		return
-	}
-
-	d.logger.WithFields(logrus.Fields{
-		"url":    webhook.URL,
-		"events": webhook.Events,
-	}).Info("Found webhook config")
-
-	if !webhook.ShouldTrigger(payload.Event) {
-		d.logger.WithFields(logrus.Fields{
-			"event":             string(payload.Event),
-			"subscribed_events": webhook.Events,
-		}).Info("Webhook not subscribed to event")
-		return
-	}
-
-	target := webhookTarget{
-		URL:       webhook.URL,
-		Headers:   webhook.Headers,
-		ByEvents:  webhook.WebhookByEvents,
-		UseBase64: webhook.UseBase64,
-		Label:     "instance",
+	var targets []webhookTarget
+	for _, webhook := range webhooks {
+		if !webhook.ShouldTrigger(event) {
+			d.logger.WithFields(logrus.Fields{
+				"webhook_id":        webhook.ID.String(),
+				"event":             string(event),
+				"enabled":           webhook.Enabled,
+				"subscribed_events": webhook.Events,
+			}).Debug("Webhook not subscribed to event")
+			continue
+		}
+		targets = append(targets, instanceTarget(webhook))
 	}
-
-	d.sendWithRetry(ctx, target, payload)
+	return targets
 }
 
-func (d *Dispatcher) dispatchGlobalWebhook(ctx context.Context, payload entity.WebhookPayload) {
+// globalSubscribed returns true if the global webhook is enabled and subscribed to the event
+func (d *Dispatcher) globalSubscribed(event entity.WebhookEvent) bool {
 	if !d.config.GlobalEnabled || d.config.GlobalURL == "" {
-		return
+		return false
 	}
 
 	if len(d.config.GlobalEvents) > 0 {
-		allowed, ok := d.config.GlobalEvents[payload.Event.Slug()]
+		allowed, ok := d.config.GlobalEvents[event.Slug()]
 		if !ok || !allowed {
-			return
+			return false
 		}
 	}
+	return true
+}
 
-	target := webhookTarget{
+// globalTarget returns the delivery target of the global webhook
+func (d *Dispatcher) globalTarget() webhookTarget {
+	return webhookTarget{
 		URL:       d.config.GlobalURL,
 		Headers:   nil,
 		ByEvents:  d.config.GlobalWebhookByEvents,
 		UseBase64: d.config.GlobalBase64,
+		Secrets:   globalSecrets(d.config),
 		Label:     "global",
 	}
-
-	d.sendWithRetry(ctx, target, payload)
 }
 
-func (d *Dispatcher) sendWithRetry(ctx context.Context, target webhookTarget, payload entity.WebhookPayload) {
+func (d *Dispatcher) sendWithRetry(ctx context.Context, target webhookTarget, delivery *entity.WebhookDelivery) {
 	d.logger.WithFields(logrus.Fields{
 		"url":   target.URL,
-		"event": string(payload.Event),
+		"event": string(delivery.Event),
 	}).Info("🚀 Attempting to send webhook")
 
 	var lastErr error
@@ -181,11 +231,16 @@ func (d *Dispatcher) sendWithRetry(ctx context.Context, target webhookTarget, pa
 			}
 		}
 
-		err := d.send(ctx, target, payload)
+		if !d.waitForCircuit(ctx, target, delivery) {
+			return
+		}
+
+		_, err := d.send(ctx, target, delivery)
+		d.recordOutcome(ctx, target, err)
 		if err == nil {
 			d.logger.WithFields(logrus.Fields{
 				"url":    target.URL,
-				"event":  string(payload.Event),
+				"event":  string(delivery.Event),
 				"target": target.Label,
 			}).Debug("Webhook delivered successfully")
 			return
@@ -194,7 +249,7 @@ func (d *Dispatcher) sendWithRetry(ctx context.Context, target webhookTarget, pa
 		lastErr = err
 		d.logger.WithError(err).WithFields(logrus.Fields{
 			"url":     target.URL,
-			"event":   string(payload.Event),
+			"event":   string(delivery.Event),
 			"attempt": attempt + 1,
 			"target":  target.Label,
 		}).Warn("Webhook delivery failed")
@@ -202,34 +257,81 @@ func (d *Dispatcher) sendWithRetry(ctx context.Context, target webhookTarget, pa
 
 	d.logger.WithError(lastErr).WithFields(logrus.Fields{
 		"url":    target.URL,
-		"event":  string(payload.Event),
+		"event":  string(delivery.Event),
 		"target": target.Label,
 	}).Error("Webhook delivery failed after all retries")
 }
 
-func (d *Dispatcher) send(ctx context.Context, target webhookTarget, payload entity.WebhookPayload) error {
-	// Marshal payload
-	body, contentType, err := encodePayload(payload, target.UseBase64)
-	if err != nil {
-		return err
+// sendResult describes the outcome of a single delivery attempt
+type sendResult struct {
+	URL        string
+	StatusCode int    // 0 when no response was received
+	Response   string // Response body, truncated to maxLoggedResponse bytes
+	Latency    time.Duration
+}
+
+// maxLoggedResponse bounds how much of a response body is kept in the delivery log
+const maxLoggedResponse = 1024
+
+// waitForCircuit holds a delivery sent from memory while the circuit of its target is open,
+// returning true once it can be sent. Whenever the circuit would let a probe through, it tries to
+// hand the delivery over to the outbox instead, returning false if it did or the context ended.
+func (d *Dispatcher) waitForCircuit(ctx context.Context, target webhookTarget, delivery *entity.WebhookDelivery) bool {
+	logger := d.logger.WithFields(logrus.Fields{
+		"url":    target.URL,
+		"event":  string(delivery.Event),
+		"target": target.Label,
+	})
+
+	for {
+		allowed, retryAt := d.breaker.allow(target.URL, time.Now())
+		if allowed {
+			return true
+		}
+
+		timer := time.NewTimer(time.Until(retryAt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Error("Circuit still open, giving up webhook delivery")
+			return false
+		case <-timer.C:
+		}
+
+		if d.outboxRepo == nil {
+			continue
+		}
+		if err := d.outboxRepo.Enqueue(ctx, []*entity.WebhookDelivery{delivery}); err != nil {
+			logger.WithError(err).Debug("Outbox still unavailable, holding webhook delivery")
+			continue
+		}
+		d.wakeWorker()
+		logger.Info("Moved held webhook delivery to the outbox")
+		return false
 	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, target webhookTarget, delivery *entity.WebhookDelivery) (sendResult, error) {
+	body, contentType := encodeBody([]byte(delivery.Payload), target.UseBase64)
 
 	url := target.URL
 	if target.ByEvents {
-		url = appendEventSlug(url, payload.Event)
+		url = appendEventSlug(url, delivery.Event)
 	}
+	result := sendResult{URL: url}
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return result, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("User-Agent", "TurboZap-Webhook/1.0")
-	req.Header.Set("X-Webhook-Event", string(payload.Event))
-	req.Header.Set("X-Instance-ID", payload.InstanceID)
+	req.Header.Set("X-Webhook-Event", string(delivery.Event))
+	req.Header.Set("X-Instance-ID", delivery.InstanceID.String())
+	req.Header.Set(EventIDHeader, delivery.EventID.String())
 	if target.UseBase64 {
 		req.Header.Set("X-Content-Transfer-Encoding", "base64")
 	}
@@ -239,19 +341,37 @@ func (d *Dispatcher) send(ctx context.Context, target webhookTarget, payload ent
 		req.Header.Set(key, value)
 	}
 
+	// Sign the exact body sent so receivers can verify it came from us. Set after the custom
+	// headers so they can never forge or replace the signature
+	timestamp := time.Now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	if len(target.Secrets) > 0 {
+		req.Header.Set(SignatureHeader, signatureHeaderValue(target.Secrets, timestamp, body))
+	} else {
+		req.Header.Del(SignatureHeader)
+	}
+
 	// Send request
+	start := time.Now()
 	resp, err := d.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		result.Latency = time.Since(start)
+		return result, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, maxLoggedResponse))
+	result.Latency = time.Since(start)
+	result.StatusCode = resp.StatusCode
+	// Truncating may split a character, and Postgres rejects invalid UTF-8 and NUL bytes in text
+	result.Response = strings.ReplaceAll(strings.ToValidUTF8(string(response), ""), "\x00", "")
+
 	// Check status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return result, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	return nil
+	return result, nil
 }
 
 // DispatchBatch sends multiple events at once
@@ -265,11 +385,41 @@ func (d *Dispatcher) DispatchBatch(instanceID uuid.UUID, events []struct {
 }
 
 type webhookTarget struct {
+	WebhookID *uuid.UUID // Nil for the global webhook
 	URL       string
 	Headers   map[string]string
 	ByEvents  bool
 	UseBase64 bool
+	Secrets   []string
 	Label     string
+	UpdatedAt time.Time // Last change of the webhook, zero for the global webhook
+}
+
+// instanceTarget returns the delivery target of an instance webhook
+func instanceTarget(webhook *entity.Webhook) webhookTarget {
+	id := webhook.ID
+	return webhookTarget{
+		WebhookID: &id,
+		URL:       webhook.URL,
+		Headers:   webhook.Headers,
+		ByEvents:  webhook.WebhookByEvents,
+		UseBase64: webhook.UseBase64,
+		Secrets:   webhook.Secrets(),
+		Label:     "instance",
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+// globalSecrets returns the secrets global webhook deliveries are signed with
+func globalSecrets(cfg config.WebhookConfig) []string {
+	var secrets []string
+	if cfg.GlobalSecret != "" {
+		secrets = append(secrets, cfg.GlobalSecret)
+	}
+	if cfg.GlobalPreviousSecret != "" {
+		secrets = append(secrets, cfg.GlobalPreviousSecret)
+	}
+	return secrets
 }
 
 func appendEventSlug(base string, event entity.WebhookEvent) string {
@@ -287,16 +437,12 @@ func appendEventSlug(base string, event entity.WebhookEvent) string {
 	return fmt.Sprintf("%s/%s", base, slug)
 }
 
-func encodePayload(payload entity.WebhookPayload, useBase64 bool) ([]byte, string, error) {
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
+// encodeBody returns the request body and its content type, base64 encoding the JSON payload if asked to
+func encodeBody(payload []byte, useBase64 bool) ([]byte, string) {
 	if !useBase64 {
-		return body, "application/json", nil
+		return payload, "application/json"
 	}
 
-	encoded := base64.StdEncoding.EncodeToString(body)
-	return []byte(encoded), "text/plain", nil
+	encoded := base64.StdEncoding.EncodeToString(payload)
+	return []byte(encoded), "text/plain"
 }
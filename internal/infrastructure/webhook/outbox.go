@@ -0,0 +1,266 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// outboxPollInterval is how often idle workers look for due deliveries
+	outboxPollInterval = time.Second
+
+	// outboxLeaseMargin is added to the request timeout so a claimed delivery is not taken over while it is sent
+	outboxLeaseMargin = 30 * time.Second
+
+	// outboxRetryBase and outboxRetryMax bound the wait between delivery attempts
+	outboxRetryBase = 30 * time.Second
+	outboxRetryMax  = time.Hour
+
+	// outboxPurgeInterval is how often delivered and dead-lettered events past their retention are removed
+	outboxPurgeInterval = time.Hour
+
+	// outboxStateTimeout bounds recording the outcome of a delivery, even while shutting down
+	outboxStateTimeout = 5 * time.Second
+)
+
+// outboxRetryDelay returns the wait before retrying a delivery after the given number of attempts:
+// the base delay quadrupled on every attempt, capped at outboxRetryMax
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxRetryMax; i++ {
+		delay *= 4
+	}
+	if delay > outboxRetryMax {
+		delay = outboxRetryMax
+	}
+	return delay
+}
+
+// Start runs the workers delivering the outbox and purging delivered and dead-lettered events. It
+// stops when the context is cancelled; deliveries in flight are retried once their lease expires.
+func (d *Dispatcher) Start(ctx context.Context) {
+	if d.outboxRepo == nil {
+		return
+	}
+
+	workers := d.config.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go d.runWorker(ctx, fmt.Sprintf("%s-%d", d.workerID, i))
+	}
+
+	if d.config.DeliveryRetention > 0 || d.config.DeadLetterRetention > 0 {
+		go d.purgeOutbox(ctx)
+	}
+}
+
+// wakeWorker tells an idle worker that deliveries were enqueued
+func (d *Dispatcher) wakeWorker() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runWorker delivers due deliveries until the context is cancelled
+func (d *Dispatcher) runWorker(ctx context.Context, workerID string) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for d.deliverNext(ctx, workerID) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// deliverNext claims and sends the next due delivery, returning false when nothing was due
+func (d *Dispatcher) deliverNext(ctx context.Context, workerID string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	timeout := time.Duration(d.config.Timeout) * time.Second
+	delivery, err := d.outboxRepo.Claim(ctx, workerID, timeout+outboxLeaseMargin)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.WithError(err).Error("Failed to claim webhook delivery")
+		}
+		return false
+	}
+	if delivery == nil {
+		return false
+	}
+
+	d.deliver(ctx, workerID, delivery)
+	return true
+}
+
+// deliver sends a claimed delivery and records the outcome, rescheduling it while attempts remain
+func (d *Dispatcher) deliver(ctx context.Context, workerID string, delivery *entity.WebhookDelivery) {
+	logger := d.logger.WithFields(logrus.Fields{
+		"delivery_id": delivery.ID.String(),
+		"event_id":    delivery.EventID.String(),
+		"event":       string(delivery.Event),
+		"attempt":     delivery.Attempts,
+	})
+
+	target, reason, sendErr := d.resolveTarget(ctx, delivery)
+	var result sendResult
+	if reason == "" && sendErr == nil {
+		if allowed, retryAt := d.breaker.allow(target.URL, time.Now()); !allowed {
+			d.postpone(workerID, delivery, time.Until(retryAt), logger)
+			return
+		}
+
+		timeout := time.Duration(d.config.Timeout) * time.Second
+		sendCtx, cancel := context.WithTimeout(ctx, timeout)
+		result, sendErr = d.send(sendCtx, target, delivery)
+		cancel()
+		d.recordOutcome(ctx, target, sendErr)
+		if sendErr != nil && ctx.Err() == nil && d.disableIfFailing(ctx, delivery, target, sendErr, time.Now()) {
+			reason = fmt.Sprintf("webhook disabled after failing for too long: %v", sendErr)
+		}
+		logger = logger.WithFields(logrus.Fields{
+			"url":    target.URL,
+			"target": target.Label,
+		})
+	}
+
+	stateCtx, cancel := context.WithTimeout(context.Background(), outboxStateTimeout)
+	defer cancel()
+
+	// The URL is only set once the delivery was actually attempted
+	if result.URL != "" {
+		d.logAttempt(stateCtx, delivery, result, sendErr)
+	}
+
+	var err error
+	switch {
+	case reason != "":
+		logger.WithField("reason", reason).Warn("Moving webhook delivery to dead-letter")
+		err = d.outboxRepo.MarkDeadLetter(stateCtx, delivery.ID, workerID, reason)
+	case sendErr == nil:
+		logger.Debug("Webhook delivered successfully")
+		err = d.outboxRepo.MarkDelivered(stateCtx, delivery.ID, workerID)
+	case delivery.Attempts > d.config.RetryCount:
+		logger.WithError(sendErr).Error("Webhook delivery failed after all retries")
+		err = d.outboxRepo.MarkDeadLetter(stateCtx, delivery.ID, workerID, sendErr.Error())
+	default:
+		delay := outboxRetryDelay(delivery.Attempts)
+		logger.WithError(sendErr).WithField("retry_in", delay.String()).Warn("Webhook delivery failed")
+		err = d.outboxRepo.Retry(stateCtx, delivery.ID, workerID, delay, sendErr.Error())
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to record webhook delivery outcome")
+	}
+}
+
+// postpone puts a claimed delivery back in the outbox without counting the attempt, while the
+// circuit of its URL is open
+func (d *Dispatcher) postpone(workerID string, delivery *entity.WebhookDelivery, delay time.Duration, logger *logrus.Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxStateTimeout)
+	defer cancel()
+
+	logger.WithField("retry_in", delay.String()).Debug("Circuit open, postponing webhook delivery")
+	if err := d.outboxRepo.Postpone(ctx, delivery.ID, workerID, delay); err != nil {
+		logger.WithError(err).Error("Failed to postpone webhook delivery")
+	}
+}
+
+// logAttempt records an attempt at a delivery in the delivery log
+func (d *Dispatcher) logAttempt(ctx context.Context, delivery *entity.WebhookDelivery, result sendResult, sendErr error) {
+	if d.logRepo == nil {
+		return
+	}
+
+	log := &entity.WebhookDeliveryLog{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		URL:        result.URL,
+		StatusCode: result.StatusCode,
+		Response:   result.Response,
+		Success:    sendErr == nil,
+		LatencyMs:  result.Latency.Milliseconds(),
+		CreatedAt:  time.Now(),
+	}
+	if sendErr != nil {
+		log.ErrorMessage = sendErr.Error()
+	}
+
+	if err := d.logRepo.Create(ctx, log); err != nil {
+		d.logger.WithError(err).WithField("delivery_id", delivery.ID.String()).Warn("Failed to record webhook delivery attempt")
+	}
+}
+
+// resolveTarget returns where a delivery goes with the current webhook settings, or the reason it
+// can't be sent anymore
+func (d *Dispatcher) resolveTarget(ctx context.Context, delivery *entity.WebhookDelivery) (webhookTarget, string, error) {
+	if delivery.WebhookID == nil {
+		if !d.config.GlobalEnabled || d.config.GlobalURL == "" {
+			return webhookTarget{}, "global webhook disabled", nil
+		}
+		return d.globalTarget(), "", nil
+	}
+
+	if d.webhookRepo == nil {
+		return webhookTarget{}, "webhook repository not configured", nil
+	}
+
+	webhook, err := d.webhookRepo.GetByID(ctx, *delivery.WebhookID)
+	if err != nil {
+		return webhookTarget{}, "", err
+	}
+	if webhook == nil {
+		return webhookTarget{}, "webhook deleted", nil
+	}
+	if !webhook.Enabled {
+		return webhookTarget{}, "webhook disabled", nil
+	}
+	return instanceTarget(webhook), "", nil
+}
+
+// purgeOutbox periodically removes the events delivered or dead-lettered longer than their
+// retention ago
+func (d *Dispatcher) purgeOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if d.config.DeliveryRetention > 0 {
+			deleted, err := d.outboxRepo.DeleteDelivered(ctx, time.Duration(d.config.DeliveryRetention)*time.Hour)
+			if err != nil {
+				d.logger.WithError(err).Warn("Failed to purge delivered webhook events")
+			} else if deleted > 0 {
+				d.logger.WithField("deleted", deleted).Debug("Purged delivered webhook events")
+			}
+		}
+
+		if d.config.DeadLetterRetention > 0 {
+			deleted, err := d.outboxRepo.DeleteDeadLetters(ctx, time.Duration(d.config.DeadLetterRetention)*time.Hour)
+			if err != nil {
+				d.logger.WithError(err).Warn("Failed to purge dead-lettered webhook events")
+			} else if deleted > 0 {
+				d.logger.WithField("deleted", deleted).Debug("Purged dead-lettered webhook events")
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers identifying and signing a delivery
+const (
+	EventIDHeader   = "X-TurboZap-Event-ID"
+	SignatureHeader = "X-TurboZap-Signature"
+	TimestampHeader = "X-TurboZap-Timestamp"
+)
+
+// signatureScheme prefixes every signature of the signature header
+const signatureScheme = "sha256="
+
+// DefaultSignatureTolerance is how old a delivery can be before receivers should reject it as a replay
+const DefaultSignatureTolerance = 5 * time.Minute
+
+// Signature verification errors
+var (
+	ErrMissingSignature = errors.New("missing signature headers")
+	ErrInvalidTimestamp = errors.New("invalid signature timestamp")
+	ErrStaleTimestamp   = errors.New("signature timestamp outside of tolerance")
+	ErrInvalidSignature = errors.New("no signature matches the secret")
+)
+
+// GenerateSecret returns a random secret to sign deliveries with
+func GenerateSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" with the secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signatureHeaderValue signs the body with every secret, receivers accept the delivery if any of
+// the signatures matches so a secret can be rotated without downtime
+func signatureHeaderValue(secrets []string, timestamp int64, body []byte) string {
+	signatures := make([]string, len(secrets))
+	for i, secret := range secrets {
+		signatures[i] = signatureScheme + Sign(secret, timestamp, body)
+	}
+	return strings.Join(signatures, ",")
+}
+
+// VerifySignature checks the signature and timestamp headers of a delivery against a secret,
+// rejecting deliveries whose timestamp is further than tolerance from now
+func VerifySignature(secret, signatureHeader, timestampHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	if signatureHeader == "" || timestampHeader == "" {
+		return ErrMissingSignature
+	}
+
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	age := now.Sub(time.Unix(timestamp, 0))
+	if tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return ErrStaleTimestamp
+	}
+
+	expected := []byte(Sign(secret, timestamp, body))
+	for _, signature := range strings.Split(signatureHeader, ",") {
+		signature = strings.TrimPrefix(strings.TrimSpace(signature), signatureScheme)
+		if hmac.Equal([]byte(signature), expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
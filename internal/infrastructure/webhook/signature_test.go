@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"messages.upsert"}`)
+
+	if Sign("secret", 1705312800, body) != Sign("secret", 1705312800, body) {
+		t.Fatal("signing the same delivery twice gave different signatures")
+	}
+	if Sign("secret", 1705312800, body) == Sign("other", 1705312800, body) {
+		t.Fatal("different secrets gave the same signature")
+	}
+	if Sign("secret", 1705312800, body) == Sign("secret", 1705312801, body) {
+		t.Fatal("different timestamps gave the same signature")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	body := []byte(`{"event":"messages.upsert","data":{"id":"3EB0A1"}}`)
+	timestamp := now.Unix()
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		timestamp string
+		body      []byte
+		tolerance time.Duration
+		wantErr   error
+	}{
+		{
+			name:      "round trip",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current"}, timestamp, body),
+			timestamp: strconv.FormatInt(timestamp, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+		},
+		{
+			name:      "current secret while rotating",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current", "previous"}, timestamp, body),
+			timestamp: strconv.FormatInt(timestamp, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+		},
+		{
+			name:      "previous secret while rotating",
+			secret:    "previous",
+			signature: signatureHeaderValue([]string{"current", "previous"}, timestamp, body),
+			timestamp: strconv.FormatInt(timestamp, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+		},
+		{
+			name:      "secret no longer signing",
+			secret:    "retired",
+			signature: signatureHeaderValue([]string{"current", "previous"}, timestamp, body),
+			timestamp: strconv.FormatInt(timestamp, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "tampered body",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current"}, timestamp, body),
+			timestamp: strconv.FormatInt(timestamp, 10),
+			body:      []byte(`{"event":"messages.upsert","data":{"id":"3EB0A2"}}`),
+			tolerance: DefaultSignatureTolerance,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "tampered timestamp",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current"}, timestamp, body),
+			timestamp: strconv.FormatInt(timestamp+1, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+			wantErr:   ErrInvalidSignature,
+		},
+		{
+			name:      "old delivery within the tolerance",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current"}, timestamp-299, body),
+			timestamp: strconv.FormatInt(timestamp-299, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+		},
+		{
+			name:      "old delivery outside of the tolerance",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current"}, timestamp-301, body),
+			timestamp: strconv.FormatInt(timestamp-301, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+			wantErr:   ErrStaleTimestamp,
+		},
+		{
+			name:      "delivery from the future outside of the tolerance",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current"}, timestamp+301, body),
+			timestamp: strconv.FormatInt(timestamp+301, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+			wantErr:   ErrStaleTimestamp,
+		},
+		{
+			name:      "no tolerance accepts any timestamp",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current"}, timestamp-86400, body),
+			timestamp: strconv.FormatInt(timestamp-86400, 10),
+			body:      body,
+		},
+		{
+			name:      "missing signature",
+			secret:    "current",
+			timestamp: strconv.FormatInt(timestamp, 10),
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+			wantErr:   ErrMissingSignature,
+		},
+		{
+			name:      "invalid timestamp",
+			secret:    "current",
+			signature: signatureHeaderValue([]string{"current"}, timestamp, body),
+			timestamp: "yesterday",
+			body:      body,
+			tolerance: DefaultSignatureTolerance,
+			wantErr:   ErrInvalidTimestamp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.signature, tt.timestamp, tt.body, tt.tolerance, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifySignature() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/jonadableite/turbozap-api/internal/application/dto"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// Call statuses reported in call webhooks
+const (
+	callStatusRinging  = "ringing"
+	callStatusAnswered = "answered"
+	callStatusRejected = "rejected"
+	callStatusMissed   = "missed"
+)
+
+// callRejectTimeout bounds the automatic rejection of a call and the reply sent to the caller
+const callRejectTimeout = 15 * time.Second
+
+func (h *EventHandler) handleCallOffer(evt *events.CallOffer) {
+	isVideo := false
+	if evt.Data != nil {
+		_, isVideo = evt.Data.GetOptionalChildByTag("video")
+	}
+	h.handleIncomingCall(evt.BasicCallMeta, isVideo)
+}
+
+func (h *EventHandler) handleCallOfferNotice(evt *events.CallOfferNotice) {
+	h.handleIncomingCall(evt.BasicCallMeta, evt.Media == "video")
+}
+
+// handleIncomingCall emits call.received and rejects the call when the instance is configured to
+func (h *EventHandler) handleIncomingCall(meta types.BasicCallMeta, isVideo bool) {
+	data := newCallEventData(meta, callStatusRinging)
+	data.IsVideo = isVideo
+	h.calls.Store(meta.CallID, callStatusRinging)
+
+	h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventCallReceived, data)
+
+	settings := h.currentSettings()
+	if !settings.RejectCalls || h.waClient == nil {
+		return
+	}
+
+	go h.rejectCall(meta, data, settings.RejectCallMessage)
+}
+
+// rejectCall rejects an incoming call and optionally replies to the caller with a text message
+func (h *EventHandler) rejectCall(meta types.BasicCallMeta, data dto.CallEventData, reply string) {
+	ctx, cancel := context.WithTimeout(context.Background(), callRejectTimeout)
+	defer cancel()
+
+	caller := callerJID(meta)
+	logger := h.logger.WithFields(logrus.Fields{
+		"instance": h.instanceName,
+		"call_id":  meta.CallID,
+		"from":     caller.String(),
+	})
+
+	if err := h.waClient.RejectCall(ctx, caller, meta.CallID); err != nil {
+		logger.WithError(err).Warn("Failed to reject incoming call")
+		return
+	}
+	h.calls.Store(meta.CallID, callStatusRejected)
+
+	data.Status = callStatusRejected
+	h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventCallMissed, data)
+	logger.Info("Incoming call rejected automatically")
+
+	// Replies to group calls would go to the caller privately, so only direct calls get one
+	if reply == "" || !meta.GroupJID.IsEmpty() {
+		return
+	}
+	if _, err := h.waClient.SendMessage(ctx, caller, &waE2E.Message{Conversation: proto.String(reply)}); err != nil {
+		logger.WithError(err).Warn("Failed to send rejected call reply")
+	}
+}
+
+func (h *EventHandler) handleCallAccept(evt *events.CallAccept) {
+	h.calls.Store(evt.CallID, callStatusAnswered)
+}
+
+// handleCallTerminate emits call.missed for calls that ended without being answered or rejected by us
+func (h *EventHandler) handleCallTerminate(evt *events.CallTerminate) {
+	status, _ := h.calls.LoadAndDelete(evt.CallID)
+	if status == callStatusAnswered || status == callStatusRejected {
+		return
+	}
+
+	data := newCallEventData(evt.BasicCallMeta, callStatusMissed)
+	data.Reason = evt.Reason
+	h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventCallMissed, data)
+}
+
+// newCallEventData builds the webhook payload of a call event
+func newCallEventData(meta types.BasicCallMeta, status string) dto.CallEventData {
+	data := dto.CallEventData{
+		CallID:    meta.CallID,
+		From:      callerJID(meta).String(),
+		Timestamp: meta.Timestamp.Unix(),
+		Status:    status,
+	}
+	if !meta.GroupJID.IsEmpty() {
+		data.Group = meta.GroupJID.String()
+	}
+	return data
+}
+
+// callerJID returns the user who started a call
+func callerJID(meta types.BasicCallMeta) types.JID {
+	if !meta.CallCreator.IsEmpty() {
+		return meta.CallCreator.ToNonAD()
+	}
+	return meta.From.ToNonAD()
+}
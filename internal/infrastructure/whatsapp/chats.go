@@ -0,0 +1,472 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/application/dto"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/proto/waCommon"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// Sources of a chats.update event
+const (
+	chatUpdateSourceAPI    = "api"
+	chatUpdateSourceDevice = "device"
+)
+
+// maxReadReceipts bounds how many unread messages get a read receipt when a chat is marked as read
+const maxReadReceipts = 100
+
+// updatesChatList returns true if a message of the given type shows up as last message of a chat
+func updatesChatList(chatJID string, msgType string) bool {
+	if chatJID == "" || chatJID == types.StatusBroadcastJID.String() {
+		return false
+	}
+	switch msgType {
+	case "", "unknown", "reaction":
+		return false
+	}
+	return true
+}
+
+// recordChatMessage updates the chat list with a received message
+func (h *EventHandler) recordChatMessage(msgEvent dto.MessageReceivedEvent) {
+	if h.chatRepo == nil || !updatesChatList(msgEvent.To, msgEvent.Type) {
+		return
+	}
+
+	chat := entity.NewChat(h.instanceID, msgEvent.To)
+	chat.IsGroup = msgEvent.IsGroup
+	text := msgEvent.Content
+	if text == "" {
+		text = msgEvent.Caption
+	}
+	chat.SetLastMessage(msgEvent.MessageID, entity.MessageType(msgEvent.Type), text, msgEvent.FromMe, msgEvent.Timestamp)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := h.chatRepo.RecordMessage(ctx, chat); err != nil {
+			h.logger.WithError(err).Warn("Failed to update chat with received message")
+			return
+		}
+
+		// Auto-read messages never count as unread
+		if !chat.LastMessageFromMe && h.currentSettings().ReadMessages {
+			if err := h.chatRepo.MarkRead(ctx, h.instanceID, msgEvent.To); err != nil {
+				h.logger.WithError(err).Warn("Failed to mark chat as read")
+			}
+		}
+
+		if chat.IsGroup {
+			h.resolveGroupName(ctx, msgEvent.To)
+		}
+	}()
+}
+
+// resolveGroupName fetches the subject of a group the chat list does not know the name of yet
+func (h *EventHandler) resolveGroupName(ctx context.Context, groupJID string) {
+	if h.waClient == nil {
+		return
+	}
+
+	chat, err := h.chatRepo.GetByJID(ctx, h.instanceID, groupJID)
+	if err != nil || chat == nil || chat.Name != "" {
+		return
+	}
+
+	jid, err := types.ParseJID(groupJID)
+	if err != nil {
+		return
+	}
+
+	info, err := h.waClient.GetGroupInfo(ctx, jid)
+	if err != nil || info.Name == "" {
+		return
+	}
+
+	if err := h.chatRepo.UpdateName(ctx, h.instanceID, groupJID, info.Name); err != nil {
+		h.logger.WithError(err).Warn("Failed to update group chat name")
+	}
+}
+
+// recordChatRead clears the unread count of a chat read from one of our own devices
+func (h *EventHandler) recordChatRead(evt *events.Receipt) {
+	if h.chatRepo == nil || !evt.IsFromMe {
+		return
+	}
+	if evt.Type != types.ReceiptTypeRead && evt.Type != types.ReceiptTypeReadSelf {
+		return
+	}
+
+	chatJID := evt.Chat.String()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := h.chatRepo.MarkRead(ctx, h.instanceID, chatJID); err != nil {
+			h.logger.WithError(err).Warn("Failed to mark chat as read")
+		}
+	}()
+}
+
+// recordChatName stores the new subject of a group in the chat list
+func (h *EventHandler) recordChatName(groupJID, name string) {
+	if h.chatRepo == nil || name == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := h.chatRepo.UpdateName(ctx, h.instanceID, groupJID, name); err != nil {
+			h.logger.WithError(err).Warn("Failed to update group chat name")
+		}
+	}()
+}
+
+// recordSentChatMessage updates the chat list with a message sent through the API
+func (m *Manager) recordSentChatMessage(instanceID uuid.UUID, to types.JID, messageType, messageID, text string) {
+	if m.chatRepo == nil || !updatesChatList(to.String(), messageType) {
+		return
+	}
+
+	chat := entity.NewChat(instanceID, to.ToNonAD().String())
+	chat.IsGroup = to.Server == types.GroupServer
+	chat.SetLastMessage(messageID, entity.MessageType(messageType), text, true, time.Now())
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := m.chatRepo.RecordMessage(ctx, chat); err != nil {
+			m.logger.WithError(err).Warn("Failed to update chat with sent message")
+		}
+	}()
+}
+
+// ArchiveChat archives or unarchives a chat on all devices of the account
+func (m *Manager) ArchiveChat(ctx context.Context, instanceID uuid.UUID, chat string, archive bool) error {
+	client, jid, err := m.getChatClient(instanceID, chat)
+	if err != nil {
+		return err
+	}
+
+	lastMessageAt, lastMessageKey := m.chatLastMessageKey(ctx, instanceID, jid)
+	if err := client.WAClient.SendAppState(ctx, appstate.BuildArchive(jid, archive, lastMessageAt, lastMessageKey)); err != nil {
+		return fmt.Errorf("failed to archive chat: %w", err)
+	}
+
+	m.saveChatState(ctx, func(chatRepo repository.ChatRepository) error {
+		return chatRepo.SetArchived(ctx, instanceID, jid.String(), archive)
+	})
+	m.dispatchChatUpdate(instanceID, dto.ChatUpdateEvent{
+		JID:      jid.String(),
+		Event:    "archive",
+		Source:   chatUpdateSourceAPI,
+		Archived: &archive,
+	})
+	return nil
+}
+
+// PinChat pins or unpins a chat on all devices of the account
+func (m *Manager) PinChat(ctx context.Context, instanceID uuid.UUID, chat string, pin bool) error {
+	client, jid, err := m.getChatClient(instanceID, chat)
+	if err != nil {
+		return err
+	}
+
+	if err := client.WAClient.SendAppState(ctx, appstate.BuildPin(jid, pin)); err != nil {
+		return fmt.Errorf("failed to pin chat: %w", err)
+	}
+
+	m.saveChatState(ctx, func(chatRepo repository.ChatRepository) error {
+		return chatRepo.SetPinned(ctx, instanceID, jid.String(), pin)
+	})
+	m.dispatchChatUpdate(instanceID, dto.ChatUpdateEvent{
+		JID:    jid.String(),
+		Event:  "pin",
+		Source: chatUpdateSourceAPI,
+		Pinned: &pin,
+	})
+	return nil
+}
+
+// MuteChat mutes a chat for the given duration, forever when it is zero, or unmutes it
+func (m *Manager) MuteChat(ctx context.Context, instanceID uuid.UUID, chat string, mute bool, duration time.Duration) error {
+	client, jid, err := m.getChatClient(instanceID, chat)
+	if err != nil {
+		return err
+	}
+
+	if err := client.WAClient.SendAppState(ctx, appstate.BuildMute(jid, mute, duration)); err != nil {
+		return fmt.Errorf("failed to mute chat: %w", err)
+	}
+
+	var mutedUntil *time.Time
+	if mute {
+		until := mutedForever
+		if duration > 0 {
+			until = time.Now().Add(duration)
+		}
+		mutedUntil = &until
+	}
+
+	m.saveChatState(ctx, func(chatRepo repository.ChatRepository) error {
+		return chatRepo.SetMutedUntil(ctx, instanceID, jid.String(), mutedUntil)
+	})
+	m.dispatchChatUpdate(instanceID, dto.ChatUpdateEvent{
+		JID:        jid.String(),
+		Event:      "mute",
+		Source:     chatUpdateSourceAPI,
+		Muted:      &mute,
+		MutedUntil: mutedUntil,
+	})
+	return nil
+}
+
+// MarkChatRead marks a chat as read, sending read receipts for its unread messages, or as unread
+func (m *Manager) MarkChatRead(ctx context.Context, instanceID uuid.UUID, chat string, read bool) error {
+	client, jid, err := m.getChatClient(instanceID, chat)
+	if err != nil {
+		return err
+	}
+
+	if read {
+		m.sendReadReceipts(ctx, client, instanceID, jid)
+	}
+
+	lastMessageAt, lastMessageKey := m.chatLastMessageKey(ctx, instanceID, jid)
+	if err := client.WAClient.SendAppState(ctx, appstate.BuildMarkChatAsRead(jid, read, lastMessageAt, lastMessageKey)); err != nil {
+		return fmt.Errorf("failed to mark chat as read: %w", err)
+	}
+
+	m.saveChatState(ctx, func(chatRepo repository.ChatRepository) error {
+		if read {
+			return chatRepo.MarkRead(ctx, instanceID, jid.String())
+		}
+		return chatRepo.MarkUnread(ctx, instanceID, jid.String())
+	})
+	m.dispatchChatUpdate(instanceID, dto.ChatUpdateEvent{
+		JID:    jid.String(),
+		Event:  "read",
+		Source: chatUpdateSourceAPI,
+		Read:   &read,
+	})
+	return nil
+}
+
+// getChatClient returns the connected client of an instance and the parsed chat JID
+func (m *Manager) getChatClient(instanceID uuid.UUID, chat string) (*Client, types.JID, error) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return nil, types.EmptyJID, fmt.Errorf("client not found")
+	}
+
+	jid, err := types.ParseJID(chat)
+	if err != nil {
+		return nil, types.EmptyJID, fmt.Errorf("invalid JID: %w", err)
+	}
+
+	if client.WAClient == nil {
+		return nil, types.EmptyJID, fmt.Errorf("WhatsApp client is not initialized")
+	}
+
+	return client, jid.ToNonAD(), nil
+}
+
+// chatLastMessageKey returns the last known message of a chat, which app state patches use to
+// tell other devices up to which message the action applies. Both values are optional.
+func (m *Manager) chatLastMessageKey(ctx context.Context, instanceID uuid.UUID, jid types.JID) (time.Time, *waCommon.MessageKey) {
+	if m.chatRepo == nil {
+		return time.Time{}, nil
+	}
+
+	chat, err := m.chatRepo.GetByJID(ctx, instanceID, jid.String())
+	if err != nil || chat == nil || chat.LastMessageAt == nil {
+		return time.Time{}, nil
+	}
+
+	// Keys of group messages from others need the participant, which the chat list does not keep
+	if chat.LastMessageID == "" || (chat.IsGroup && !chat.LastMessageFromMe) {
+		return *chat.LastMessageAt, nil
+	}
+
+	return *chat.LastMessageAt, &waCommon.MessageKey{
+		RemoteJID: proto.String(jid.String()),
+		FromMe:    proto.Bool(chat.LastMessageFromMe),
+		ID:        proto.String(chat.LastMessageID),
+	}
+}
+
+// sendReadReceipts sends read receipts for the most recent unread messages of a chat
+func (m *Manager) sendReadReceipts(ctx context.Context, client *Client, instanceID uuid.UUID, jid types.JID) {
+	if m.chatRepo == nil || m.messageRepo == nil {
+		return
+	}
+
+	chat, err := m.chatRepo.GetByJID(ctx, instanceID, jid.String())
+	if err != nil || chat == nil || chat.UnreadCount <= 0 {
+		return
+	}
+
+	limit := chat.UnreadCount
+	if limit > maxReadReceipts {
+		limit = maxReadReceipts
+	}
+	fromMe := false
+	messages, err := m.messageRepo.GetByRemoteJID(ctx, instanceID, jid.String(), repository.MessageFilter{
+		FromMe: &fromMe,
+		Limit:  limit,
+	})
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to load unread messages for read receipts")
+		return
+	}
+
+	// Receipts in groups are sent per author
+	bySender := make(map[string][]types.MessageID)
+	for _, message := range messages {
+		sender := ""
+		if jid.Server == types.GroupServer {
+			sender = message.SenderJID
+		}
+		bySender[sender] = append(bySender[sender], message.MessageID)
+	}
+
+	for sender, ids := range bySender {
+		senderJID := types.EmptyJID
+		if sender != "" {
+			if senderJID, err = types.ParseJID(sender); err != nil {
+				continue
+			}
+		}
+		if err := client.WAClient.MarkRead(ctx, ids, time.Now(), jid, senderJID); err != nil {
+			m.logger.WithError(err).Warn("Failed to send read receipts")
+		}
+	}
+}
+
+// saveChatState applies a chat state change to the chat list
+func (m *Manager) saveChatState(ctx context.Context, save func(chatRepo repository.ChatRepository) error) {
+	if m.chatRepo == nil {
+		return
+	}
+	if err := save(m.chatRepo); err != nil {
+		m.logger.WithError(err).Warn("Failed to save chat state")
+	}
+}
+
+// dispatchChatUpdate sends a chats.update event
+func (m *Manager) dispatchChatUpdate(instanceID uuid.UUID, event dto.ChatUpdateEvent) {
+	if m.dispatcher == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	m.dispatcher.Dispatch(instanceID, entity.WebhookEventChatsUpdate, event)
+}
+
+func (h *EventHandler) handleArchive(evt *events.Archive) {
+	archived := evt.Action.GetArchived()
+	h.saveChatState(func(ctx context.Context) error {
+		return h.chatRepo.SetArchived(ctx, h.instanceID, evt.JID.String(), archived)
+	})
+	h.dispatchChatUpdate(evt.FromFullSync, dto.ChatUpdateEvent{
+		JID:       evt.JID.String(),
+		Event:     "archive",
+		Archived:  &archived,
+		Timestamp: evt.Timestamp,
+	})
+}
+
+func (h *EventHandler) handlePin(evt *events.Pin) {
+	pinned := evt.Action.GetPinned()
+	h.saveChatState(func(ctx context.Context) error {
+		return h.chatRepo.SetPinned(ctx, h.instanceID, evt.JID.String(), pinned)
+	})
+	h.dispatchChatUpdate(evt.FromFullSync, dto.ChatUpdateEvent{
+		JID:       evt.JID.String(),
+		Event:     "pin",
+		Pinned:    &pinned,
+		Timestamp: evt.Timestamp,
+	})
+}
+
+func (h *EventHandler) handleMute(evt *events.Mute) {
+	muted := evt.Action.GetMuted()
+
+	// The mute end is in milliseconds, -1 when muted forever
+	var mutedUntil *time.Time
+	if muted {
+		until := mutedForever
+		if end := evt.Action.GetMuteEndTimestamp(); end > 0 {
+			until = time.UnixMilli(end)
+		}
+		mutedUntil = &until
+	}
+
+	h.saveChatState(func(ctx context.Context) error {
+		return h.chatRepo.SetMutedUntil(ctx, h.instanceID, evt.JID.String(), mutedUntil)
+	})
+	h.dispatchChatUpdate(evt.FromFullSync, dto.ChatUpdateEvent{
+		JID:        evt.JID.String(),
+		Event:      "mute",
+		Muted:      &muted,
+		MutedUntil: mutedUntil,
+		Timestamp:  evt.Timestamp,
+	})
+}
+
+func (h *EventHandler) handleMarkChatAsRead(evt *events.MarkChatAsRead) {
+	read := evt.Action.GetRead()
+	h.saveChatState(func(ctx context.Context) error {
+		if read {
+			return h.chatRepo.MarkRead(ctx, h.instanceID, evt.JID.String())
+		}
+		return h.chatRepo.MarkUnread(ctx, h.instanceID, evt.JID.String())
+	})
+	h.dispatchChatUpdate(evt.FromFullSync, dto.ChatUpdateEvent{
+		JID:       evt.JID.String(),
+		Event:     "read",
+		Read:      &read,
+		Timestamp: evt.Timestamp,
+	})
+}
+
+// saveChatState applies a chat state change received from another device to the chat list
+func (h *EventHandler) saveChatState(save func(ctx context.Context) error) {
+	if h.chatRepo == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := save(ctx); err != nil {
+			h.logger.WithError(err).Warn("Failed to save chat state")
+		}
+	}()
+}
+
+// dispatchChatUpdate sends a chats.update event for a change made on another device.
+// Full app state syncs replay every chat, so they only update the chat list.
+func (h *EventHandler) dispatchChatUpdate(fromFullSync bool, event dto.ChatUpdateEvent) {
+	if fromFullSync {
+		return
+	}
+	event.Source = chatUpdateSourceDevice
+	h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventChatsUpdate, event)
+}
@@ -2,8 +2,10 @@ package whatsapp
 
 import (
 	"context"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,11 +18,14 @@ import (
 	"github.com/jonadableite/turbozap-api/internal/application/dto"
 	"github.com/jonadableite/turbozap-api/internal/domain/entity"
 	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+	"github.com/jonadableite/turbozap-api/internal/infrastructure/storage"
 	"github.com/jonadableite/turbozap-api/pkg/config"
 	"github.com/sirupsen/logrus"
 	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/appstate"
 	"go.mau.fi/whatsmeow/proto/waCommon"
 	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
 	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	"go.mau.fi/whatsmeow/types"
@@ -28,16 +33,36 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+const (
+	// pairClientDisplayName is shown on the phone when linking with a pairing code
+	pairClientDisplayName = "Chrome (Linux)"
+	// pairCodeWaitTimeout bounds how long a pairing code request waits for the login websocket
+	pairCodeWaitTimeout = 15 * time.Second
+)
+
 // Manager manages multiple WhatsApp client instances
 type Manager struct {
-	config       *config.Config
-	logger       *logrus.Logger
-	dispatcher   WebhookDispatcher
-	instanceRepo repository.InstanceRepository
-	messageRepo  repository.MessageRepository
-	container    *sqlstore.Container
-	clients      map[uuid.UUID]*Client
-	mu           sync.RWMutex
+	config        *config.Config
+	logger        *logrus.Logger
+	dispatcher    WebhookDispatcher
+	instanceRepo  repository.InstanceRepository
+	messageRepo   repository.MessageRepository
+	chatRepo      repository.ChatRepository
+	contactRepo   repository.ContactRepository
+	pollRepo      repository.PollRepository
+	connEventRepo repository.ConnectionEventRepository
+	mediaStorage  *storage.Client
+	container     *sqlstore.Container
+	storeDB       *sql.DB // Database of the whatsmeow device store
+	clients       map[uuid.UUID]*Client
+	mu            sync.RWMutex
+
+	leaseMu     sync.Mutex
+	leaseRepo   repository.LeaseRepository
+	nodeID      string
+	nodeAddress string
+	leaseTTL    time.Duration
+	leases      map[uuid.UUID]struct{} // Instances whose lease this node holds
 }
 
 // Client represents a single WhatsApp client instance
@@ -50,6 +75,12 @@ type Client struct {
 	QRCodeImage string
 	Connected   bool
 	mu          sync.RWMutex
+
+	keepAliveFailingSince time.Time // When keepalive pings started failing, zero while they succeed
+
+	reconnectMu     sync.Mutex
+	reconnectCancel context.CancelFunc
+	reconnectState  ReconnectState
 }
 
 // NewManager creates a new WhatsApp manager
@@ -57,10 +88,19 @@ func NewManager(cfg *config.Config, pool *pgxpool.Pool, logger *logrus.Logger, d
 	// Create whatsmeow logger
 	waLogger := waLog.Stdout("whatsmeow", "INFO", true)
 
-	// Create SQL store container
+	// Create SQL store container, on the main database unless a dedicated store is configured
 	ctx := context.Background()
-	dbURL := pool.Config().ConnString()
-	container, err := sqlstore.New(ctx, "pgx", dbURL, waLogger)
+	storeURL := cfg.WhatsApp.StoreURL
+	if storeURL == "" {
+		storeURL = pool.Config().ConnString()
+		if cfg.WhatsApp.StoreDriver == DeviceStoreSQLite {
+			storeURL = defaultSQLiteStorePath
+		}
+	}
+	storeDB, container, err := OpenDeviceStore(ctx, cfg.WhatsApp.StoreDriver, storeURL, waLogger)
+	if container == nil {
+		logger.WithError(err).Fatal("Failed to open device store")
+	}
 	if err != nil {
 		// Check if error is about tables already existing (schema already initialized)
 		// This can happen if tables were created manually or by a previous run
@@ -80,10 +120,93 @@ func NewManager(cfg *config.Config, pool *pgxpool.Pool, logger *logrus.Logger, d
 		instanceRepo: instanceRepo,
 		messageRepo:  messageRepo,
 		container:    container,
+		storeDB:      storeDB,
 		clients:      make(map[uuid.UUID]*Client),
 	}
 }
 
+// SetMediaStorage sets the object storage used by instances that keep incoming media
+func (m *Manager) SetMediaStorage(mediaStorage *storage.Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mediaStorage = mediaStorage
+	for _, client := range m.clients {
+		if client.Handler != nil {
+			client.Handler.SetMediaStorage(mediaStorage, m.presignedURLTTL())
+		}
+	}
+}
+
+// SetChatRepositories sets the repositories used to persist chats and contacts
+func (m *Manager) SetChatRepositories(chatRepo repository.ChatRepository, contactRepo repository.ContactRepository) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.chatRepo = chatRepo
+	m.contactRepo = contactRepo
+	for _, client := range m.clients {
+		if client.Handler != nil {
+			client.Handler.SetChatRepositories(chatRepo, contactRepo)
+		}
+	}
+}
+
+// SetPollRepository sets the repository used to persist polls and their votes
+func (m *Manager) SetPollRepository(pollRepo repository.PollRepository) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pollRepo = pollRepo
+	for _, client := range m.clients {
+		if client.Handler != nil {
+			client.Handler.SetPollRepository(pollRepo)
+		}
+	}
+}
+
+// SetConnectionEventRepository sets the repository used to keep the connection history of instances
+func (m *Manager) SetConnectionEventRepository(connEventRepo repository.ConnectionEventRepository) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connEventRepo = connEventRepo
+	for _, client := range m.clients {
+		if client.Handler != nil {
+			client.Handler.SetConnectionEventRepository(connEventRepo)
+		}
+	}
+}
+
+// presignedURLTTL returns how long presigned media URLs stay valid
+func (m *Manager) presignedURLTTL() time.Duration {
+	return time.Duration(m.config.MinIO.PresignedURLExpiry) * time.Second
+}
+
+// ApplySettings updates the settings of a running instance
+func (m *Manager) ApplySettings(instanceID uuid.UUID, settings entity.InstanceSettings) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return
+	}
+
+	client.mu.Lock()
+	previous := client.Instance.Settings
+	client.Instance.Settings = settings
+	client.mu.Unlock()
+
+	if previous.AlwaysOnline != settings.AlwaysOnline {
+		go m.applyPresence(client, settings.AlwaysOnline)
+	}
+}
+
+// Settings returns the current settings of the instance
+func (c *Client) Settings() entity.InstanceSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Instance.Settings
+}
+
 // getDeviceByJID retrieves a device by its JID from the store container
 func (m *Manager) getDeviceByJID(ctx context.Context, jid string) (*store.Device, error) {
 	devices, err := m.container.GetAllDevices(ctx)
@@ -154,6 +277,19 @@ func (m *Manager) CreateClient(instance *entity.Instance) (*Client, error) {
 		return nil, fmt.Errorf("failed to create whatsmeow client: NewClient returned nil")
 	}
 
+	// Reconnections are handled by the manager with backoff
+	if m.config.WhatsApp.AutoReconnect {
+		waClient.EnableAutoReconnect = false
+	}
+
+	// Route the connection through the instance proxy before connecting. Never fall back to a
+	// direct connection, the proxy may be there to hide the address of this server.
+	if instance.ProxyURL != "" {
+		if err := waClient.SetProxyAddress(instance.ProxyURL); err != nil {
+			return nil, fmt.Errorf("failed to apply instance proxy: %w", err)
+		}
+	}
+
 	// Create event handler
 	handler := NewEventHandler(instance.ID, instance.Name, m.logger, m.dispatcher, m.messageRepo)
 	handler.SetWAClient(waClient)
@@ -164,6 +300,14 @@ func (m *Manager) CreateClient(instance *entity.Instance) (*Client, error) {
 		Device:   device,
 		Handler:  handler,
 	}
+	handler.SetSettingsProvider(client.Settings)
+	waClient.GetClientPayload = client.clientPayload
+	handler.SetChatRepositories(m.chatRepo, m.contactRepo)
+	handler.SetPollRepository(m.pollRepo)
+	handler.SetConnectionEventRepository(m.connEventRepo)
+	if m.mediaStorage != nil {
+		handler.SetMediaStorage(m.mediaStorage, m.presignedURLTTL())
+	}
 
 	// Register instance in dispatcher
 	if m.dispatcher != nil {
@@ -182,6 +326,8 @@ func (m *Manager) CreateClient(instance *entity.Instance) (*Client, error) {
 	})
 
 	handler.SetConnectedHandler(func(phone, name, pic string) {
+		m.cancelReconnect(client)
+
 		client.mu.Lock()
 		client.Connected = true
 		client.QRCode = ""
@@ -204,8 +350,8 @@ func (m *Manager) CreateClient(instance *entity.Instance) (*Client, error) {
 	handler.SetDisconnectHandler(func() {
 		client.mu.Lock()
 		client.Connected = false
-		client.Instance.SetDisconnected()
 		wasConnected := client.Instance.Status == entity.InstanceStatusConnected
+		client.Instance.SetDisconnected()
 		client.mu.Unlock()
 
 		m.persistInstanceState(client.Instance)
@@ -218,6 +364,7 @@ func (m *Manager) CreateClient(instance *entity.Instance) (*Client, error) {
 
 	// Register event handler
 	waClient.AddEventHandler(handler.Handle)
+	waClient.AddEventHandler(m.watchKeepAlive(client))
 
 	// Store client
 	m.clients[instance.ID] = client
@@ -269,6 +416,10 @@ func (m *Manager) Connect(ctx context.Context, instanceID uuid.UUID) error {
 		return nil
 	}
 
+	if err := m.acquireLease(ctx, instanceID); err != nil {
+		return err
+	}
+
 	m.logger.WithFields(logrus.Fields{
 		"instance":   client.Instance.Name,
 		"hasSession": hasSession,
@@ -378,12 +529,80 @@ func (m *Manager) handleQRChannel(client *Client, qrChan <-chan whatsmeow.QRChan
 	}
 }
 
+// PairPhone connects a client that has no session yet and requests a pairing code for the given
+// phone number, as an alternative to scanning the QR code
+func (m *Manager) PairPhone(ctx context.Context, instanceID uuid.UUID, phone string) (string, error) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return "", fmt.Errorf("client not found")
+	}
+	if m.IsLoggedIn(instanceID) {
+		return "", fmt.Errorf("instance is already paired")
+	}
+
+	if err := m.Connect(ctx, instanceID); err != nil {
+		return "", err
+	}
+
+	// Pairing codes can only be requested once the login websocket sent its first QR code
+	if err := m.waitForQRCode(ctx, client); err != nil {
+		return "", err
+	}
+
+	code, err := client.WAClient.PairPhone(ctx, phone, true, whatsmeow.PairClientChrome, pairClientDisplayName)
+	if err != nil {
+		return "", fmt.Errorf("failed to request pairing code: %w", err)
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"instance": client.Instance.Name,
+	}).Info("Pairing code generated")
+
+	if m.dispatcher != nil {
+		client.mu.RLock()
+		data := dto.QRCodeUpdateData{
+			QRCode:      client.QRCodeImage,
+			Code:        client.QRCode,
+			PairingCode: code,
+		}
+		client.mu.RUnlock()
+		m.dispatcher.Dispatch(client.Instance.ID, entity.WebhookEventQRCodeUpdated, data)
+	}
+
+	return code, nil
+}
+
+// waitForQRCode waits until the QR channel of a connecting client delivered a code
+func (m *Manager) waitForQRCode(ctx context.Context, client *Client) error {
+	ctx, cancel := context.WithTimeout(ctx, pairCodeWaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		client.mu.RLock()
+		ready := client.QRCode != ""
+		client.mu.RUnlock()
+		if ready {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for the login websocket")
+		case <-ticker.C:
+		}
+	}
+}
+
 // Disconnect disconnects a client from WhatsApp
 func (m *Manager) Disconnect(instanceID uuid.UUID) error {
 	client, exists := m.GetClient(instanceID)
 	if !exists {
 		return fmt.Errorf("client not found")
 	}
+	m.cancelReconnect(client)
 
 	client.mu.Lock()
 	defer client.mu.Unlock()
@@ -428,6 +647,7 @@ func (m *Manager) Disconnect(instanceID uuid.UUID) error {
 	client.Connected = false
 	client.Instance.SetDisconnected()
 	m.persistInstanceState(client.Instance)
+	m.dispatchConnectionUpdate(client, entity.InstanceStatusDisconnected, connectionReasonManual)
 
 	return nil
 }
@@ -438,6 +658,7 @@ func (m *Manager) Logout(instanceID uuid.UUID) error {
 	if !exists {
 		return fmt.Errorf("client not found")
 	}
+	m.cancelReconnect(client)
 
 	client.mu.Lock()
 	defer client.mu.Unlock()
@@ -498,6 +719,8 @@ func (m *Manager) Logout(instanceID uuid.UUID) error {
 	client.Instance.SetDisconnected()
 
 	m.persistInstanceState(client.Instance)
+	m.dispatchConnectionUpdate(client, connectionStatusLoggedOut, connectionReasonManual)
+	m.releaseLease(instanceID)
 
 	return nil
 }
@@ -511,6 +734,7 @@ func (m *Manager) DeleteClient(instanceID uuid.UUID) error {
 	if !exists {
 		return nil
 	}
+	m.cancelReconnect(client)
 
 	// Disconnect and cleanup with error handling
 	if client.WAClient != nil {
@@ -535,6 +759,7 @@ func (m *Manager) DeleteClient(instanceID uuid.UUID) error {
 	}
 
 	delete(m.clients, instanceID)
+	m.releaseLease(instanceID)
 	return nil
 }
 
@@ -581,6 +806,9 @@ func (m *Manager) DisconnectAll() {
 		client.mu.Unlock()
 	}
 
+	// Other nodes can take the instances over without waiting for the leases to expire
+	m.releaseAllLeases()
+
 	m.logger.Info("DisconnectAll: all clients disconnected")
 }
 
@@ -623,6 +851,22 @@ func (m *Manager) RestoreInstances(ctx context.Context) error {
 			continue
 		}
 
+		// Leave the instance to the node that owns it
+		if err := m.acquireLease(ctx, instance.ID); err != nil {
+			var notOwner *NotOwnerError
+			if errors.As(err, &notOwner) {
+				m.logger.WithFields(logrus.Fields{
+					"instance": instance.Name,
+					"owner":    notOwner.Lease.NodeID,
+				}).Info("Instance owned by another node, skipping reconnect")
+			} else {
+				m.logger.WithFields(logrus.Fields{
+					"instance": instance.Name,
+				}).WithError(err).Warn("Failed to acquire instance lease, skipping reconnect")
+			}
+			continue
+		}
+
 		// Try to reconnect if instance was previously connected
 		// Note: This will work if the device already has a session saved
 		// For new devices, they will need QR code scan
@@ -731,52 +975,6 @@ func (m *Manager) RestoreInstances(ctx context.Context) error {
 	return nil
 }
 
-// scheduleAutoReconnect schedules an automatic reconnection attempt after a delay
-func (m *Manager) scheduleAutoReconnect(instanceID uuid.UUID, instanceName string) {
-	reconnectDelay := time.Duration(m.config.WhatsApp.ReconnectInterval) * time.Second
-	m.logger.WithFields(logrus.Fields{
-		"instance": instanceName,
-		"delay":    reconnectDelay.String(),
-	}).Info("Scheduling auto-reconnect")
-	time.Sleep(reconnectDelay)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	client, exists := m.GetClient(instanceID)
-	if !exists {
-		m.logger.Warn("Client not found for auto-reconnect")
-		return
-	}
-
-	// Check if already connected
-	client.mu.RLock()
-	isConnected := client.WAClient != nil && client.WAClient.IsConnected()
-	hasSession := client.WAClient != nil && client.WAClient.Store.ID != nil
-	client.mu.RUnlock()
-
-	if isConnected {
-		m.logger.Info("Instance already connected, skipping auto-reconnect")
-		return
-	}
-
-	if !hasSession {
-		m.logger.Info("No valid session found, skipping auto-reconnect")
-		return
-	}
-
-	// Attempt to reconnect
-	if err := m.Connect(ctx, instanceID); err != nil {
-		// Schedule another attempt if enabled
-		if m.config.WhatsApp.AutoReconnect {
-			go m.scheduleAutoReconnect(instanceID, instanceName)
-		}
-		return
-	}
-
-	m.logger.Info("Auto-reconnect successful")
-}
-
 // GetQRCode returns the current QR code for an instance
 func (m *Manager) GetQRCode(instanceID uuid.UUID) (string, string, error) {
 	client, exists := m.GetClient(instanceID)
@@ -918,8 +1116,271 @@ func (m *Manager) SendText(ctx context.Context, instanceID uuid.UUID, to, text s
 	return resp.ID, nil
 }
 
+// EditMessage edits the text or caption of a message previously sent by the instance
+func (m *Manager) EditMessage(ctx context.Context, instanceID uuid.UUID, to, messageID, text string, msgType entity.MessageType) (string, error) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return "", fmt.Errorf("client not found")
+	}
+
+	jid, err := types.ParseJID(to)
+	if err != nil {
+		return "", fmt.Errorf("invalid JID: %w", err)
+	}
+
+	if client.WAClient == nil {
+		return "", fmt.Errorf("WhatsApp client is not initialized")
+	}
+
+	content, err := editedContent(msgType, text)
+	if err != nil {
+		return "", err
+	}
+	msg := client.WAClient.BuildEdit(jid, messageID, content)
+
+	resp, err := client.WAClient.SendMessage(ctx, jid, msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to edit message: %w", err)
+	}
+
+	var editor string
+	if client.WAClient.Store.ID != nil {
+		editor = client.WAClient.Store.ID.ToNonAD().String()
+	}
+	m.recordMessageEdit(instanceID, messageID, text, editor)
+
+	if m.dispatcher != nil {
+		m.dispatcher.Dispatch(instanceID, entity.WebhookEventMessagesUpdate, dto.MessageUpdateEvent{
+			MessageID: messageID,
+			Chat:      jid.String(),
+			Event:     "edit",
+			Content:   text,
+			Actor:     editor,
+			FromMe:    true,
+			Timestamp: resp.Timestamp,
+		})
+	}
+
+	return resp.ID, nil
+}
+
+// editedContent returns the new content of an edited message: the caption of image, video and
+// document messages, the text of every other message
+func editedContent(msgType entity.MessageType, text string) (*waE2E.Message, error) {
+	switch msgType {
+	case entity.MessageTypeImage:
+		return &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: proto.String(text)}}, nil
+	case entity.MessageTypeVideo:
+		return &waE2E.Message{VideoMessage: &waE2E.VideoMessage{Caption: proto.String(text)}}, nil
+	case entity.MessageTypeDocument:
+		return &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{Caption: proto.String(text)}}, nil
+	case entity.MessageTypeAudio, entity.MessageTypeSticker:
+		return nil, fmt.Errorf("%s messages have no text to edit", msgType)
+	}
+	return &waE2E.Message{Conversation: proto.String(text)}, nil
+}
+
+// recordMessageEdit stores the new content of an edited message and its edit history
+func (m *Manager) recordMessageEdit(instanceID uuid.UUID, messageID, text, editedBy string) {
+	if m.messageRepo == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		edit := entity.NewMessageEdit(instanceID, messageID, text, editedBy)
+		if err := m.messageRepo.RecordEdit(ctx, edit); err != nil {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"message_id": messageID,
+			}).Warn("Failed to record message edit")
+		}
+	}()
+}
+
+// RevokeMessage deletes a message for everyone in the chat.
+// Pass an empty sender to revoke our own message, or the original sender to revoke it as group admin.
+func (m *Manager) RevokeMessage(ctx context.Context, instanceID uuid.UUID, to, sender, messageID string) (string, error) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return "", fmt.Errorf("client not found")
+	}
+
+	jid, err := types.ParseJID(to)
+	if err != nil {
+		return "", fmt.Errorf("invalid JID: %w", err)
+	}
+
+	senderJID := types.EmptyJID
+	if sender != "" {
+		senderJID, err = types.ParseJID(sender)
+		if err != nil {
+			return "", fmt.Errorf("invalid sender JID: %w", err)
+		}
+	}
+
+	if client.WAClient == nil {
+		return "", fmt.Errorf("WhatsApp client is not initialized")
+	}
+
+	msg := client.WAClient.BuildRevoke(jid, senderJID, messageID)
+
+	resp, err := client.WAClient.SendMessage(ctx, jid, msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke message: %w", err)
+	}
+
+	var actor string
+	if client.WAClient.Store.ID != nil {
+		actor = client.WAClient.Store.ID.ToNonAD().String()
+	}
+	m.markMessageRevoked(instanceID, messageID, actor, resp.Timestamp)
+
+	if m.dispatcher != nil {
+		event := dto.MessageDeleteEvent{
+			MessageID:   messageID,
+			Chat:        jid.String(),
+			Actor:       actor,
+			ForEveryone: true,
+			Timestamp:   resp.Timestamp,
+		}
+		m.dispatcher.Dispatch(instanceID, entity.WebhookEventMessagesDelete, event)
+		m.dispatcher.Dispatch(instanceID, entity.WebhookEventMessageRevoked, event)
+	}
+
+	return resp.ID, nil
+}
+
+// DeleteMessageForMe deletes a stored message on all devices of the account without revoking it
+// for the other participants, then removes it from local storage
+func (m *Manager) DeleteMessageForMe(ctx context.Context, instanceID uuid.UUID, chat string, message *entity.Message) error {
+	if m.messageRepo == nil {
+		return fmt.Errorf("message repository is not configured")
+	}
+
+	client, jid, err := m.getChatClient(instanceID, chat)
+	if err != nil {
+		return err
+	}
+
+	if err := client.WAClient.SendAppState(ctx, buildDeleteForMe(jid, message)); err != nil {
+		return fmt.Errorf("failed to delete message for me: %w", err)
+	}
+
+	if err := m.messageRepo.DeleteByMessageID(ctx, instanceID, message.MessageID); err != nil {
+		return err
+	}
+
+	if m.dispatcher != nil {
+		m.dispatcher.Dispatch(instanceID, entity.WebhookEventMessagesDelete, dto.MessageDeleteEvent{
+			MessageID:   message.MessageID,
+			Chat:        jid.String(),
+			ForEveryone: false,
+			Timestamp:   time.Now(),
+		})
+	}
+
+	return nil
+}
+
+// buildDeleteForMe builds the app state patch deleting a message for the account. The sender is
+// only set for messages sent by others in groups, as the other devices of the account expect.
+func buildDeleteForMe(chat types.JID, message *entity.Message) appstate.PatchInfo {
+	fromMe, sender := "0", "0"
+	if message.FromMe {
+		fromMe = "1"
+	} else if chat.Server == types.GroupServer && message.SenderJID != "" {
+		sender = message.SenderJID
+	}
+
+	return appstate.PatchInfo{
+		Type: appstate.WAPatchRegularHigh,
+		Mutations: []appstate.MutationInfo{{
+			Index:   []string{appstate.IndexDeleteMessageForMe, chat.String(), message.MessageID, fromMe, sender},
+			Version: 3,
+			Value: &waSyncAction.SyncActionValue{
+				DeleteMessageForMeAction: &waSyncAction.DeleteMessageForMeAction{
+					DeleteMedia:      proto.Bool(true),
+					MessageTimestamp: proto.Int64(message.Timestamp.Unix()),
+				},
+			},
+		}},
+	}
+}
+
+// markMessageRevoked flags a stored message as deleted for everyone
+func (m *Manager) markMessageRevoked(instanceID uuid.UUID, messageID, revokedBy string, revokedAt time.Time) {
+	if m.messageRepo == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := m.messageRepo.MarkRevoked(ctx, instanceID, messageID, revokedBy, revokedAt); err != nil {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"message_id": messageID,
+			}).Warn("Failed to mark message as revoked")
+		}
+	}()
+}
+
+// IsOwnJID returns true if the given JID belongs to the instance account
+func (m *Manager) IsOwnJID(instanceID uuid.UUID, jid string) bool {
+	client, exists := m.GetClient(instanceID)
+	if !exists || client.WAClient == nil || client.WAClient.Store.ID == nil {
+		return false
+	}
+
+	parsed, err := types.ParseJID(jid)
+	if err != nil {
+		return false
+	}
+
+	if parsed.User == client.WAClient.Store.ID.User {
+		return true
+	}
+	lid := client.WAClient.Store.GetLID()
+	return !lid.IsEmpty() && parsed.User == lid.User
+}
+
+// IsGroupAdmin checks whether the instance account is an admin of the given group
+func (m *Manager) IsGroupAdmin(ctx context.Context, instanceID uuid.UUID, group string) (bool, error) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return false, fmt.Errorf("client not found")
+	}
+
+	jid, err := types.ParseJID(group)
+	if err != nil {
+		return false, fmt.Errorf("invalid JID: %w", err)
+	}
+
+	if client.WAClient == nil || client.WAClient.Store.ID == nil {
+		return false, fmt.Errorf("WhatsApp client is not initialized")
+	}
+
+	info, err := client.WAClient.GetGroupInfo(ctx, jid)
+	if err != nil {
+		return false, fmt.Errorf("failed to get group info: %w", err)
+	}
+
+	ownPN := client.WAClient.Store.ID.ToNonAD()
+	ownLID := client.WAClient.Store.GetLID().ToNonAD()
+	for _, participant := range info.Participants {
+		if participant.JID.User == ownPN.User || participant.PhoneNumber.User == ownPN.User ||
+			(!ownLID.IsEmpty() && (participant.JID.User == ownLID.User || participant.LID.User == ownLID.User)) {
+			return participant.IsAdmin || participant.IsSuperAdmin, nil
+		}
+	}
+
+	return false, nil
+}
+
 // SendImage sends an image message
-func (m *Manager) SendImage(ctx context.Context, instanceID uuid.UUID, to string, imageData []byte, mimeType, caption string, quotedID string) (string, error) {
+func (m *Manager) SendImage(ctx context.Context, instanceID uuid.UUID, to string, media Media, mimeType, caption string, quotedID string) (string, error) {
 	client, exists := m.GetClient(instanceID)
 	if !exists {
 		return "", fmt.Errorf("client not found")
@@ -931,7 +1392,7 @@ func (m *Manager) SendImage(ctx context.Context, instanceID uuid.UUID, to string
 	}
 
 	// Upload image
-	uploaded, err := client.WAClient.Upload(ctx, imageData, whatsmeow.MediaImage)
+	uploaded, err := uploadMedia(ctx, client.WAClient, media, whatsmeow.MediaImage)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload image: %w", err)
 	}
@@ -945,7 +1406,7 @@ func (m *Manager) SendImage(ctx context.Context, instanceID uuid.UUID, to string
 			MediaKey:      uploaded.MediaKey,
 			FileEncSHA256: uploaded.FileEncSHA256,
 			FileSHA256:    uploaded.FileSHA256,
-			FileLength:    proto.Uint64(uint64(len(imageData))),
+			FileLength:    proto.Uint64(uploaded.FileLength),
 		},
 	}
 
@@ -965,13 +1426,14 @@ func (m *Manager) SendImage(ctx context.Context, instanceID uuid.UUID, to string
 		return "", fmt.Errorf("failed to send image: %w", err)
 	}
 
+	m.saveSentMessage(instanceID, jid, entity.MessageTypeImage, resp.ID, msg)
 	m.emitMessageSent(instanceID, jid, "image", resp.ID, "", caption, "")
 
 	return resp.ID, nil
 }
 
 // SendVideo sends a video message
-func (m *Manager) SendVideo(ctx context.Context, instanceID uuid.UUID, to string, videoData []byte, mimeType, caption string, quotedID string) (string, error) {
+func (m *Manager) SendVideo(ctx context.Context, instanceID uuid.UUID, to string, media Media, mimeType, caption string, quotedID string) (string, error) {
 	client, exists := m.GetClient(instanceID)
 	if !exists {
 		return "", fmt.Errorf("client not found")
@@ -983,7 +1445,7 @@ func (m *Manager) SendVideo(ctx context.Context, instanceID uuid.UUID, to string
 	}
 
 	// Upload video
-	uploaded, err := client.WAClient.Upload(ctx, videoData, whatsmeow.MediaVideo)
+	uploaded, err := uploadMedia(ctx, client.WAClient, media, whatsmeow.MediaVideo)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload video: %w", err)
 	}
@@ -997,7 +1459,7 @@ func (m *Manager) SendVideo(ctx context.Context, instanceID uuid.UUID, to string
 			MediaKey:      uploaded.MediaKey,
 			FileEncSHA256: uploaded.FileEncSHA256,
 			FileSHA256:    uploaded.FileSHA256,
-			FileLength:    proto.Uint64(uint64(len(videoData))),
+			FileLength:    proto.Uint64(uploaded.FileLength),
 		},
 	}
 
@@ -1017,13 +1479,14 @@ func (m *Manager) SendVideo(ctx context.Context, instanceID uuid.UUID, to string
 		return "", fmt.Errorf("failed to send video: %w", err)
 	}
 
+	m.saveSentMessage(instanceID, jid, entity.MessageTypeVideo, resp.ID, msg)
 	m.emitMessageSent(instanceID, jid, "video", resp.ID, "", caption, "")
 
 	return resp.ID, nil
 }
 
 // SendAudio sends an audio message
-func (m *Manager) SendAudio(ctx context.Context, instanceID uuid.UUID, to string, audioData []byte, mimeType string, ptt bool, quotedID string) (string, error) {
+func (m *Manager) SendAudio(ctx context.Context, instanceID uuid.UUID, to string, media Media, mimeType string, ptt bool, quotedID string) (string, error) {
 	client, exists := m.GetClient(instanceID)
 	if !exists {
 		return "", fmt.Errorf("client not found")
@@ -1035,7 +1498,7 @@ func (m *Manager) SendAudio(ctx context.Context, instanceID uuid.UUID, to string
 	}
 
 	// Upload audio
-	uploaded, err := client.WAClient.Upload(ctx, audioData, whatsmeow.MediaAudio)
+	uploaded, err := uploadMedia(ctx, client.WAClient, media, whatsmeow.MediaAudio)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload audio: %w", err)
 	}
@@ -1048,7 +1511,7 @@ func (m *Manager) SendAudio(ctx context.Context, instanceID uuid.UUID, to string
 			MediaKey:      uploaded.MediaKey,
 			FileEncSHA256: uploaded.FileEncSHA256,
 			FileSHA256:    uploaded.FileSHA256,
-			FileLength:    proto.Uint64(uint64(len(audioData))),
+			FileLength:    proto.Uint64(uploaded.FileLength),
 			PTT:           proto.Bool(ptt),
 		},
 	}
@@ -1069,13 +1532,14 @@ func (m *Manager) SendAudio(ctx context.Context, instanceID uuid.UUID, to string
 		return "", fmt.Errorf("failed to send audio: %w", err)
 	}
 
+	m.saveSentMessage(instanceID, jid, entity.MessageTypeAudio, resp.ID, msg)
 	m.emitMessageSent(instanceID, jid, "audio", resp.ID, "", "", "")
 
 	return resp.ID, nil
 }
 
 // SendDocument sends a document message
-func (m *Manager) SendDocument(ctx context.Context, instanceID uuid.UUID, to string, docData []byte, mimeType, fileName, caption string, quotedID string) (string, error) {
+func (m *Manager) SendDocument(ctx context.Context, instanceID uuid.UUID, to string, media Media, mimeType, fileName, caption string, quotedID string) (string, error) {
 	client, exists := m.GetClient(instanceID)
 	if !exists {
 		return "", fmt.Errorf("client not found")
@@ -1087,7 +1551,7 @@ func (m *Manager) SendDocument(ctx context.Context, instanceID uuid.UUID, to str
 	}
 
 	// Upload document
-	uploaded, err := client.WAClient.Upload(ctx, docData, whatsmeow.MediaDocument)
+	uploaded, err := uploadMedia(ctx, client.WAClient, media, whatsmeow.MediaDocument)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload document: %w", err)
 	}
@@ -1102,7 +1566,7 @@ func (m *Manager) SendDocument(ctx context.Context, instanceID uuid.UUID, to str
 			MediaKey:      uploaded.MediaKey,
 			FileEncSHA256: uploaded.FileEncSHA256,
 			FileSHA256:    uploaded.FileSHA256,
-			FileLength:    proto.Uint64(uint64(len(docData))),
+			FileLength:    proto.Uint64(uploaded.FileLength),
 		},
 	}
 
@@ -1122,13 +1586,14 @@ func (m *Manager) SendDocument(ctx context.Context, instanceID uuid.UUID, to str
 		return "", fmt.Errorf("failed to send document: %w", err)
 	}
 
+	m.saveSentMessage(instanceID, jid, entity.MessageTypeDocument, resp.ID, msg)
 	m.emitMessageSent(instanceID, jid, "document", resp.ID, "", caption, fileName)
 
 	return resp.ID, nil
 }
 
 // SendSticker sends a sticker message
-func (m *Manager) SendSticker(ctx context.Context, instanceID uuid.UUID, to string, stickerData []byte, mimeType string) (string, error) {
+func (m *Manager) SendSticker(ctx context.Context, instanceID uuid.UUID, to string, media Media, mimeType string) (string, error) {
 	client, exists := m.GetClient(instanceID)
 	if !exists {
 		return "", fmt.Errorf("client not found")
@@ -1140,7 +1605,7 @@ func (m *Manager) SendSticker(ctx context.Context, instanceID uuid.UUID, to stri
 	}
 
 	// Upload sticker
-	uploaded, err := client.WAClient.Upload(ctx, stickerData, whatsmeow.MediaImage)
+	uploaded, err := uploadMedia(ctx, client.WAClient, media, whatsmeow.MediaImage)
 	if err != nil {
 		return "", fmt.Errorf("failed to upload sticker: %w", err)
 	}
@@ -1153,7 +1618,7 @@ func (m *Manager) SendSticker(ctx context.Context, instanceID uuid.UUID, to stri
 			MediaKey:      uploaded.MediaKey,
 			FileEncSHA256: uploaded.FileEncSHA256,
 			FileSHA256:    uploaded.FileSHA256,
-			FileLength:    proto.Uint64(uint64(len(stickerData))),
+			FileLength:    proto.Uint64(uploaded.FileLength),
 		},
 	}
 
@@ -1166,6 +1631,7 @@ func (m *Manager) SendSticker(ctx context.Context, instanceID uuid.UUID, to stri
 		return "", fmt.Errorf("failed to send sticker: %w", err)
 	}
 
+	m.saveSentMessage(instanceID, jid, entity.MessageTypeSticker, resp.ID, msg)
 	m.emitMessageSent(instanceID, jid, "sticker", resp.ID, "", "", "")
 
 	return resp.ID, nil
@@ -1310,31 +1776,24 @@ func (m *Manager) SendPoll(ctx context.Context, instanceID uuid.UUID, to, questi
 		selectableCount = 1
 	}
 
-	// Build poll options
-	pollOptions := make([]*waE2E.PollCreationMessage_Option, len(options))
-	for i, opt := range options {
-		pollOptions[i] = &waE2E.PollCreationMessage_Option{
-			OptionName: proto.String(opt),
-		}
-	}
-
-	msg := &waE2E.Message{
-		PollCreationMessage: &waE2E.PollCreationMessage{
-			Name:                   proto.String(question),
-			Options:                pollOptions,
-			SelectableOptionsCount: proto.Uint32(uint32(selectableCount)),
-		},
-	}
-
 	if client.WAClient == nil {
 		return "", fmt.Errorf("WhatsApp client is not initialized")
 	}
 
+	// BuildPollCreation attaches the message secret votes are encrypted with
+	msg := client.WAClient.BuildPollCreation(question, options, selectableCount)
+
 	resp, err := client.WAClient.SendMessage(ctx, jid, msg)
 	if err != nil {
 		return "", fmt.Errorf("failed to send poll: %w", err)
 	}
 
+	var creator string
+	if client.WAClient.Store.ID != nil {
+		creator = client.WAClient.Store.ID.ToNonAD().String()
+	}
+	m.savePoll(instanceID, resp.ID, jid, msg.GetPollCreationMessage(), creator)
+
 	m.emitMessageSent(instanceID, jid, "poll", resp.ID, question, "", "")
 
 	return resp.ID, nil
@@ -2083,7 +2542,17 @@ func (m *Manager) SendList(ctx context.Context, instanceID uuid.UUID, to, title,
 }
 
 func (m *Manager) emitMessageSent(instanceID uuid.UUID, to types.JID, messageType, messageID string, content, caption, fileName string) {
-	if m.dispatcher == nil || messageID == "" {
+	if messageID == "" {
+		return
+	}
+
+	preview := content
+	if preview == "" {
+		preview = caption
+	}
+	m.recordSentChatMessage(instanceID, to, messageType, messageID, preview)
+
+	if m.dispatcher == nil {
 		return
 	}
 
@@ -0,0 +1,147 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/application/dto"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// connectionStatusLoggedOut is reported when the session was removed from the phone
+const connectionStatusLoggedOut entity.InstanceStatus = "logged_out"
+
+// Reasons recorded with connection events triggered by the API or the watchdog
+const (
+	connectionReasonManual           = "manual"
+	connectionReasonSocketLost       = "socket lost"
+	connectionReasonKeepAliveTimeout = "keepalive timeout"
+)
+
+// saveConnectionEvent stores a connection state transition in the background
+func saveConnectionEvent(repo repository.ConnectionEventRepository, logger *logrus.Logger, instanceID uuid.UUID, status entity.InstanceStatus, reason string) {
+	if repo == nil {
+		return
+	}
+
+	event := entity.NewConnectionEvent(instanceID, status, reason)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := repo.Create(ctx, event); err != nil {
+			logger.WithError(err).Warn("Failed to save connection event")
+		}
+	}()
+}
+
+// recordConnectionEvent stores a connection state transition of the instance
+func (h *EventHandler) recordConnectionEvent(status entity.InstanceStatus, reason string) {
+	saveConnectionEvent(h.connEventRepo, h.logger, h.instanceID, status, reason)
+}
+
+// dispatchConnectionUpdate records a connection state transition and sends it as a connection.update event
+func (m *Manager) dispatchConnectionUpdate(client *Client, status entity.InstanceStatus, reason string) {
+	saveConnectionEvent(m.connEventRepo, m.logger, client.Instance.ID, status, reason)
+
+	if m.dispatcher == nil {
+		return
+	}
+	m.dispatcher.Dispatch(client.Instance.ID, entity.WebhookEventConnectionUpdate, dto.ConnectionUpdateData{
+		Status: string(status),
+		Reason: reason,
+	})
+}
+
+// watchKeepAlive returns an event handler tracking since when the keepalive pings of a client fail
+func (m *Manager) watchKeepAlive(client *Client) func(interface{}) {
+	return func(evt interface{}) {
+		switch v := evt.(type) {
+		case *events.KeepAliveTimeout:
+			client.mu.Lock()
+			if client.keepAliveFailingSince.IsZero() {
+				client.keepAliveFailingSince = v.LastSuccess
+			}
+			client.mu.Unlock()
+		case *events.KeepAliveRestored, *events.Connected, *events.Disconnected:
+			client.mu.Lock()
+			client.keepAliveFailingSince = time.Time{}
+			client.mu.Unlock()
+		}
+	}
+}
+
+// StartWatchdog periodically checks that connected instances still have a live socket and forces
+// a reconnect of the ones that don't. It stops when the context is cancelled.
+func (m *Manager) StartWatchdog(ctx context.Context) {
+	interval := time.Duration(m.config.WhatsApp.WatchdogInterval) * time.Second
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.checkConnections()
+			}
+		}
+	}()
+}
+
+// checkConnections forces a reconnect of the instances marked as connected whose socket is dead
+func (m *Manager) checkConnections() {
+	m.mu.RLock()
+	clients := make([]*Client, 0, len(m.clients))
+	for _, client := range m.clients {
+		clients = append(clients, client)
+	}
+	m.mu.RUnlock()
+
+	timeout := time.Duration(m.config.WhatsApp.KeepAliveTimeout) * time.Second
+	for _, client := range clients {
+		client.mu.RLock()
+		connected := client.Connected && client.WAClient != nil
+		socketAlive := connected && client.WAClient.IsConnected()
+		failingSince := client.keepAliveFailingSince
+		client.mu.RUnlock()
+
+		switch {
+		case !connected:
+			continue
+		case !socketAlive:
+			m.forceReconnect(client, connectionReasonSocketLost)
+		case !failingSince.IsZero() && time.Since(failingSince) > timeout:
+			m.forceReconnect(client, connectionReasonKeepAliveTimeout)
+		}
+	}
+}
+
+// forceReconnect drops the socket of a half-dead client and starts the reconnection loop
+func (m *Manager) forceReconnect(client *Client, reason string) {
+	m.logger.WithFields(logrus.Fields{
+		"instance": client.Instance.Name,
+		"reason":   reason,
+	}).Warn("Watchdog detected a dead connection, forcing reconnect")
+
+	client.WAClient.Disconnect()
+
+	client.mu.Lock()
+	client.Connected = false
+	client.keepAliveFailingSince = time.Time{}
+	client.Instance.SetDisconnected()
+	client.mu.Unlock()
+
+	m.persistInstanceState(client.Instance)
+	m.dispatchConnectionUpdate(client, entity.InstanceStatusDisconnected, reason)
+	m.scheduleAutoReconnect(client.Instance.ID, client.Instance.Name)
+}
@@ -3,30 +3,40 @@ package whatsapp
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jonadableite/turbozap-api/internal/application/dto"
 	"github.com/jonadableite/turbozap-api/internal/domain/entity"
 	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+	"github.com/jonadableite/turbozap-api/internal/infrastructure/storage"
+	"github.com/sirupsen/logrus"
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
-	"github.com/sirupsen/logrus"
 )
 
 // EventHandler handles WhatsApp events for a client
 type EventHandler struct {
-	instanceID   uuid.UUID
-	instanceName string
-	logger       *logrus.Logger
-	dispatcher   WebhookDispatcher
-	messageRepo  repository.MessageRepository
-	waClient     *whatsmeow.Client
-	onQRCode     func(string)
-	onConnected  func(string, string, string)
-	onDisconnect func()
+	instanceID    uuid.UUID
+	instanceName  string
+	logger        *logrus.Logger
+	dispatcher    WebhookDispatcher
+	messageRepo   repository.MessageRepository
+	chatRepo      repository.ChatRepository
+	contactRepo   repository.ContactRepository
+	pollRepo      repository.PollRepository
+	connEventRepo repository.ConnectionEventRepository
+	waClient      *whatsmeow.Client
+	mediaStorage  *storage.Client
+	mediaURLTTL   time.Duration
+	settings      func() entity.InstanceSettings
+	calls         sync.Map // call ID -> last known call status
+	onQRCode      func(string)
+	onConnected   func(string, string, string)
+	onDisconnect  func()
 }
 
 // WebhookDispatcher interface for dispatching webhook events
@@ -66,6 +76,41 @@ func (h *EventHandler) SetWAClient(client *whatsmeow.Client) {
 	h.waClient = client
 }
 
+// SetMediaStorage sets the object storage used to keep incoming media
+func (h *EventHandler) SetMediaStorage(mediaStorage *storage.Client, presignedURLTTL time.Duration) {
+	h.mediaStorage = mediaStorage
+	h.mediaURLTTL = presignedURLTTL
+}
+
+// SetChatRepositories sets the repositories used to persist chats and contacts
+func (h *EventHandler) SetChatRepositories(chatRepo repository.ChatRepository, contactRepo repository.ContactRepository) {
+	h.chatRepo = chatRepo
+	h.contactRepo = contactRepo
+}
+
+// SetPollRepository sets the repository used to persist polls and their votes
+func (h *EventHandler) SetPollRepository(pollRepo repository.PollRepository) {
+	h.pollRepo = pollRepo
+}
+
+// SetConnectionEventRepository sets the repository used to keep the connection history
+func (h *EventHandler) SetConnectionEventRepository(connEventRepo repository.ConnectionEventRepository) {
+	h.connEventRepo = connEventRepo
+}
+
+// SetSettingsProvider sets the callback returning the current instance settings
+func (h *EventHandler) SetSettingsProvider(provider func() entity.InstanceSettings) {
+	h.settings = provider
+}
+
+// currentSettings returns the current instance settings
+func (h *EventHandler) currentSettings() entity.InstanceSettings {
+	if h.settings == nil {
+		return entity.InstanceSettings{}
+	}
+	return h.settings()
+}
+
 // Handle processes WhatsApp events
 func (h *EventHandler) Handle(evt interface{}) {
 	defer func() {
@@ -95,6 +140,22 @@ func (h *EventHandler) Handle(evt interface{}) {
 		h.handlePresence(v)
 	case *events.GroupInfo:
 		h.handleGroupInfo(v)
+	case *events.Archive:
+		h.handleArchive(v)
+	case *events.Pin:
+		h.handlePin(v)
+	case *events.Mute:
+		h.handleMute(v)
+	case *events.MarkChatAsRead:
+		h.handleMarkChatAsRead(v)
+	case *events.CallOffer:
+		h.handleCallOffer(v)
+	case *events.CallOfferNotice:
+		h.handleCallOfferNotice(v)
+	case *events.CallAccept:
+		h.handleCallAccept(v)
+	case *events.CallTerminate:
+		h.handleCallTerminate(v)
 	case *events.JoinedGroup:
 		h.handleJoinedGroup(v)
 	case *events.HistorySync:
@@ -159,7 +220,10 @@ func (h *EventHandler) handleConnected(evt *events.Connected) {
 		h.onConnected(phone, name, pic)
 	}
 
+	h.sendSettingsPresence()
+
 	// Dispatch webhook
+	h.recordConnectionEvent(entity.InstanceStatusConnected, "")
 	h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventConnectionUpdate, dto.ConnectionUpdateData{
 		Status: "connected",
 	})
@@ -198,12 +262,13 @@ func (h *EventHandler) handleDisconnected(evt *events.Disconnected) {
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
-					h.logger.WithFields(logrus.Fields{
-						"instance": h.instanceName,
-						"panic":    r,
-					}).Warn("handleDisconnected: recovered from panic in webhook dispatch")
+				h.logger.WithFields(logrus.Fields{
+					"instance": h.instanceName,
+					"panic":    r,
+				}).Warn("handleDisconnected: recovered from panic in webhook dispatch")
 			}
 		}()
+		h.recordConnectionEvent(entity.InstanceStatusDisconnected, "")
 		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventConnectionUpdate, dto.ConnectionUpdateData{
 			Status: "disconnected",
 		})
@@ -244,14 +309,16 @@ func (h *EventHandler) handleLoggedOut(evt *events.LoggedOut) {
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
-					h.logger.WithFields(logrus.Fields{
-						"instance": h.instanceName,
-						"panic":    r,
-					}).Warn("handleLoggedOut: recovered from panic in webhook dispatch")
+				h.logger.WithFields(logrus.Fields{
+					"instance": h.instanceName,
+					"panic":    r,
+				}).Warn("handleLoggedOut: recovered from panic in webhook dispatch")
 			}
 		}()
+		h.recordConnectionEvent(connectionStatusLoggedOut, evt.Reason.String())
 		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventConnectionUpdate, dto.ConnectionUpdateData{
 			Status: "logged_out",
+			Reason: evt.Reason.String(),
 		})
 	}()
 }
@@ -267,13 +334,57 @@ func (h *EventHandler) handleMessage(evt *events.Message) {
 		return
 	}
 
+	if h.ignoresMessage(evt) {
+		return
+	}
+
 	if h.handleProtocolMessage(evt, msg.ProtocolMessage) {
 		return
 	}
 
+	if msg.PollUpdateMessage != nil {
+		h.handlePollVote(evt)
+		return
+	}
+
 	h.handleInteractiveResponses(evt, msg)
+	if h.shouldAutoRead(evt) {
+		h.markMessageRead(evt)
+	}
 
-	// Build message event data
+	msgEvent := newMessageReceivedEvent(evt)
+	if poll := pollCreationMessage(msg); poll != nil {
+		h.savePoll(evt, poll)
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"instance": h.instanceName,
+		"id":       msgEvent.MessageID,
+		"type":     msgEvent.Type,
+		"from":     msgEvent.From,
+	}).Debug("Message received")
+
+	// Media is downloaded off the event loop, so the webhook waits for the upload
+	if h.shouldStoreMedia(msg) {
+		go func() {
+			storedURL := h.storeIncomingMedia(msg, &msgEvent)
+			h.saveMessage(msgEvent, msg, storedURL)
+			h.recordChatMessage(msgEvent)
+			h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessageReceived, msgEvent)
+		}()
+		return
+	}
+
+	h.saveMessage(msgEvent, msg, "")
+	h.recordChatMessage(msgEvent)
+
+	// Dispatch webhook
+	h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessageReceived, msgEvent)
+}
+
+// newMessageReceivedEvent builds the webhook payload of a received message
+func newMessageReceivedEvent(evt *events.Message) dto.MessageReceivedEvent {
+	msg := evt.Message
 	msgEvent := dto.MessageReceivedEvent{
 		MessageID: evt.Info.ID,
 		From:      evt.Info.Sender.String(),
@@ -313,6 +424,7 @@ func (h *EventHandler) handleMessage(evt *events.Message) {
 		msgEvent.Type = "document"
 		msgEvent.Caption = msg.DocumentMessage.GetCaption()
 		msgEvent.MediaMimeType = msg.DocumentMessage.GetMimetype()
+		msgEvent.FileName = msg.DocumentMessage.GetFileName()
 	case msg.StickerMessage != nil:
 		msgEvent.Type = "sticker"
 		msgEvent.MediaMimeType = msg.StickerMessage.GetMimetype()
@@ -323,9 +435,9 @@ func (h *EventHandler) handleMessage(evt *events.Message) {
 	case msg.ReactionMessage != nil:
 		msgEvent.Type = "reaction"
 		msgEvent.Content = msg.ReactionMessage.GetText()
-	case msg.PollCreationMessage != nil:
+	case pollCreationMessage(msg) != nil:
 		msgEvent.Type = "poll"
-		msgEvent.Content = msg.PollCreationMessage.GetName()
+		msgEvent.Content = pollCreationMessage(msg).GetName()
 	case msg.ButtonsMessage != nil:
 		msgEvent.Type = "button"
 	case msg.ListMessage != nil:
@@ -334,42 +446,46 @@ func (h *EventHandler) handleMessage(evt *events.Message) {
 		msgEvent.Type = "unknown"
 	}
 
-	h.logger.WithFields(logrus.Fields{
-		"instance": h.instanceName,
-		"id":       msgEvent.MessageID,
-		"type":    msgEvent.Type,
-		"from":    msgEvent.From,
-	}).Debug("Message received")
-
-	// Save message to database
-	if h.messageRepo != nil {
-		go func() {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
+	return msgEvent
+}
 
-			message := entity.NewMessage(
-				h.instanceID,
-				msgEvent.To,
-				entity.MessageType(msgEvent.Type),
-			)
-			message.MessageID = msgEvent.MessageID
-			message.RemoteJID = msgEvent.From
-			message.FromMe = msgEvent.FromMe
-			message.Content = msgEvent.Content
-			message.MediaCaption = msgEvent.Caption
-			message.MediaMimeType = msgEvent.MediaMimeType
-			message.QuotedMsgID = msgEvent.QuotedMsgID
-			message.Timestamp = msgEvent.Timestamp
-			message.Status = entity.MessageStatusSent
-
-			if err := h.messageRepo.Create(ctx, message); err != nil {
-				h.logger.WithError(err).Warn("Failed to save message to database")
-			}
-		}()
+// saveMessage persists a received message to the database
+func (h *EventHandler) saveMessage(msgEvent dto.MessageReceivedEvent, msg *waE2E.Message, mediaURL string) {
+	if h.messageRepo == nil {
+		return
 	}
 
-	// Dispatch webhook
-	h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessageReceived, msgEvent)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		message := h.newMessageEntity(msgEvent, msg, mediaURL)
+		if err := h.messageRepo.Create(ctx, message); err != nil {
+			h.logger.WithError(err).Warn("Failed to save message to database")
+		}
+	}()
+}
+
+// newMessageEntity builds the stored representation of a received message
+func (h *EventHandler) newMessageEntity(msgEvent dto.MessageReceivedEvent, msg *waE2E.Message, mediaURL string) *entity.Message {
+	message := entity.NewMessage(
+		h.instanceID,
+		msgEvent.To,
+		entity.MessageType(msgEvent.Type),
+	)
+	message.MessageID = msgEvent.MessageID
+	message.SenderJID = msgEvent.From
+	message.FromMe = msgEvent.FromMe
+	message.Content = msgEvent.Content
+	message.MediaURL = mediaURL
+	message.MediaCaption = msgEvent.Caption
+	message.MediaMimeType = msgEvent.MediaMimeType
+	message.MediaFileName = msgEvent.FileName
+	message.MediaKeys = mediaKeysFromMessage(msg)
+	message.QuotedMsgID = msgEvent.QuotedMsgID
+	message.Timestamp = msgEvent.Timestamp
+	message.Status = entity.MessageStatusSent
+	return message
 }
 
 func (h *EventHandler) handleReceipt(evt *events.Receipt) {
@@ -394,6 +510,35 @@ func (h *EventHandler) handleReceipt(evt *events.Receipt) {
 		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessagesUpdate, eventData)
 		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessageAck, eventData)
 	}
+
+	h.recordReceipt(evt, entity.MessageStatus(status))
+	h.recordChatRead(evt)
+}
+
+// recordReceipt updates the stored status of the messages acknowledged by a receipt.
+// Receipts sent by our own devices only mean we read the chat, so they are ignored.
+func (h *EventHandler) recordReceipt(evt *events.Receipt, status entity.MessageStatus) {
+	if h.messageRepo == nil || evt.IsFromMe || len(evt.MessageIDs) == 0 {
+		return
+	}
+
+	switch status {
+	case entity.MessageStatusDelivered, entity.MessageStatusRead, entity.MessageStatusPlayed:
+	default:
+		return
+	}
+
+	messageIDs := make([]string, len(evt.MessageIDs))
+	copy(messageIDs, evt.MessageIDs)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := h.messageRepo.ApplyReceipt(ctx, h.instanceID, messageIDs, status, evt.Timestamp); err != nil {
+			h.logger.WithError(err).Warn("Failed to update message status from receipt")
+		}
+	}()
 }
 
 func (h *EventHandler) handlePresence(evt *events.Presence) {
@@ -430,6 +575,7 @@ func (h *EventHandler) handleGroupInfo(evt *events.GroupInfo) {
 	}
 	if evt.Name != nil {
 		metadata.Subject = evt.Name.Name
+		h.recordChatName(evt.JID.String(), evt.Name.Name)
 	}
 	if evt.Topic != nil {
 		metadata.Topic = evt.Topic.Topic
@@ -499,6 +645,8 @@ func (h *EventHandler) handleHistorySync(evt *events.HistorySync) {
 			SyncType: syncType,
 		})
 	}
+
+	h.persistHistorySync(evt.Data)
 }
 
 func (h *EventHandler) handlePushName(evt *events.PushName) {
@@ -513,6 +661,20 @@ func (h *EventHandler) handlePushName(evt *events.PushName) {
 		PushName: evt.NewPushName,
 		Event:    "push_name",
 	})
+
+	if h.contactRepo != nil {
+		contact := entity.NewContact(h.instanceID, evt.JID.ToNonAD().String(), evt.JID.User)
+		contact.PushName = evt.NewPushName
+
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := h.contactRepo.Upsert(ctx, contact); err != nil {
+				h.logger.WithError(err).Warn("Failed to save contact push name")
+			}
+		}()
+	}
 }
 
 // extractTextFromMessage extracts text content from a message
@@ -585,17 +747,29 @@ func (h *EventHandler) handleProtocolMessage(evt *events.Message, protocolMsg *w
 
 	switch protocolMsg.GetType() {
 	case waE2E.ProtocolMessage_REVOKE:
-		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessagesDelete, dto.MessageDeleteEvent{
-			MessageID: key.GetID(),
-			Chat:      chatJID,
-			Actor:     evt.Info.Sender.String(),
-			Timestamp: evt.Info.Timestamp,
-		})
+		h.recordRevoke(key.GetID(), evt.Info.Sender.ToNonAD().String(), evt.Info.Timestamp)
+
+		event := dto.MessageDeleteEvent{
+			MessageID:   key.GetID(),
+			Chat:        chatJID,
+			Actor:       evt.Info.Sender.String(),
+			ForEveryone: true,
+			Timestamp:   evt.Info.Timestamp,
+		}
+		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessagesDelete, event)
+		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessageRevoked, event)
 		return true
 	case waE2E.ProtocolMessage_MESSAGE_EDIT:
+		content := extractTextFromMessage(protocolMsg.GetEditedMessage())
+		h.recordEdit(key.GetID(), content, evt.Info.Sender.ToNonAD().String())
+
 		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventMessagesUpdate, dto.MessageUpdateEvent{
 			MessageID: key.GetID(),
 			Chat:      chatJID,
+			Event:     "edit",
+			Content:   content,
+			Actor:     evt.Info.Sender.String(),
+			FromMe:    evt.Info.IsFromMe,
 			Timestamp: evt.Info.Timestamp,
 		})
 		return true
@@ -604,6 +778,39 @@ func (h *EventHandler) handleProtocolMessage(evt *events.Message, protocolMsg *w
 	}
 }
 
+// recordEdit stores the new content of an edited message and its edit history
+func (h *EventHandler) recordEdit(messageID, content, editedBy string) {
+	if h.messageRepo == nil || messageID == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		edit := entity.NewMessageEdit(h.instanceID, messageID, content, editedBy)
+		if err := h.messageRepo.RecordEdit(ctx, edit); err != nil {
+			h.logger.WithError(err).Warn("Failed to record message edit")
+		}
+	}()
+}
+
+// recordRevoke flags a stored message as deleted for everyone
+func (h *EventHandler) recordRevoke(messageID, revokedBy string, revokedAt time.Time) {
+	if h.messageRepo == nil || messageID == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := h.messageRepo.MarkRevoked(ctx, h.instanceID, messageID, revokedBy, revokedAt); err != nil {
+			h.logger.WithError(err).Warn("Failed to mark message as revoked")
+		}
+	}()
+}
+
 func jidsToStrings(jids []types.JID) []string {
 	if len(jids) == 0 {
 		return nil
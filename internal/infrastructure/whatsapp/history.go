@@ -0,0 +1,199 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/proto/waWeb"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// historySyncTimeout bounds the persistence of a single history sync chunk
+const historySyncTimeout = 2 * time.Minute
+
+// mutedForever is stored for chats muted without an end date
+var mutedForever = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// persistHistorySync stores the conversations, messages and push names of a history sync chunk
+func (h *EventHandler) persistHistorySync(data *waHistorySync.HistorySync) {
+	if h.messageRepo == nil && h.chatRepo == nil && h.contactRepo == nil {
+		return
+	}
+
+	var cutoff time.Time
+	if days := h.currentSettings().HistorySyncDays; days > 0 {
+		cutoff = time.Now().AddDate(0, 0, -days)
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), historySyncTimeout)
+		defer cancel()
+
+		var chats []*entity.Chat
+		stored := 0
+		for _, conv := range data.GetConversations() {
+			chatJID, err := types.ParseJID(conv.GetID())
+			if err != nil {
+				continue
+			}
+
+			chat := h.newChatFromConversation(chatJID, conv)
+			if !cutoff.IsZero() && chat.LastMessageAt != nil && chat.LastMessageAt.Before(cutoff) {
+				continue
+			}
+			chats = append(chats, chat)
+
+			messages := h.historyMessages(chatJID, conv.GetMessages(), cutoff)
+			setChatLastMessage(chat, messages)
+			if h.messageRepo == nil || len(messages) == 0 {
+				continue
+			}
+			count, err := h.messageRepo.CreateBatch(ctx, h.instanceID, messages)
+			if err != nil {
+				h.logger.WithError(err).WithField("chat", chatJID.String()).Warn("Failed to save history sync messages")
+				continue
+			}
+			stored += count
+		}
+
+		if h.chatRepo != nil {
+			if err := h.chatRepo.UpsertBatch(ctx, chats); err != nil {
+				h.logger.WithError(err).Warn("Failed to save history sync chats")
+			}
+		}
+
+		if h.contactRepo != nil {
+			if err := h.contactRepo.UpsertBatch(ctx, h.historyContacts(data.GetPushnames())); err != nil {
+				h.logger.WithError(err).Warn("Failed to save history sync contacts")
+			}
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"instance": h.instanceName,
+			"chats":    len(chats),
+			"messages": stored,
+		}).Debug("History sync persisted")
+	}()
+}
+
+// newChatFromConversation builds a chat entity from a synced conversation
+func (h *EventHandler) newChatFromConversation(chatJID types.JID, conv *waHistorySync.Conversation) *entity.Chat {
+	chat := entity.NewChat(h.instanceID, chatJID.String())
+	chat.Name = conv.GetName()
+	if chat.Name == "" {
+		chat.Name = conv.GetDisplayName()
+	}
+	chat.IsGroup = chatJID.Server == types.GroupServer
+	chat.UnreadCount = int(conv.GetUnreadCount())
+	chat.Archived = conv.GetArchived()
+	chat.Pinned = conv.GetPinned() > 0
+
+	if muteEnd := conv.GetMuteEndTime(); muteEnd > 0 {
+		mutedUntil := mutedForever
+		if muteEnd < uint64(mutedForever.Unix()) {
+			mutedUntil = time.Unix(int64(muteEnd), 0)
+		}
+		chat.MutedUntil = &mutedUntil
+	}
+
+	lastMessage := conv.GetLastMsgTimestamp()
+	if lastMessage == 0 {
+		lastMessage = conv.GetConversationTimestamp()
+	}
+	if lastMessage > 0 {
+		lastMessageAt := time.Unix(int64(lastMessage), 0)
+		chat.LastMessageAt = &lastMessageAt
+	}
+
+	return chat
+}
+
+// setChatLastMessage sets the most recent listable message of a conversation as last message of its chat
+func setChatLastMessage(chat *entity.Chat, messages []*entity.Message) {
+	var last *entity.Message
+	for _, message := range messages {
+		if !updatesChatList(chat.JID, string(message.Type)) {
+			continue
+		}
+		if last == nil || message.Timestamp.After(last.Timestamp) {
+			last = message
+		}
+	}
+	if last == nil {
+		return
+	}
+
+	text := last.Content
+	if text == "" {
+		text = last.MediaCaption
+	}
+	chat.SetLastMessage(last.MessageID, last.Type, text, last.FromMe, last.Timestamp)
+}
+
+// historyMessages converts the synced messages of a conversation into message entities
+func (h *EventHandler) historyMessages(chatJID types.JID, history []*waHistorySync.HistorySyncMsg, cutoff time.Time) []*entity.Message {
+	if h.waClient == nil {
+		return nil
+	}
+
+	messages := make([]*entity.Message, 0, len(history))
+	for _, item := range history {
+		webMsg := item.GetMessage()
+		if webMsg == nil {
+			continue
+		}
+
+		evt, err := h.waClient.ParseWebMessage(chatJID, webMsg)
+		if err != nil || evt.Message == nil || evt.Message.ProtocolMessage != nil {
+			continue
+		}
+		if !cutoff.IsZero() && evt.Info.Timestamp.Before(cutoff) {
+			continue
+		}
+
+		message := h.newMessageEntity(newMessageReceivedEvent(evt), evt.Message, "")
+		if evt.Info.IsFromMe {
+			message.Status = historyMessageStatus(webMsg.GetStatus())
+		}
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+// historyContacts converts synced push names into contact entities
+func (h *EventHandler) historyContacts(pushnames []*waHistorySync.Pushname) []*entity.Contact {
+	contacts := make([]*entity.Contact, 0, len(pushnames))
+	for _, pushname := range pushnames {
+		jid, err := types.ParseJID(pushname.GetID())
+		if err != nil || pushname.GetPushname() == "" {
+			continue
+		}
+
+		contact := entity.NewContact(h.instanceID, jid.ToNonAD().String(), jid.User)
+		contact.PushName = pushname.GetPushname()
+		contacts = append(contacts, contact)
+	}
+	return contacts
+}
+
+// historyMessageStatus maps the status of a synced message to a message status
+func historyMessageStatus(status waWeb.WebMessageInfo_Status) entity.MessageStatus {
+	switch status {
+	case waWeb.WebMessageInfo_ERROR:
+		return entity.MessageStatusFailed
+	case waWeb.WebMessageInfo_PENDING:
+		return entity.MessageStatusPending
+	case waWeb.WebMessageInfo_DELIVERY_ACK:
+		return entity.MessageStatusDelivered
+	case waWeb.WebMessageInfo_READ:
+		return entity.MessageStatusRead
+	case waWeb.WebMessageInfo_PLAYED:
+		return entity.MessageStatusPlayed
+	default:
+		return entity.MessageStatusSent
+	}
+}
@@ -0,0 +1,262 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// leaseTimeout bounds a single lease query
+const leaseTimeout = 5 * time.Second
+
+// NotOwnerError is returned when the instance is owned by another API node
+type NotOwnerError struct {
+	Lease *entity.InstanceLease
+}
+
+func (e *NotOwnerError) Error() string {
+	return fmt.Sprintf("instance is owned by node %s", e.Lease.NodeID)
+}
+
+// SetLeaseRepository makes the manager share instances with other API nodes: an instance is only
+// connected by the node holding its lease, and leases of dead nodes are taken over. A nil
+// repository disables leases.
+func (m *Manager) SetLeaseRepository(leaseRepo repository.LeaseRepository, nodeID, nodeAddress string, ttl time.Duration) {
+	m.leaseMu.Lock()
+	defer m.leaseMu.Unlock()
+
+	m.leaseRepo = leaseRepo
+	m.nodeID = nodeID
+	m.nodeAddress = nodeAddress
+	m.leaseTTL = ttl
+	m.leases = make(map[uuid.UUID]struct{})
+}
+
+// leaseRepository returns the lease repository, nil when leases are disabled
+func (m *Manager) leaseRepository() repository.LeaseRepository {
+	m.leaseMu.Lock()
+	defer m.leaseMu.Unlock()
+	return m.leaseRepo
+}
+
+// acquireLease claims the lease of an instance for this node, returning a NotOwnerError when a
+// live lease is held by another node
+func (m *Manager) acquireLease(ctx context.Context, instanceID uuid.UUID) error {
+	leaseRepo := m.leaseRepository()
+	if leaseRepo == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, leaseTimeout)
+	defer cancel()
+
+	lease, held, err := leaseRepo.Acquire(ctx, instanceID, m.nodeID, m.nodeAddress, m.leaseTTL)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return &NotOwnerError{Lease: lease}
+	}
+
+	m.leaseMu.Lock()
+	m.leases[instanceID] = struct{}{}
+	m.leaseMu.Unlock()
+	return nil
+}
+
+// releaseLease gives up the lease of an instance so another node can take it right away
+func (m *Manager) releaseLease(instanceID uuid.UUID) {
+	leaseRepo := m.leaseRepository()
+	if leaseRepo == nil {
+		return
+	}
+
+	m.leaseMu.Lock()
+	delete(m.leases, instanceID)
+	m.leaseMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaseTimeout)
+	defer cancel()
+
+	if err := leaseRepo.Release(ctx, instanceID, m.nodeID); err != nil {
+		m.logger.WithError(err).Warn("Failed to release instance lease")
+	}
+}
+
+// releaseAllLeases gives up every lease held by this node
+func (m *Manager) releaseAllLeases() {
+	leaseRepo := m.leaseRepository()
+	if leaseRepo == nil {
+		return
+	}
+
+	m.leaseMu.Lock()
+	m.leases = make(map[uuid.UUID]struct{})
+	m.leaseMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), leaseTimeout)
+	defer cancel()
+
+	if err := leaseRepo.ReleaseAll(ctx, m.nodeID); err != nil {
+		m.logger.WithError(err).Warn("Failed to release instance leases")
+	}
+}
+
+// InstanceOwner returns the address of the node owning an instance. local is true when the instance
+// belongs to this node, or to no node at all, and the request can be served here.
+func (m *Manager) InstanceOwner(ctx context.Context, name string) (address string, local bool, err error) {
+	leaseRepo := m.leaseRepository()
+	if leaseRepo == nil || m.instanceRepo == nil {
+		return "", true, nil
+	}
+
+	instance, err := m.instanceRepo.GetByName(ctx, name)
+	if err != nil {
+		return "", false, err
+	}
+	if instance == nil {
+		return "", true, nil
+	}
+
+	lease, err := leaseRepo.Get(ctx, instance.ID)
+	if err != nil {
+		return "", false, err
+	}
+	if lease == nil || lease.IsExpired() || lease.NodeID == m.nodeID {
+		return "", true, nil
+	}
+	return lease.NodeAddress, false, nil
+}
+
+// StartLeaseRenewal periodically renews the leases held by this node, drops the instances whose
+// lease was lost and takes over the instances of nodes that stopped renewing theirs. It stops when
+// the context is cancelled.
+func (m *Manager) StartLeaseRenewal(ctx context.Context) {
+	if m.leaseRepository() == nil {
+		return
+	}
+
+	interval := m.leaseTTL / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.renewLeases(ctx)
+				m.adoptOrphanedInstances(ctx)
+			}
+		}
+	}()
+}
+
+// renewLeases extends the leases held by this node and drops the instances it no longer holds
+func (m *Manager) renewLeases(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, leaseTimeout)
+	defer cancel()
+
+	held, err := m.leaseRepo.RenewAll(ctx, m.nodeID, m.leaseTTL)
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to renew instance leases")
+		return
+	}
+
+	stillHeld := make(map[uuid.UUID]struct{}, len(held))
+	for _, instanceID := range held {
+		stillHeld[instanceID] = struct{}{}
+	}
+
+	m.leaseMu.Lock()
+	var lost []uuid.UUID
+	for instanceID := range m.leases {
+		if _, ok := stillHeld[instanceID]; !ok {
+			lost = append(lost, instanceID)
+		}
+	}
+	m.leases = stillHeld
+	m.leaseMu.Unlock()
+
+	for _, instanceID := range lost {
+		m.dropInstance(instanceID)
+	}
+}
+
+// dropInstance disconnects an instance whose lease was taken by another node, keeping its session
+// so the new owner can use it
+func (m *Manager) dropInstance(instanceID uuid.UUID) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return
+	}
+	m.cancelReconnect(client)
+
+	m.logger.WithFields(logrus.Fields{
+		"instance": client.Instance.Name,
+	}).Warn("Instance lease lost, disconnecting")
+
+	client.mu.Lock()
+	waClient := client.WAClient
+	client.Connected = false
+	client.mu.Unlock()
+
+	if waClient != nil && waClient.IsConnected() {
+		waClient.Disconnect()
+	}
+}
+
+// adoptOrphanedInstances connects the online instances whose owner stopped renewing its lease
+func (m *Manager) adoptOrphanedInstances(ctx context.Context) {
+	if !m.config.WhatsApp.AutoReconnect {
+		return
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, leaseTimeout)
+	instanceIDs, err := m.leaseRepo.ListOrphaned(listCtx)
+	cancel()
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to list orphaned instances")
+		return
+	}
+
+	for _, instanceID := range instanceIDs {
+		var notOwner *NotOwnerError
+		if err := m.acquireLease(ctx, instanceID); errors.As(err, &notOwner) {
+			continue // Another node was faster
+		} else if err != nil {
+			m.logger.WithError(err).Warn("Failed to acquire orphaned instance lease")
+			continue
+		}
+
+		if _, exists := m.GetClient(instanceID); !exists {
+			instance, err := m.instanceRepo.GetByID(ctx, instanceID)
+			if err != nil || instance == nil {
+				m.releaseLease(instanceID)
+				continue
+			}
+			if _, err := m.CreateClient(instance); err != nil {
+				m.logger.WithError(err).WithField("instance", instance.Name).Error("Failed to create client for orphaned instance")
+				m.releaseLease(instanceID)
+				continue
+			}
+		}
+
+		client, _ := m.GetClient(instanceID)
+		m.logger.WithFields(logrus.Fields{
+			"instance": client.Instance.Name,
+		}).Info("Taking over orphaned instance")
+		m.scheduleAutoReconnect(instanceID, client.Instance.Name)
+	}
+}
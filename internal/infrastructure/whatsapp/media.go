@@ -0,0 +1,240 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/application/dto"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/infrastructure/storage"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// mediaDownloadTimeout bounds the download and upload of a single media file
+const mediaDownloadTimeout = 2 * time.Minute
+
+// Media is the content of an outgoing media message, held in memory or read from a stream
+type Media struct {
+	data   []byte
+	reader io.Reader
+}
+
+// MediaFromBytes wraps media content held in memory
+func MediaFromBytes(data []byte) Media {
+	return Media{data: data}
+}
+
+// MediaFromReader wraps media content read from a stream. It is encrypted through a temporary
+// file instead of being loaded in memory, so large files can be sent.
+func MediaFromReader(r io.Reader) Media {
+	return Media{reader: r}
+}
+
+// uploadMedia encrypts and uploads the content of an outgoing media message
+func uploadMedia(ctx context.Context, waClient *whatsmeow.Client, media Media, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
+	if media.reader != nil {
+		return waClient.UploadReader(ctx, media.reader, nil, mediaType)
+	}
+	return waClient.Upload(ctx, media.data, mediaType)
+}
+
+// downloadableMedia returns the downloadable part of a media message and its mimetype
+func downloadableMedia(msg *waE2E.Message) (whatsmeow.DownloadableMessage, string) {
+	switch {
+	case msg.ImageMessage != nil:
+		return msg.ImageMessage, msg.ImageMessage.GetMimetype()
+	case msg.VideoMessage != nil:
+		return msg.VideoMessage, msg.VideoMessage.GetMimetype()
+	case msg.AudioMessage != nil:
+		return msg.AudioMessage, msg.AudioMessage.GetMimetype()
+	case msg.DocumentMessage != nil:
+		return msg.DocumentMessage, msg.DocumentMessage.GetMimetype()
+	case msg.StickerMessage != nil:
+		return msg.StickerMessage, msg.StickerMessage.GetMimetype()
+	}
+	return nil, ""
+}
+
+// mediaKeysFromMessage extracts the keys needed to download the media of a message later
+func mediaKeysFromMessage(msg *waE2E.Message) *entity.MediaKeys {
+	media, _ := downloadableMedia(msg)
+	if media == nil || media.GetDirectPath() == "" {
+		return nil
+	}
+
+	keys := &entity.MediaKeys{
+		DirectPath:    media.GetDirectPath(),
+		MediaKey:      media.GetMediaKey(),
+		FileSHA256:    media.GetFileSHA256(),
+		FileEncSHA256: media.GetFileEncSHA256(),
+		MediaType:     string(whatsmeow.GetMediaType(media)),
+	}
+	if sized, ok := media.(interface{ GetFileLength() uint64 }); ok {
+		keys.FileLength = int64(sized.GetFileLength())
+	}
+	return keys
+}
+
+// shouldStoreMedia returns true if the message carries media that must be kept in object storage
+func (h *EventHandler) shouldStoreMedia(msg *waE2E.Message) bool {
+	if h.mediaStorage == nil || h.waClient == nil || !h.currentSettings().StoreMedia {
+		return false
+	}
+	media, _ := downloadableMedia(msg)
+	return media != nil
+}
+
+// storeIncomingMedia downloads and decrypts the media of a message and uploads it to object storage.
+// It sets a presigned URL on the event and returns the permanent URL to persist, or "" on failure.
+func (h *EventHandler) storeIncomingMedia(msg *waE2E.Message, msgEvent *dto.MessageReceivedEvent) string {
+	media, mimeType := downloadableMedia(msg)
+	if media == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mediaDownloadTimeout)
+	defer cancel()
+
+	logger := h.logger.WithFields(logrus.Fields{
+		"instance":   h.instanceName,
+		"message_id": msgEvent.MessageID,
+	})
+
+	data, err := h.waClient.Download(ctx, media)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to download incoming media")
+		return ""
+	}
+
+	folder := path.Join(h.instanceID.String(), storage.GetMediaTypeFolder(mimeType))
+	result, err := h.mediaStorage.Upload(ctx, data, mimeType, folder)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to upload incoming media")
+		return ""
+	}
+
+	msgEvent.MediaURL = result.URL
+	if presignedURL, err := h.mediaStorage.GetPresignedURL(ctx, result.Key, h.mediaURLTTL); err != nil {
+		logger.WithError(err).Warn("Failed to generate presigned media URL")
+	} else {
+		msgEvent.MediaURL = presignedURL
+	}
+
+	return result.URL
+}
+
+// OpenMessageMedia opens the decrypted media of a stored message to be streamed, returning its size.
+// Media kept in object storage is streamed from there; otherwise it is downloaded from WhatsApp to
+// a temporary file, removed when the reader is closed. The caller must close the reader.
+func (m *Manager) OpenMessageMedia(ctx context.Context, instanceID uuid.UUID, message *entity.Message) (io.ReadCloser, int64, error) {
+	if m.mediaStorage != nil && message.MediaURL != "" {
+		if key, ok := m.mediaStorage.KeyFromURL(message.MediaURL); ok {
+			reader, size, _, err := m.mediaStorage.Open(ctx, key)
+			if err == nil {
+				return reader, size, nil
+			}
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"message_id": message.MessageID,
+			}).Warn("Failed to read media from storage, falling back to WhatsApp")
+		}
+	}
+
+	keys := message.MediaKeys
+	if keys == nil {
+		return nil, 0, fmt.Errorf("message has no media keys")
+	}
+
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return nil, 0, fmt.Errorf("client not found")
+	}
+
+	if client.WAClient == nil {
+		return nil, 0, fmt.Errorf("WhatsApp client is not initialized")
+	}
+
+	fileLength := int(keys.FileLength)
+	if fileLength == 0 {
+		fileLength = -1
+	}
+
+	file, err := os.CreateTemp("", "turbozap-media-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	media := &tempFile{file}
+
+	err = client.WAClient.DownloadMediaWithPathToFile(ctx, keys.DirectPath, keys.FileEncSHA256, keys.FileSHA256, keys.MediaKey, fileLength, whatsmeow.MediaType(keys.MediaType), "", file)
+	if err != nil {
+		media.Close()
+		return nil, 0, fmt.Errorf("failed to download media: %w", err)
+	}
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		media.Close()
+		return nil, 0, fmt.Errorf("failed to read downloaded media: %w", err)
+	}
+
+	return media, size, nil
+}
+
+// tempFile is a temporary file removed once closed
+type tempFile struct {
+	*os.File
+}
+
+// Close closes and removes the file
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// saveSentMessage persists an outgoing media message, including the keys needed to download it again
+func (m *Manager) saveSentMessage(instanceID uuid.UUID, chat types.JID, messageType entity.MessageType, messageID string, msg *waE2E.Message) {
+	if m.messageRepo == nil || messageID == "" {
+		return
+	}
+
+	message := entity.NewMessage(instanceID, chat.String(), messageType)
+	message.MessageID = messageID
+	message.Status = entity.MessageStatusSent
+	message.MediaKeys = mediaKeysFromMessage(msg)
+	switch {
+	case msg.ImageMessage != nil:
+		message.MediaMimeType = msg.ImageMessage.GetMimetype()
+		message.MediaCaption = msg.ImageMessage.GetCaption()
+	case msg.VideoMessage != nil:
+		message.MediaMimeType = msg.VideoMessage.GetMimetype()
+		message.MediaCaption = msg.VideoMessage.GetCaption()
+	case msg.AudioMessage != nil:
+		message.MediaMimeType = msg.AudioMessage.GetMimetype()
+	case msg.DocumentMessage != nil:
+		message.MediaMimeType = msg.DocumentMessage.GetMimetype()
+		message.MediaCaption = msg.DocumentMessage.GetCaption()
+		message.MediaFileName = msg.DocumentMessage.GetFileName()
+	case msg.StickerMessage != nil:
+		message.MediaMimeType = msg.StickerMessage.GetMimetype()
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := m.messageRepo.Create(ctx, message); err != nil {
+			m.logger.WithError(err).Warn("Failed to save sent message to database")
+		}
+	}()
+}
@@ -0,0 +1,155 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/application/dto"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// pollCreationMessage returns the poll carried by a message, whichever version of poll it is
+func pollCreationMessage(msg *waE2E.Message) *waE2E.PollCreationMessage {
+	switch {
+	case msg.GetPollCreationMessage() != nil:
+		return msg.GetPollCreationMessage()
+	case msg.GetPollCreationMessageV2() != nil:
+		return msg.GetPollCreationMessageV2()
+	case msg.GetPollCreationMessageV3() != nil:
+		return msg.GetPollCreationMessageV3()
+	case msg.GetPollCreationMessageV5() != nil:
+		return msg.GetPollCreationMessageV5()
+	}
+	return nil
+}
+
+// newPollEntity builds the stored representation of a poll
+func newPollEntity(instanceID uuid.UUID, messageID string, chat types.JID, poll *waE2E.PollCreationMessage) *entity.Poll {
+	options := make([]string, 0, len(poll.GetOptions()))
+	for _, option := range poll.GetOptions() {
+		options = append(options, option.GetOptionName())
+	}
+	return entity.NewPoll(instanceID, messageID, chat.ToNonAD().String(), poll.GetName(), options, int(poll.GetSelectableOptionsCount()))
+}
+
+// savePoll stores a received poll so votes on it can be resolved to option names
+func (h *EventHandler) savePoll(evt *events.Message, poll *waE2E.PollCreationMessage) {
+	if h.pollRepo == nil {
+		return
+	}
+
+	entityPoll := newPollEntity(h.instanceID, evt.Info.ID, evt.Info.Chat, poll)
+	entityPoll.CreatorJID = evt.Info.Sender.ToNonAD().String()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := h.pollRepo.Create(ctx, entityPoll); err != nil {
+			h.logger.WithError(err).Warn("Failed to save poll")
+		}
+	}()
+}
+
+// handlePollVote decrypts a poll vote, stores it and dispatches it as poll.vote
+func (h *EventHandler) handlePollVote(evt *events.Message) {
+	if h.waClient == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		vote, err := h.waClient.DecryptPollVote(ctx, evt)
+		if err != nil {
+			h.logger.WithError(err).WithFields(logrus.Fields{
+				"instance": h.instanceName,
+				"id":       evt.Info.ID,
+			}).Warn("Failed to decrypt poll vote")
+			return
+		}
+
+		pollID := evt.Message.GetPollUpdateMessage().GetPollCreationMessageKey().GetID()
+		voteData := dto.PollVoteData{
+			MessageID:       pollID,
+			Chat:            evt.Info.Chat.String(),
+			Voter:           evt.Info.Sender.ToNonAD().String(),
+			SelectedOptions: []string{},
+			SelectedIndexes: []int{},
+			Timestamp:       evt.Info.Timestamp,
+		}
+
+		var poll *entity.Poll
+		if h.pollRepo != nil {
+			if poll, err = h.pollRepo.GetByMessageID(ctx, h.instanceID, pollID); err != nil {
+				h.logger.WithError(err).Warn("Failed to load poll for vote")
+			}
+		}
+
+		if poll != nil {
+			voteData.Question = poll.Question
+			voteData.SelectedIndexes, voteData.SelectedOptions = resolvePollOptions(poll.Options, vote.GetSelectedOptions())
+
+			if err := h.pollRepo.SaveVote(ctx, &entity.PollVote{
+				InstanceID:      h.instanceID,
+				PollMessageID:   pollID,
+				VoterJID:        voteData.Voter,
+				SelectedOptions: voteData.SelectedOptions,
+				VotedAt:         evt.Info.Timestamp,
+			}); err != nil {
+				h.logger.WithError(err).Warn("Failed to save poll vote")
+			}
+		} else {
+			h.logger.WithFields(logrus.Fields{
+				"instance": h.instanceName,
+				"poll":     pollID,
+			}).Debug("Poll vote received for an unknown poll")
+		}
+
+		h.dispatcher.Dispatch(h.instanceID, entity.WebhookEventPollVote, voteData)
+	}()
+}
+
+// resolvePollOptions maps the option hashes of a vote back to the option indexes and names
+func resolvePollOptions(options []string, selected [][]byte) ([]int, []string) {
+	hashes := whatsmeow.HashPollOptions(options)
+	indexes := []int{}
+	names := []string{}
+	for _, hash := range selected {
+		for i, optionHash := range hashes {
+			if bytes.Equal(hash, optionHash) {
+				indexes = append(indexes, i)
+				names = append(names, options[i])
+				break
+			}
+		}
+	}
+	return indexes, names
+}
+
+// savePoll stores a poll sent through the API so votes on it can be resolved to option names
+func (m *Manager) savePoll(instanceID uuid.UUID, messageID string, chat types.JID, poll *waE2E.PollCreationMessage, creator string) {
+	if m.pollRepo == nil {
+		return
+	}
+
+	entityPoll := newPollEntity(instanceID, messageID, chat, poll)
+	entityPoll.CreatorJID = creator
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := m.pollRepo.Create(ctx, entityPoll); err != nil {
+			m.logger.WithError(err).Warn("Failed to save sent poll")
+		}
+	}()
+}
@@ -0,0 +1,109 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// proxyTestURL is requested through a proxy to check it can reach WhatsApp
+const proxyTestURL = "https://web.whatsapp.com"
+
+// ParseProxyURL validates a proxy URL, only HTTP, HTTPS and SOCKS5 proxies are supported
+func ParseProxyURL(raw string) (*url.URL, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch parsed.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", parsed.Scheme)
+	}
+	if parsed.Hostname() == "" || parsed.Port() == "" {
+		return nil, fmt.Errorf("proxy URL must include a host and a port")
+	}
+	return parsed, nil
+}
+
+// RedactProxyURL hides the password of a proxy URL
+func RedactProxyURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return parsed.Redacted()
+}
+
+// TestProxy requests WhatsApp Web through a proxy and returns how long it took
+func TestProxy(ctx context.Context, raw string) (time.Duration, error) {
+	proxyURL, err := ParseProxyURL(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+		Timeout:   15 * time.Second,
+	}
+	defer httpClient.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, proxyTestURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach WhatsApp through proxy: %w", err)
+	}
+	resp.Body.Close()
+
+	return time.Since(start), nil
+}
+
+// SetProxy changes the proxy of a running instance, reconnecting it so the new route is used.
+// An empty URL removes the proxy.
+func (m *Manager) SetProxy(ctx context.Context, instanceID uuid.UUID, proxyURL string) error {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return nil
+	}
+
+	client.mu.Lock()
+	client.Instance.ProxyURL = proxyURL
+	waClient := client.WAClient
+	client.mu.Unlock()
+
+	if waClient == nil {
+		return nil
+	}
+	if err := waClient.SetProxyAddress(proxyURL); err != nil {
+		return fmt.Errorf("failed to set proxy: %w", err)
+	}
+
+	if !waClient.IsConnected() {
+		return nil
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"instance": client.Instance.Name,
+	}).Info("Reconnecting instance to apply proxy change")
+
+	// Go through the manager so the instance status, reconnection and lease stay consistent
+	if err := m.Disconnect(instanceID); err != nil {
+		return fmt.Errorf("failed to disconnect: %w", err)
+	}
+	connectCtx, cancel := context.WithTimeout(ctx, reconnectConnectTimeout)
+	defer cancel()
+	if err := m.Connect(connectCtx, instanceID); err != nil {
+		return fmt.Errorf("failed to reconnect: %w", err)
+	}
+	return nil
+}
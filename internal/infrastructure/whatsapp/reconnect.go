@@ -0,0 +1,199 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/sirupsen/logrus"
+)
+
+// reconnectConnectTimeout bounds a single reconnection attempt
+const reconnectConnectTimeout = 30 * time.Second
+
+// ReconnectState describes a pending automatic reconnection of an instance
+type ReconnectState struct {
+	Attempt     int       // Number of the next attempt, starting at 1
+	MaxAttempts int       // Attempts before giving up (0 = unlimited)
+	NextAttempt time.Time // When the next attempt happens
+}
+
+// reconnectDelay returns the wait before the given attempt (0-based): the base delay doubled on
+// every attempt, capped at max, with half of it randomised so instances don't retry in lockstep
+func reconnectDelay(attempt int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		base = time.Second
+	}
+	if max < base {
+		max = base
+	}
+
+	delay := base
+	for i := 0; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// ReconnectState returns the pending automatic reconnection of an instance, if any
+func (m *Manager) ReconnectState(instanceID uuid.UUID) (ReconnectState, bool) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return ReconnectState{}, false
+	}
+
+	client.reconnectMu.Lock()
+	defer client.reconnectMu.Unlock()
+
+	if client.reconnectCancel == nil {
+		return ReconnectState{}, false
+	}
+	return client.reconnectState, true
+}
+
+// scheduleAutoReconnect starts the reconnection loop of an instance unless one is already running
+func (m *Manager) scheduleAutoReconnect(instanceID uuid.UUID, instanceName string) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		m.logger.Warn("Client not found for auto-reconnect")
+		return
+	}
+
+	client.reconnectMu.Lock()
+	if client.reconnectCancel != nil {
+		client.reconnectMu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	client.reconnectCancel = cancel
+	client.reconnectState = ReconnectState{MaxAttempts: m.config.WhatsApp.ReconnectMaxAttempts}
+	client.reconnectMu.Unlock()
+
+	m.logger.WithFields(logrus.Fields{
+		"instance": instanceName,
+	}).Info("Scheduling auto-reconnect")
+
+	go m.reconnectLoop(ctx, client)
+}
+
+// cancelReconnect stops the reconnection loop of an instance, if one is running
+func (m *Manager) cancelReconnect(client *Client) {
+	client.reconnectMu.Lock()
+	defer client.reconnectMu.Unlock()
+
+	if client.reconnectCancel != nil {
+		client.reconnectCancel()
+		client.reconnectCancel = nil
+	}
+	client.reconnectState = ReconnectState{}
+}
+
+// reconnectLoop retries connecting an instance with exponential backoff until it is connected,
+// the attempts run out or the loop is cancelled
+func (m *Manager) reconnectLoop(ctx context.Context, client *Client) {
+	defer m.finishReconnect(ctx, client)
+
+	base := time.Duration(m.config.WhatsApp.ReconnectInterval) * time.Second
+	max := time.Duration(m.config.WhatsApp.ReconnectMaxInterval) * time.Second
+	maxAttempts := m.config.WhatsApp.ReconnectMaxAttempts
+	logger := m.logger.WithField("instance", client.Instance.Name)
+
+	for attempt := 0; maxAttempts <= 0 || attempt < maxAttempts; attempt++ {
+		delay := reconnectDelay(attempt, base, max)
+		m.setReconnecting(client, attempt+1, time.Now().Add(delay))
+
+		logger.WithFields(logrus.Fields{
+			"attempt": attempt + 1,
+			"delay":   delay.String(),
+		}).Info("Waiting before reconnect attempt")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		client.mu.RLock()
+		isConnected := client.WAClient != nil && client.WAClient.IsConnected()
+		hasSession := client.WAClient != nil && client.WAClient.Store.ID != nil
+		client.mu.RUnlock()
+
+		if isConnected {
+			logger.Info("Instance already connected, stopping auto-reconnect")
+			return
+		}
+		if !hasSession {
+			logger.Info("No valid session found, stopping auto-reconnect")
+			return
+		}
+
+		connectCtx, cancel := context.WithTimeout(ctx, reconnectConnectTimeout)
+		err := m.Connect(connectCtx, client.Instance.ID)
+		cancel()
+		if err == nil && client.WAClient.IsConnected() {
+			logger.Info("Auto-reconnect successful")
+			return
+		}
+
+		var notOwner *NotOwnerError
+		if errors.As(err, &notOwner) {
+			logger.WithField("owner", notOwner.Lease.NodeID).Info("Instance taken over by another node, stopping auto-reconnect")
+			return
+		}
+
+		logger.WithError(err).WithField("attempt", attempt+1).Warn("Reconnect attempt failed")
+	}
+
+	logger.WithField("attempts", maxAttempts).Error("Giving up auto-reconnect")
+
+	client.mu.Lock()
+	client.Instance.SetDisconnected()
+	client.mu.Unlock()
+	m.persistInstanceState(client.Instance)
+	m.dispatchConnectionUpdate(client, entity.InstanceStatusDisconnected, "reconnect attempts exhausted")
+}
+
+// finishReconnect clears the reconnection state once the loop it belongs to has ended
+func (m *Manager) finishReconnect(ctx context.Context, client *Client) {
+	client.reconnectMu.Lock()
+	defer client.reconnectMu.Unlock()
+
+	// A cancelled loop may already have been replaced by a new one
+	if ctx.Err() != nil {
+		return
+	}
+	if client.reconnectCancel != nil {
+		client.reconnectCancel()
+		client.reconnectCancel = nil
+	}
+	client.reconnectState = ReconnectState{}
+}
+
+// setReconnecting records the next reconnection attempt and publishes the reconnecting state
+func (m *Manager) setReconnecting(client *Client, attempt int, next time.Time) {
+	client.reconnectMu.Lock()
+	client.reconnectState.Attempt = attempt
+	client.reconnectState.NextAttempt = next
+	client.reconnectMu.Unlock()
+
+	client.mu.Lock()
+	wasReconnecting := client.Instance.Status == entity.InstanceStatusReconnecting
+	client.Instance.SetReconnecting()
+	client.mu.Unlock()
+
+	if wasReconnecting {
+		return
+	}
+	m.persistInstanceState(client.Instance)
+	m.dispatchConnectionUpdate(client, entity.InstanceStatusReconnecting, "")
+}
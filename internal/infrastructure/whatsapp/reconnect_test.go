@@ -0,0 +1,39 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		base    time.Duration
+		max     time.Duration
+		want    time.Duration // Un-randomised delay, the result is within [want/2, want]
+	}{
+		{name: "first attempt", attempt: 0, base: 5 * time.Second, max: 5 * time.Minute, want: 5 * time.Second},
+		{name: "doubles on every attempt", attempt: 1, base: 5 * time.Second, max: 5 * time.Minute, want: 10 * time.Second},
+		{name: "doubles again", attempt: 3, base: 5 * time.Second, max: 5 * time.Minute, want: 40 * time.Second},
+		{name: "capped at max", attempt: 6, base: 5 * time.Second, max: 5 * time.Minute, want: 5 * time.Minute},
+		{name: "stays capped", attempt: 1000, base: 5 * time.Second, max: 5 * time.Minute, want: 5 * time.Minute},
+		{name: "no base defaults to a second", attempt: 0, base: 0, max: time.Minute, want: time.Second},
+		{name: "max below base", attempt: 4, base: 10 * time.Second, max: time.Second, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The delay is randomised, sample it enough to cover its range
+			for i := 0; i < 100; i++ {
+				got := reconnectDelay(tt.attempt, tt.base, tt.max)
+				if got < tt.want/2 || got > tt.want {
+					t.Fatalf("reconnectDelay(%d, %s, %s) = %s, want within [%s, %s]", tt.attempt, tt.base, tt.max, got, tt.want/2, tt.want)
+				}
+				if tt.max >= tt.base && got > tt.max {
+					t.Fatalf("reconnectDelay(%d, %s, %s) = %s, exceeds max", tt.attempt, tt.base, tt.max, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,450 @@
+package whatsapp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const (
+	// sessionBundleVersion is bumped when the bundle format changes
+	sessionBundleVersion = 1
+	// sessionBundleKDFIterations is the PBKDF2-SHA256 cost of the bundle key
+	sessionBundleKDFIterations = 600000
+	// MinSessionPassphraseLength is the shortest passphrase accepted to encrypt a session bundle
+	MinSessionPassphraseLength = 12
+)
+
+var (
+	// ErrInvalidSessionBundle is returned when a session bundle can't be decrypted
+	ErrInvalidSessionBundle = errors.New("invalid session bundle or wrong passphrase")
+	// ErrInstanceNotPaired is returned when exporting the session of an instance that was never paired
+	ErrInstanceNotPaired = errors.New("instance is not paired")
+	// ErrSessionInUse is returned when importing a session already used by another instance
+	ErrSessionInUse = errors.New("session is already used")
+)
+
+// deviceStoreTable is a whatsmeow table holding data of a device
+type deviceStoreTable struct {
+	Name      string
+	KeyColumn string // Column holding the device JID
+}
+
+// deviceStoreTables lists the device tables of the whatsmeow store, parents first so rows can be
+// inserted in order. whatsmeow_lid_map is shared by all devices and rebuilt by whatsmeow, so it is
+// not part of a session.
+var deviceStoreTables = []deviceStoreTable{
+	{"whatsmeow_device", "jid"},
+	{"whatsmeow_identity_keys", "our_jid"},
+	{"whatsmeow_pre_keys", "jid"},
+	{"whatsmeow_sessions", "our_jid"},
+	{"whatsmeow_sender_keys", "our_jid"},
+	{"whatsmeow_app_state_sync_keys", "jid"},
+	{"whatsmeow_app_state_version", "jid"},
+	{"whatsmeow_app_state_mutation_macs", "jid"},
+	{"whatsmeow_contacts", "our_jid"},
+	{"whatsmeow_chat_settings", "our_jid"},
+	{"whatsmeow_message_secrets", "our_jid"},
+	{"whatsmeow_privacy_tokens", "our_jid"},
+	{"whatsmeow_event_buffer", "our_jid"},
+}
+
+func init() {
+	gob.Register(time.Time{})
+}
+
+// deviceSnapshot holds every row of a device in the whatsmeow store
+type deviceSnapshot struct {
+	DeviceJID  string
+	ExportedAt time.Time
+	Tables     []tableSnapshot
+}
+
+// tableSnapshot holds the rows of a device in one table
+type tableSnapshot struct {
+	Name    string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// sessionBundle is the encrypted envelope of a device snapshot
+type sessionBundle struct {
+	Version    int    `json:"version"`
+	DeviceJID  string `json:"device_jid"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// readDeviceSnapshot reads the rows of a device from the whatsmeow store
+func readDeviceSnapshot(ctx context.Context, db *sql.DB, deviceJID string) (*deviceSnapshot, error) {
+	snapshot := &deviceSnapshot{DeviceJID: deviceJID, ExportedAt: time.Now()}
+
+	for _, table := range deviceStoreTables {
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s = $1", table.Name, table.KeyColumn), deviceJID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table.Name, err)
+		}
+
+		tableRows, columns, err := scanAllRows(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table.Name, err)
+		}
+		if table.Name == "whatsmeow_device" && len(tableRows) == 0 {
+			return nil, fmt.Errorf("device %s not found in store", deviceJID)
+		}
+
+		snapshot.Tables = append(snapshot.Tables, tableSnapshot{
+			Name:    table.Name,
+			Columns: columns,
+			Rows:    tableRows,
+		})
+	}
+
+	return snapshot, nil
+}
+
+// scanAllRows reads every row of a result set as generic values and closes it
+func scanAllRows(rows *sql.Rows) ([][]interface{}, []string, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, err
+		}
+		result = append(result, values)
+	}
+
+	return result, columns, rows.Err()
+}
+
+// writeDeviceSnapshot replaces the rows of a device in the whatsmeow store with the ones of a snapshot
+func writeDeviceSnapshot(ctx context.Context, db *sql.DB, snapshot *deviceSnapshot) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Deleting the device cascades to the other tables, the explicit deletes cover stores created
+	// without foreign keys
+	for i := len(deviceStoreTables) - 1; i >= 0; i-- {
+		table := deviceStoreTables[i]
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = $1", table.Name, table.KeyColumn), snapshot.DeviceJID); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table.Name, err)
+		}
+	}
+
+	for _, table := range snapshot.Tables {
+		if !isDeviceStoreTable(table.Name) {
+			return fmt.Errorf("unexpected table %s in session", table.Name)
+		}
+		if len(table.Rows) == 0 {
+			continue
+		}
+
+		columnTypes, err := columnTypeNames(ctx, tx, table.Name, table.Columns)
+		if err != nil {
+			return fmt.Errorf("failed to read columns of %s: %w", table.Name, err)
+		}
+
+		placeholders := make([]string, len(table.Columns))
+		for i := range placeholders {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table.Name, strings.Join(table.Columns, ", "), strings.Join(placeholders, ", "))
+
+		for _, row := range table.Rows {
+			values := make([]interface{}, len(row))
+			for i, value := range row {
+				values[i] = convertColumnValue(value, columnTypes[i])
+			}
+			if _, err := tx.ExecContext(ctx, query, values...); err != nil {
+				return fmt.Errorf("failed to write %s: %w", table.Name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	return nil
+}
+
+// columnTypeNames returns the database type names of the given columns of a table
+func columnTypeNames(ctx context.Context, tx *sql.Tx, table string, columns []string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", strings.Join(columns, ", "), table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		names[i] = strings.ToUpper(columnType.DatabaseTypeName())
+	}
+	return names, rows.Err()
+}
+
+// convertColumnValue converts a value read from a store to the column type of the store it is
+// written to. SQLite has no boolean type and returns the booleans of the whatsmeow tables as
+// integers, which Postgres doesn't accept in its boolean columns.
+func convertColumnValue(value interface{}, typeName string) interface{} {
+	switch typeName {
+	case "BOOL", "BOOLEAN":
+		if v, ok := value.(int64); ok {
+			return v != 0
+		}
+	}
+	return value
+}
+
+// isDeviceStoreTable returns true if the table is one of the device tables of the whatsmeow store
+func isDeviceStoreTable(name string) bool {
+	for _, table := range deviceStoreTables {
+		if table.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sealSessionBundle compresses and encrypts a device snapshot with a key derived from the passphrase
+func sealSessionBundle(snapshot *deviceSnapshot, passphrase string) ([]byte, error) {
+	var plaintext bytes.Buffer
+	zw := gzip.NewWriter(&plaintext)
+	if err := gob.NewEncoder(zw).Encode(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress session: %w", err)
+	}
+
+	bundle := sessionBundle{
+		Version:    sessionBundleVersion,
+		DeviceJID:  snapshot.DeviceJID,
+		KDF:        "pbkdf2-sha256",
+		Iterations: sessionBundleKDFIterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(bundle.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := sessionBundleCipher(passphrase, bundle.Salt, bundle.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	bundle.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(bundle.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	// The device JID is authenticated so it can't be swapped in the envelope
+	bundle.Ciphertext = aead.Seal(nil, bundle.Nonce, plaintext.Bytes(), []byte(bundle.DeviceJID))
+
+	return json.Marshal(bundle)
+}
+
+// openSessionBundle decrypts a session bundle
+func openSessionBundle(raw []byte, passphrase string) (*deviceSnapshot, error) {
+	var bundle sessionBundle
+	if err := json.Unmarshal(raw, &bundle); err != nil {
+		return nil, ErrInvalidSessionBundle
+	}
+	if bundle.Version != sessionBundleVersion {
+		return nil, fmt.Errorf("unsupported session bundle version %d", bundle.Version)
+	}
+	if bundle.Iterations <= 0 || bundle.Iterations > 10*sessionBundleKDFIterations {
+		return nil, ErrInvalidSessionBundle
+	}
+
+	aead, err := sessionBundleCipher(passphrase, bundle.Salt, bundle.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(bundle.Nonce) != aead.NonceSize() {
+		return nil, ErrInvalidSessionBundle
+	}
+	plaintext, err := aead.Open(nil, bundle.Nonce, bundle.Ciphertext, []byte(bundle.DeviceJID))
+	if err != nil {
+		return nil, ErrInvalidSessionBundle
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(plaintext))
+	if err != nil {
+		return nil, ErrInvalidSessionBundle
+	}
+	defer zr.Close()
+
+	var snapshot deviceSnapshot
+	if err := gob.NewDecoder(zr).Decode(&snapshot); err != nil {
+		return nil, ErrInvalidSessionBundle
+	}
+	if snapshot.DeviceJID != bundle.DeviceJID {
+		return nil, ErrInvalidSessionBundle
+	}
+	return &snapshot, nil
+}
+
+// sessionBundleCipher derives the AES-256-GCM cipher of a bundle from its passphrase
+func sessionBundleCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// ExportSession returns the device store of an instance as a bundle encrypted with the passphrase.
+// The bundle can be imported on another deployment to restore the session without pairing again.
+func (m *Manager) ExportSession(ctx context.Context, instanceID uuid.UUID, passphrase string) ([]byte, string, error) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return nil, "", fmt.Errorf("client not found")
+	}
+
+	client.mu.RLock()
+	var deviceJID string
+	if client.WAClient != nil && client.WAClient.Store.ID != nil {
+		deviceJID = client.WAClient.Store.ID.String()
+	}
+	client.mu.RUnlock()
+
+	if deviceJID == "" {
+		return nil, "", ErrInstanceNotPaired
+	}
+
+	snapshot, err := readDeviceSnapshot(ctx, m.storeDB, deviceJID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	bundle, err := sealSessionBundle(snapshot, passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"instance":   client.Instance.Name,
+		"device_jid": deviceJID,
+	}).Info("Session exported")
+
+	return bundle, deviceJID, nil
+}
+
+// ImportSession replaces the session of an instance with the one of an exported bundle and connects
+// it. The exporting deployment must not connect the session anymore, WhatsApp only keeps one socket
+// per device.
+func (m *Manager) ImportSession(ctx context.Context, instanceID uuid.UUID, bundle []byte, passphrase string) (string, error) {
+	client, exists := m.GetClient(instanceID)
+	if !exists {
+		return "", fmt.Errorf("client not found")
+	}
+
+	snapshot, err := openSessionBundle(bundle, passphrase)
+	if err != nil {
+		return "", err
+	}
+	if _, err := types.ParseJID(snapshot.DeviceJID); err != nil {
+		return "", ErrInvalidSessionBundle
+	}
+
+	m.mu.RLock()
+	for id, other := range m.clients {
+		if id != instanceID && other.Instance.DeviceJID == snapshot.DeviceJID {
+			m.mu.RUnlock()
+			return "", fmt.Errorf("%w by instance %s", ErrSessionInUse, other.Instance.Name)
+		}
+	}
+	m.mu.RUnlock()
+
+	// Stop the current session before its store is replaced
+	m.cancelReconnect(client)
+	client.mu.Lock()
+	waClient := client.WAClient
+	client.mu.Unlock()
+	if waClient != nil && waClient.IsConnected() {
+		waClient.Disconnect()
+	}
+
+	if err := writeDeviceSnapshot(ctx, m.storeDB, snapshot); err != nil {
+		return "", err
+	}
+
+	// The previous device of the instance is replaced by the imported one
+	previousJID := ""
+	if waClient != nil && waClient.Store.ID != nil {
+		previousJID = waClient.Store.ID.String()
+	}
+	if previousJID != "" && previousJID != snapshot.DeviceJID && client.Device != nil {
+		if err := client.Device.Delete(ctx); err != nil {
+			m.logger.WithError(err).WithField("instance", client.Instance.Name).Warn("Failed to delete replaced device")
+		}
+	}
+
+	m.mu.Lock()
+	delete(m.clients, instanceID)
+	m.mu.Unlock()
+
+	instance := client.Instance
+	instance.SetDeviceJID(snapshot.DeviceJID)
+	instance.SetDisconnected()
+	if m.instanceRepo != nil {
+		if err := m.instanceRepo.Update(ctx, instance); err != nil {
+			return "", fmt.Errorf("failed to update instance: %w", err)
+		}
+	}
+
+	if _, err := m.CreateClient(instance); err != nil {
+		return "", fmt.Errorf("failed to create client: %w", err)
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"instance":   instance.Name,
+		"device_jid": snapshot.DeviceJID,
+		"exported":   snapshot.ExportedAt,
+	}).Info("Session imported")
+
+	if err := m.Connect(ctx, instanceID); err != nil {
+		return snapshot.DeviceJID, fmt.Errorf("session imported but failed to connect: %w", err)
+	}
+	return snapshot.DeviceJID, nil
+}
@@ -0,0 +1,103 @@
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waCompanionReg"
+	"go.mau.fi/whatsmeow/proto/waWa6"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// ignoresMessage returns true if the instance settings drop the chat a message was received in
+func (h *EventHandler) ignoresMessage(evt *events.Message) bool {
+	settings := h.currentSettings()
+	if settings.IgnoreStatus && evt.Info.Chat == types.StatusBroadcastJID {
+		return true
+	}
+	return settings.IgnoreGroups && evt.Info.IsGroup
+}
+
+// shouldAutoRead returns true if a received message gets a read receipt right away
+func (h *EventHandler) shouldAutoRead(evt *events.Message) bool {
+	if h.waClient == nil || evt.Info.IsFromMe || evt.Info.Chat == types.StatusBroadcastJID {
+		return false
+	}
+	return h.currentSettings().ReadMessages
+}
+
+// markMessageRead sends a read receipt for a received message
+func (h *EventHandler) markMessageRead(evt *events.Message) {
+	sender := types.EmptyJID
+	if evt.Info.IsGroup {
+		sender = evt.Info.Sender
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := h.waClient.MarkRead(ctx, []types.MessageID{evt.Info.ID}, time.Now(), evt.Info.Chat, sender); err != nil {
+			h.logger.WithError(err).Warn("Failed to mark incoming message as read")
+		}
+	}()
+}
+
+// sendSettingsPresence announces the account as online when the instance is configured to stay online
+func (h *EventHandler) sendSettingsPresence() {
+	if h.waClient == nil || !h.currentSettings().AlwaysOnline {
+		return
+	}
+	if err := h.waClient.SendPresence(context.Background(), types.PresenceAvailable); err != nil {
+		h.logger.WithError(err).Warn("Failed to send online presence")
+	}
+}
+
+// applyPresence updates the presence of a connected client after its always online setting changed
+func (m *Manager) applyPresence(client *Client, online bool) {
+	if client.WAClient == nil || !client.WAClient.IsLoggedIn() {
+		return
+	}
+
+	presence := types.PresenceUnavailable
+	if online {
+		presence = types.PresenceAvailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := client.WAClient.SendPresence(ctx, presence); err != nil {
+		m.logger.WithError(err).WithField("instance", client.Instance.Name).Warn("Failed to update presence after settings change")
+	}
+}
+
+// clientPayload returns the payload sent to WhatsApp when connecting, requesting
+// the full history when a new device is paired and the instance is configured to
+func (c *Client) clientPayload() *waWa6.ClientPayload {
+	payload := c.WAClient.Store.GetClientPayload()
+	if payload.DevicePairingData == nil {
+		return payload
+	}
+
+	settings := c.Settings()
+	if !settings.SyncFullHistory {
+		return payload
+	}
+
+	props := proto.Clone(store.DeviceProps).(*waCompanionReg.DeviceProps)
+	props.RequireFullSync = proto.Bool(true)
+	if settings.HistorySyncDays > 0 && props.HistorySyncConfig != nil {
+		props.HistorySyncConfig.FullSyncDaysLimit = proto.Uint32(uint32(settings.HistorySyncDays))
+	}
+
+	raw, err := proto.Marshal(props)
+	if err != nil {
+		return payload
+	}
+	payload.DevicePairingData.DeviceProps = raw
+	return payload
+}
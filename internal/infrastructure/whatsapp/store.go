@@ -2,15 +2,124 @@ package whatsapp
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib" // Register pgx driver for database/sql
 	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
 	waLog "go.mau.fi/whatsmeow/util/log"
+	_ "modernc.org/sqlite" // Register sqlite driver for database/sql
 )
 
+// Device store backends
+const (
+	DeviceStorePostgres = "postgres"
+	DeviceStoreSQLite   = "sqlite"
+)
+
+// defaultSQLiteStorePath is the SQLite device store used when no file is configured
+const defaultSQLiteStorePath = "whatsmeow.db"
+
+// OpenDeviceStore opens the whatsmeow device store on a Postgres database or a SQLite file and
+// upgrades its schema
+func OpenDeviceStore(ctx context.Context, driver, address string, logger waLog.Logger) (*sql.DB, *sqlstore.Container, error) {
+	var db *sql.DB
+	var dialect string
+	var err error
+
+	switch driver {
+	case DeviceStorePostgres, "":
+		db, err = sql.Open("pgx", address)
+		dialect = "postgres"
+	case DeviceStoreSQLite:
+		db, err = sql.Open("sqlite", sqliteDSN(address))
+		dialect = "sqlite3"
+	default:
+		return nil, nil, fmt.Errorf("unsupported device store driver %q, use postgres or sqlite", driver)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open device store: %w", err)
+	}
+
+	container := sqlstore.NewWithDB(db, dialect, logger)
+	if err := container.Upgrade(ctx); err != nil {
+		return db, container, fmt.Errorf("failed to upgrade device store: %w", err)
+	}
+	return db, container, nil
+}
+
+// sqliteDSN turns a SQLite file path into a DSN with the pragmas whatsmeow needs
+func sqliteDSN(path string) string {
+	if strings.Contains(path, "?") {
+		return path
+	}
+	if !strings.HasPrefix(path, "file:") {
+		path = "file:" + path
+	}
+	return path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(10000)"
+}
+
+// CopyDevices copies every device of a whatsmeow store to another one, replacing the devices
+// already there with the same JID. It returns the number of devices copied.
+func CopyDevices(ctx context.Context, src, dst *sql.DB) (int, error) {
+	rows, err := src.QueryContext(ctx, "SELECT jid FROM whatsmeow_device")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	var deviceJIDs []string
+	for rows.Next() {
+		var jid string
+		if err := rows.Scan(&jid); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan device: %w", err)
+		}
+		deviceJIDs = append(deviceJIDs, jid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	for i, jid := range deviceJIDs {
+		snapshot, err := readDeviceSnapshot(ctx, src, jid)
+		if err != nil {
+			return i, err
+		}
+		if err := writeDeviceSnapshot(ctx, dst, snapshot); err != nil {
+			return i, fmt.Errorf("failed to copy device %s: %w", jid, err)
+		}
+	}
+
+	if err := copyLIDMap(ctx, src, dst); err != nil {
+		return len(deviceJIDs), err
+	}
+	return len(deviceJIDs), nil
+}
+
+// copyLIDMap copies the LID to phone number mappings shared by all devices
+func copyLIDMap(ctx context.Context, src, dst *sql.DB) error {
+	rows, err := src.QueryContext(ctx, "SELECT lid, pn FROM whatsmeow_lid_map")
+	if err != nil {
+		return fmt.Errorf("failed to read LID mappings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lid, pn string
+		if err := rows.Scan(&lid, &pn); err != nil {
+			return fmt.Errorf("failed to scan LID mapping: %w", err)
+		}
+		if _, err := dst.ExecContext(ctx, "INSERT INTO whatsmeow_lid_map (lid, pn) VALUES ($1, $2) ON CONFLICT DO NOTHING", lid, pn); err != nil {
+			return fmt.Errorf("failed to write LID mapping: %w", err)
+		}
+	}
+	return rows.Err()
+}
+
 // StoreContainer wraps the whatsmeow SQL store container
 type StoreContainer struct {
 	container *sqlstore.Container
@@ -0,0 +1,144 @@
+package whatsapp
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waAdv"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+func TestConvertColumnValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		typeName string
+		want     interface{}
+	}{
+		{name: "sqlite true into a postgres boolean", value: int64(1), typeName: "BOOL", want: true},
+		{name: "sqlite false into a postgres boolean", value: int64(0), typeName: "BOOL", want: false},
+		{name: "sqlite true into a sqlite boolean", value: int64(1), typeName: "BOOLEAN", want: true},
+		{name: "postgres boolean", value: true, typeName: "BOOL", want: true},
+		{name: "integer column", value: int64(1), typeName: "INT8", want: int64(1)},
+		{name: "null boolean", value: nil, typeName: "BOOL", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertColumnValue(tt.value, tt.typeName); got != tt.want {
+				t.Fatalf("convertColumnValue(%v, %s) = %#v, want %#v", tt.value, tt.typeName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyDevicesSQLite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	srcDB, src := openTestDeviceStore(t, filepath.Join(dir, "src.db"))
+	dstDB, dst := openTestDeviceStore(t, filepath.Join(dir, "dst.db"))
+
+	jid := types.JID{User: "5511999999999", Device: 12, Server: types.DefaultUserServer}
+	chat := types.NewJID("5511888888888", types.DefaultUserServer)
+
+	device := src.NewDevice()
+	device.ID = &jid
+	device.Account = &waAdv.ADVSignedDeviceIdentity{
+		Details:             []byte("details"),
+		AccountSignature:    make([]byte, 64),
+		AccountSignatureKey: make([]byte, 32),
+		DeviceSignature:     make([]byte, 64),
+	}
+	device.PushName = "TurboZap"
+	if err := device.Save(ctx); err != nil {
+		t.Fatalf("failed to save device: %v", err)
+	}
+	preKeys, err := device.PreKeys.GetOrGenPreKeys(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to generate pre keys: %v", err)
+	}
+	if err := device.PreKeys.MarkPreKeysAsUploaded(ctx, preKeys[len(preKeys)-1].KeyID); err != nil {
+		t.Fatalf("failed to mark pre keys as uploaded: %v", err)
+	}
+	if err := device.ChatSettings.PutPinned(ctx, chat, true); err != nil {
+		t.Fatalf("failed to pin chat: %v", err)
+	}
+
+	copied, err := CopyDevices(ctx, srcDB, dstDB)
+	if err != nil {
+		t.Fatalf("CopyDevices() error = %v", err)
+	}
+	if copied != 1 {
+		t.Fatalf("CopyDevices() copied %d devices, want 1", copied)
+	}
+
+	// Copying again replaces the device instead of failing on its rows
+	if _, err := CopyDevices(ctx, srcDB, dstDB); err != nil {
+		t.Fatalf("CopyDevices() again error = %v", err)
+	}
+
+	restored, err := dst.GetDevice(ctx, jid)
+	if err != nil {
+		t.Fatalf("failed to load copied device: %v", err)
+	}
+	if restored == nil {
+		t.Fatal("copied device not found")
+	}
+	if restored.PushName != device.PushName || restored.RegistrationID != device.RegistrationID {
+		t.Fatalf("copied device = %q/%d, want %q/%d", restored.PushName, restored.RegistrationID, device.PushName, device.RegistrationID)
+	}
+	if *restored.IdentityKey.Priv != *device.IdentityKey.Priv {
+		t.Fatal("copied device has a different identity key")
+	}
+
+	uploaded, err := restored.PreKeys.UploadedPreKeyCount(ctx)
+	if err != nil {
+		t.Fatalf("failed to count uploaded pre keys: %v", err)
+	}
+	if uploaded != len(preKeys) {
+		t.Fatalf("copied device has %d uploaded pre keys, want %d", uploaded, len(preKeys))
+	}
+
+	settings, err := restored.ChatSettings.GetChatSettings(ctx, chat)
+	if err != nil {
+		t.Fatalf("failed to get chat settings: %v", err)
+	}
+	if !settings.Pinned {
+		t.Fatal("copied chat settings lost the pinned flag")
+	}
+}
+
+func TestColumnTypeNamesSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestDeviceStore(t, filepath.Join(t.TempDir(), "store.db"))
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	names, err := columnTypeNames(ctx, tx, "whatsmeow_pre_keys", []string{"jid", "uploaded"})
+	if err != nil {
+		t.Fatalf("columnTypeNames() error = %v", err)
+	}
+	if len(names) != 2 || names[1] != "BOOLEAN" {
+		t.Fatalf("columnTypeNames() = %v, want the uploaded column to be BOOLEAN", names)
+	}
+}
+
+func openTestDeviceStore(t *testing.T, path string) (*sql.DB, *sqlstore.Container) {
+	t.Helper()
+
+	db, container, err := OpenDeviceStore(context.Background(), DeviceStoreSQLite, path, waLog.Noop)
+	if err != nil {
+		t.Fatalf("failed to open device store: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, container
+}
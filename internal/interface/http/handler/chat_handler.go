@@ -0,0 +1,457 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jonadableite/turbozap-api/internal/application/dto"
+	"github.com/jonadableite/turbozap-api/internal/domain/entity"
+	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+	"github.com/jonadableite/turbozap-api/internal/infrastructure/whatsapp"
+	"github.com/jonadableite/turbozap-api/internal/interface/response"
+	"github.com/jonadableite/turbozap-api/pkg/validator"
+	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const (
+	defaultChatMessagesLimit = 50
+	maxChatMessagesLimit     = 200
+	defaultChatListLimit     = 50
+	maxChatListLimit         = 500
+)
+
+// ChatHandler handles chat-related requests
+type ChatHandler struct {
+	instanceRepo repository.InstanceRepository
+	messageRepo  repository.MessageRepository
+	chatRepo     repository.ChatRepository
+	waManager    *whatsapp.Manager
+	logger       *logrus.Logger
+}
+
+// NewChatHandler creates a new chat handler
+func NewChatHandler(instanceRepo repository.InstanceRepository, messageRepo repository.MessageRepository, chatRepo repository.ChatRepository, waManager *whatsapp.Manager, logger *logrus.Logger) *ChatHandler {
+	return &ChatHandler{
+		instanceRepo: instanceRepo,
+		messageRepo:  messageRepo,
+		chatRepo:     chatRepo,
+		waManager:    waManager,
+		logger:       logger,
+	}
+}
+
+// getInstance gets the instance from the route and checks access to it
+func (h *ChatHandler) getInstance(c *fiber.Ctx) (*entity.Instance, error) {
+	instanceName := c.Params("instance")
+	if instanceName == "" {
+		return nil, response.BadRequest(c, "Instance name is required")
+	}
+
+	instance, err := h.instanceRepo.GetByName(c.Context(), instanceName)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get instance")
+		return nil, response.InternalServerError(c, "Failed to get instance")
+	}
+	if instance == nil {
+		return nil, response.NotFound(c, "Instance not found")
+	}
+
+	if err := AuthorizeInstanceAccess(c, instance); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// ListChats returns the chat list of an instance
+func (h *ChatHandler) ListChats(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	var query dto.ListChatsQuery
+	if err := c.QueryParser(&query); err != nil {
+		return response.BadRequest(c, "Invalid query parameters")
+	}
+
+	filter := repository.ChatFilter{
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultChatListLimit
+	}
+	if filter.Limit > maxChatListLimit {
+		filter.Limit = maxChatListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	switch repository.ChatSort(query.Sort) {
+	case "", repository.ChatSortLastMessage:
+		filter.Sort = repository.ChatSortLastMessage
+	case repository.ChatSortUnread:
+		filter.Sort = repository.ChatSortUnread
+	case repository.ChatSortName:
+		filter.Sort = repository.ChatSortName
+		filter.Ascending = true
+	default:
+		return response.BadRequest(c, "sort must be last_message, unread or name")
+	}
+
+	switch strings.ToLower(query.Order) {
+	case "":
+	case "asc":
+		filter.Ascending = true
+	case "desc":
+		filter.Ascending = false
+	default:
+		return response.BadRequest(c, "order must be asc or desc")
+	}
+
+	if query.Archived != "" {
+		archived, err := strconv.ParseBool(query.Archived)
+		if err != nil {
+			return response.BadRequest(c, "archived must be true or false")
+		}
+		filter.Archived = &archived
+	}
+	if query.Pinned != "" {
+		pinned, err := strconv.ParseBool(query.Pinned)
+		if err != nil {
+			return response.BadRequest(c, "pinned must be true or false")
+		}
+		filter.Pinned = &pinned
+	}
+	if query.Unread != "" {
+		unread, err := strconv.ParseBool(query.Unread)
+		if err != nil {
+			return response.BadRequest(c, "unread must be true or false")
+		}
+		filter.UnreadOnly = unread
+	}
+
+	chats, err := h.chatRepo.List(c.Context(), instance.ID, filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list chats")
+		return response.InternalServerError(c, "Failed to list chats")
+	}
+
+	total, err := h.chatRepo.Count(c.Context(), instance.ID, filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to count chats")
+		return response.InternalServerError(c, "Failed to list chats")
+	}
+
+	resp := dto.ListChatsResponse{
+		Chats:  make([]dto.ChatResponse, 0, len(chats)),
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+	for _, chat := range chats {
+		resp.Chats = append(resp.Chats, dto.ToChatResponse(chat))
+	}
+
+	return response.Success(c, resp)
+}
+
+// GetMessages returns the stored message history of a chat
+func (h *ChatHandler) GetMessages(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	jid := chatJIDParam(c.Params("jid"))
+	if jid == "" {
+		return response.BadRequest(c, "Invalid chat JID")
+	}
+
+	var query dto.ChatMessagesQuery
+	if err := c.QueryParser(&query); err != nil {
+		return response.BadRequest(c, "Invalid query parameters")
+	}
+
+	filter := repository.MessageFilter{Limit: query.Limit}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultChatMessagesLimit
+	}
+	if filter.Limit > maxChatMessagesLimit {
+		filter.Limit = maxChatMessagesLimit
+	}
+
+	if query.Type != "" {
+		for _, msgType := range strings.Split(query.Type, ",") {
+			if msgType = strings.TrimSpace(msgType); msgType != "" {
+				filter.Types = append(filter.Types, entity.MessageType(msgType))
+			}
+		}
+	}
+
+	if query.FromMe != "" {
+		fromMe, err := strconv.ParseBool(query.FromMe)
+		if err != nil {
+			return response.BadRequest(c, "from_me must be true or false")
+		}
+		filter.FromMe = &fromMe
+	}
+
+	if query.Since != "" {
+		since, err := parseTimeParam(query.Since)
+		if err != nil {
+			return response.BadRequest(c, "since must be an RFC3339 or unix timestamp")
+		}
+		filter.Since = &since
+	}
+	if query.Until != "" {
+		until, err := parseTimeParam(query.Until)
+		if err != nil {
+			return response.BadRequest(c, "until must be an RFC3339 or unix timestamp")
+		}
+		filter.Until = &until
+	}
+
+	if query.Before != "" {
+		if filter.Before, err = h.resolveCursor(c, instance, jid, query.Before); err != nil || filter.Before == nil {
+			return err
+		}
+	}
+	if query.After != "" {
+		if filter.After, err = h.resolveCursor(c, instance, jid, query.After); err != nil || filter.After == nil {
+			return err
+		}
+	}
+
+	// One extra row tells whether another page exists
+	requested := filter.Limit
+	filter.Limit++
+
+	messages, err := h.messageRepo.GetByRemoteJID(c.Context(), instance.ID, jid, filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get chat messages")
+		return response.InternalServerError(c, "Failed to get chat messages")
+	}
+
+	hasMore := len(messages) > requested
+	if hasMore {
+		// Drop the extra row on the side the page was walking towards
+		if filter.After != nil && filter.Before == nil {
+			messages = messages[1:]
+		} else {
+			messages = messages[:requested]
+		}
+	}
+	if messages == nil {
+		messages = []*entity.Message{}
+	}
+
+	resp := dto.ChatMessagesResponse{
+		JID:      jid,
+		Messages: messages,
+		Count:    len(messages),
+		HasMore:  hasMore,
+	}
+	if len(messages) > 0 {
+		resp.AfterCursor = messages[0].MessageID
+		resp.BeforeCursor = messages[len(messages)-1].MessageID
+	}
+
+	return response.Success(c, resp)
+}
+
+// getConnectedChat gets the instance and chat JID of a chat action, requiring a connected instance
+func (h *ChatHandler) getConnectedChat(c *fiber.Ctx) (*entity.Instance, string, error) {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return nil, "", err
+	}
+
+	if !h.waManager.IsConnected(instance.ID) {
+		return nil, "", response.BadRequest(c, "Instance is not connected to WhatsApp")
+	}
+
+	jid := chatJIDParam(c.Params("jid"))
+	if jid == "" {
+		return nil, "", response.BadRequest(c, "Invalid chat JID")
+	}
+
+	return instance, jid, nil
+}
+
+// ArchiveChat archives a chat
+func (h *ChatHandler) ArchiveChat(c *fiber.Ctx) error {
+	return h.setArchived(c, true)
+}
+
+// UnarchiveChat unarchives a chat
+func (h *ChatHandler) UnarchiveChat(c *fiber.Ctx) error {
+	return h.setArchived(c, false)
+}
+
+func (h *ChatHandler) setArchived(c *fiber.Ctx, archive bool) error {
+	instance, jid, err := h.getConnectedChat(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	if err := h.waManager.ArchiveChat(c.Context(), instance.ID, jid, archive); err != nil {
+		h.logger.WithError(err).Error("Failed to archive chat")
+		return response.InternalServerError(c, "Failed to update chat archive state")
+	}
+
+	return response.Success(c, fiber.Map{
+		"chat":     jid,
+		"archived": archive,
+	})
+}
+
+// PinChat pins a chat
+func (h *ChatHandler) PinChat(c *fiber.Ctx) error {
+	return h.setPinned(c, true)
+}
+
+// UnpinChat unpins a chat
+func (h *ChatHandler) UnpinChat(c *fiber.Ctx) error {
+	return h.setPinned(c, false)
+}
+
+func (h *ChatHandler) setPinned(c *fiber.Ctx, pin bool) error {
+	instance, jid, err := h.getConnectedChat(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	if err := h.waManager.PinChat(c.Context(), instance.ID, jid, pin); err != nil {
+		h.logger.WithError(err).Error("Failed to pin chat")
+		return response.InternalServerError(c, "Failed to update chat pin state")
+	}
+
+	return response.Success(c, fiber.Map{
+		"chat":   jid,
+		"pinned": pin,
+	})
+}
+
+// MuteChat mutes a chat for a duration, or forever when none is given
+func (h *ChatHandler) MuteChat(c *fiber.Ctx) error {
+	instance, jid, err := h.getConnectedChat(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	var req dto.MuteChatRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body")
+		}
+	}
+	if req.Duration < 0 {
+		return response.BadRequest(c, "duration must not be negative")
+	}
+
+	duration := time.Duration(req.Duration) * time.Second
+	if err := h.waManager.MuteChat(c.Context(), instance.ID, jid, true, duration); err != nil {
+		h.logger.WithError(err).Error("Failed to mute chat")
+		return response.InternalServerError(c, "Failed to mute chat")
+	}
+
+	result := fiber.Map{
+		"chat":  jid,
+		"muted": true,
+	}
+	if duration > 0 {
+		result["muted_until"] = time.Now().Add(duration)
+	}
+	return response.Success(c, result)
+}
+
+// UnmuteChat unmutes a chat
+func (h *ChatHandler) UnmuteChat(c *fiber.Ctx) error {
+	instance, jid, err := h.getConnectedChat(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	if err := h.waManager.MuteChat(c.Context(), instance.ID, jid, false, 0); err != nil {
+		h.logger.WithError(err).Error("Failed to unmute chat")
+		return response.InternalServerError(c, "Failed to unmute chat")
+	}
+
+	return response.Success(c, fiber.Map{
+		"chat":  jid,
+		"muted": false,
+	})
+}
+
+// MarkChatRead marks a chat as read
+func (h *ChatHandler) MarkChatRead(c *fiber.Ctx) error {
+	return h.setRead(c, true)
+}
+
+// MarkChatUnread marks a chat as unread
+func (h *ChatHandler) MarkChatUnread(c *fiber.Ctx) error {
+	return h.setRead(c, false)
+}
+
+func (h *ChatHandler) setRead(c *fiber.Ctx, read bool) error {
+	instance, jid, err := h.getConnectedChat(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	if err := h.waManager.MarkChatRead(c.Context(), instance.ID, jid, read); err != nil {
+		h.logger.WithError(err).Error("Failed to mark chat as read")
+		return response.InternalServerError(c, "Failed to update chat read state")
+	}
+
+	return response.Success(c, fiber.Map{
+		"chat": jid,
+		"read": read,
+	})
+}
+
+// resolveCursor turns a "before"/"after" value into a cursor.
+// The value is either a timestamp or the ID of a message stored for the chat.
+func (h *ChatHandler) resolveCursor(c *fiber.Ctx, instance *entity.Instance, jid, value string) (*repository.MessageCursor, error) {
+	if ts, err := parseTimeParam(value); err == nil {
+		return &repository.MessageCursor{Timestamp: ts}, nil
+	}
+
+	message, err := h.messageRepo.GetByMessageID(c.Context(), instance.ID, value)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get cursor message")
+		return nil, response.InternalServerError(c, "Failed to get chat messages")
+	}
+	if message == nil || message.RemoteJID != jid {
+		return nil, response.BadRequest(c, "Cursor message not found in this chat")
+	}
+
+	return &repository.MessageCursor{Timestamp: message.Timestamp, MessageID: message.MessageID}, nil
+}
+
+// chatJIDParam normalizes the chat JID of a route, accepting phone numbers and full JIDs
+func chatJIDParam(value string) string {
+	if jid, valid := validator.JID(value); valid {
+		return jid
+	}
+
+	jid, err := types.ParseJID(value)
+	if err != nil || jid.Server == "" || jid.User == "" {
+		return ""
+	}
+	return jid.ToNonAD().String()
+}
+
+// parseTimeParam parses an RFC3339 or unix seconds timestamp
+func parseTimeParam(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -11,21 +13,24 @@ import (
 	"github.com/jonadableite/turbozap-api/internal/interface/response"
 	"github.com/jonadableite/turbozap-api/pkg/validator"
 	"github.com/sirupsen/logrus"
+	"go.mau.fi/whatsmeow"
 )
 
 // InstanceHandler handles instance-related requests
 type InstanceHandler struct {
-	instanceRepo repository.InstanceRepository
-	waManager    *whatsapp.Manager
-	logger       *logrus.Logger
+	instanceRepo  repository.InstanceRepository
+	connEventRepo repository.ConnectionEventRepository
+	waManager     *whatsapp.Manager
+	logger        *logrus.Logger
 }
 
 // NewInstanceHandler creates a new instance handler
-func NewInstanceHandler(instanceRepo repository.InstanceRepository, waManager *whatsapp.Manager, logger *logrus.Logger) *InstanceHandler {
+func NewInstanceHandler(instanceRepo repository.InstanceRepository, connEventRepo repository.ConnectionEventRepository, waManager *whatsapp.Manager, logger *logrus.Logger) *InstanceHandler {
 	return &InstanceHandler{
-		instanceRepo: instanceRepo,
-		waManager:    waManager,
-		logger:       logger,
+		instanceRepo:  instanceRepo,
+		connEventRepo: connEventRepo,
+		waManager:     waManager,
+		logger:        logger,
 	}
 }
 
@@ -40,6 +45,9 @@ func (h *InstanceHandler) Create(c *fiber.Ctx) error {
 	if !validator.InstanceName(req.Name) {
 		return response.BadRequest(c, "Invalid instance name. Use only alphanumeric, underscore, and hyphen characters")
 	}
+	if req.Settings != nil && req.Settings.HistorySyncDays != nil && *req.Settings.HistorySyncDays < 0 {
+		return response.BadRequest(c, "history_sync_days must not be negative")
+	}
 
 	// Check if instance already exists
 	exists, err := h.instanceRepo.Exists(c.Context(), req.Name)
@@ -53,6 +61,9 @@ func (h *InstanceHandler) Create(c *fiber.Ctx) error {
 
 	// Create new instance
 	instance := entity.NewInstance(req.Name)
+	if req.Settings != nil {
+		instance.Settings = req.Settings.Apply(instance.Settings)
+	}
 	if userID, _ := c.Locals("userID").(string); userID != "" {
 		instance.UserID = userID
 	}
@@ -250,7 +261,17 @@ func (h *InstanceHandler) GetStatus(c *fiber.Ctx) error {
 		instance.SetConnected(phone, profileName, profilePic)
 	}
 
-	return response.Success(c, dto.ToInstanceStatusResponse(instance))
+	status := dto.ToInstanceStatusResponse(instance)
+	if state, reconnecting := h.waManager.ReconnectState(instance.ID); reconnecting {
+		status.Status = string(entity.InstanceStatusReconnecting)
+		status.ReconnectAttempt = state.Attempt
+		status.ReconnectMaxAttempts = state.MaxAttempts
+		if !state.NextAttempt.IsZero() {
+			status.NextReconnectAt = &state.NextAttempt
+		}
+	}
+
+	return response.Success(c, status)
 }
 
 // GetQRCode gets the QR code for an instance
@@ -326,6 +347,64 @@ func (h *InstanceHandler) GetQRCode(c *fiber.Ctx) error {
 	})
 }
 
+// PairCode requests a pairing code to link an instance by phone number instead of scanning the QR code
+func (h *InstanceHandler) PairCode(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if name == "" {
+		return response.BadRequest(c, "Instance name is required")
+	}
+
+	var req dto.PairCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+	if req.Phone == "" {
+		return response.BadRequest(c, "Phone number is required")
+	}
+
+	instance, err := h.instanceRepo.GetByName(c.Context(), name)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get instance")
+		return response.InternalServerError(c, "Failed to get pairing code")
+	}
+	if instance == nil {
+		return response.NotFound(c, "Instance not found")
+	}
+
+	if err := h.authorizeInstanceAccess(c, instance); err != nil {
+		return err
+	}
+
+	if _, exists := h.waManager.GetClient(instance.ID); !exists {
+		if _, err := h.waManager.CreateClient(instance); err != nil {
+			h.logger.WithError(err).Error("Failed to create WhatsApp client")
+			return response.InternalServerError(c, "Failed to create WhatsApp client")
+		}
+	}
+
+	if h.waManager.IsLoggedIn(instance.ID) {
+		return response.Success(c, dto.PairCodeResponse{
+			Name:   instance.Name,
+			Status: "connected",
+		})
+	}
+
+	code, err := h.waManager.PairPhone(c.Context(), instance.ID, req.Phone)
+	if err != nil {
+		if errors.Is(err, whatsmeow.ErrPhoneNumberTooShort) || errors.Is(err, whatsmeow.ErrPhoneNumberIsNotInternational) {
+			return response.BadRequest(c, "Invalid phone number, use the international format with country code")
+		}
+		h.logger.WithError(err).Error("Failed to get pairing code")
+		return response.InternalServerError(c, "Failed to get pairing code")
+	}
+
+	return response.Success(c, dto.PairCodeResponse{
+		Name:        instance.Name,
+		Status:      "pairing",
+		PairingCode: code,
+	})
+}
+
 // Connect connects an instance to WhatsApp
 func (h *InstanceHandler) Connect(c *fiber.Ctx) error {
 	name := c.Params("name")
@@ -543,3 +622,338 @@ func (h *InstanceHandler) UpdateName(c *fiber.Ctx) error {
 		Message: "Instance name updated successfully",
 	})
 }
+
+// GetSettings gets the settings of an instance
+func (h *InstanceHandler) GetSettings(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if name == "" {
+		return response.BadRequest(c, "Instance name is required")
+	}
+
+	instance, err := h.instanceRepo.GetByName(c.Context(), name)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get instance")
+		return response.InternalServerError(c, "Failed to get instance settings")
+	}
+	if instance == nil {
+		return response.NotFound(c, "Instance not found")
+	}
+
+	if err := h.authorizeInstanceAccess(c, instance); err != nil {
+		return err
+	}
+
+	return response.Success(c, dto.InstanceSettingsResponse{
+		Name:     instance.Name,
+		Settings: instance.Settings,
+	})
+}
+
+// UpdateSettings updates the settings of an instance
+func (h *InstanceHandler) UpdateSettings(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if name == "" {
+		return response.BadRequest(c, "Instance name is required")
+	}
+
+	var req dto.UpdateInstanceSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+	if req.HistorySyncDays != nil && *req.HistorySyncDays < 0 {
+		return response.BadRequest(c, "history_sync_days must not be negative")
+	}
+
+	instance, err := h.instanceRepo.GetByName(c.Context(), name)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get instance")
+		return response.InternalServerError(c, "Failed to update instance settings")
+	}
+	if instance == nil {
+		return response.NotFound(c, "Instance not found")
+	}
+
+	if err := h.authorizeInstanceAccess(c, instance); err != nil {
+		return err
+	}
+
+	settings := req.Apply(instance.Settings)
+	if err := h.instanceRepo.UpdateSettings(c.Context(), instance.ID, settings); err != nil {
+		h.logger.WithError(err).Error("Failed to update instance settings")
+		return response.InternalServerError(c, "Failed to update instance settings")
+	}
+
+	h.waManager.ApplySettings(instance.ID, settings)
+
+	h.logger.WithFields(logrus.Fields{
+		"instance": instance.Name,
+	}).Info("Instance settings updated")
+
+	return response.Success(c, dto.InstanceSettingsResponse{
+		Name:     instance.Name,
+		Settings: settings,
+	})
+}
+
+// getInstanceByName gets the instance named in the path and checks access to it
+func (h *InstanceHandler) getInstanceByName(c *fiber.Ctx, failMessage string) (*entity.Instance, error) {
+	name := c.Params("name")
+	if name == "" {
+		return nil, response.BadRequest(c, "Instance name is required")
+	}
+
+	instance, err := h.instanceRepo.GetByName(c.Context(), name)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get instance")
+		return nil, response.InternalServerError(c, failMessage)
+	}
+	if instance == nil {
+		return nil, response.NotFound(c, "Instance not found")
+	}
+
+	if err := h.authorizeInstanceAccess(c, instance); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// ExportSession exports the device store of an instance as an encrypted bundle
+func (h *InstanceHandler) ExportSession(c *fiber.Ctx) error {
+	var req dto.ExportSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+	if len(req.Passphrase) < whatsapp.MinSessionPassphraseLength {
+		return response.BadRequest(c, fmt.Sprintf("Passphrase must have at least %d characters", whatsapp.MinSessionPassphraseLength))
+	}
+
+	instance, err := h.getInstanceByName(c, "Failed to export session")
+	if err != nil || instance == nil {
+		return err
+	}
+
+	if _, exists := h.waManager.GetClient(instance.ID); !exists {
+		if _, err := h.waManager.CreateClient(instance); err != nil {
+			h.logger.WithError(err).Error("Failed to create WhatsApp client")
+			return response.InternalServerError(c, "Failed to create WhatsApp client")
+		}
+	}
+
+	bundle, deviceJID, err := h.waManager.ExportSession(c.Context(), instance.ID, req.Passphrase)
+	if err != nil {
+		if errors.Is(err, whatsapp.ErrInstanceNotPaired) {
+			return response.BadRequest(c, "Instance is not paired")
+		}
+		h.logger.WithError(err).WithField("instance", instance.Name).Error("Failed to export session")
+		return response.InternalServerError(c, "Failed to export session")
+	}
+
+	return response.Success(c, dto.ExportSessionResponse{
+		Name:      instance.Name,
+		DeviceJID: deviceJID,
+		Bundle:    bundle,
+	})
+}
+
+// ImportSession restores an exported session on an instance and connects it
+func (h *InstanceHandler) ImportSession(c *fiber.Ctx) error {
+	var req dto.ImportSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+	if len(req.Bundle) == 0 || req.Passphrase == "" {
+		return response.BadRequest(c, "Bundle and passphrase are required")
+	}
+
+	instance, err := h.getInstanceByName(c, "Failed to import session")
+	if err != nil || instance == nil {
+		return err
+	}
+
+	if _, exists := h.waManager.GetClient(instance.ID); !exists {
+		if _, err := h.waManager.CreateClient(instance); err != nil {
+			h.logger.WithError(err).Error("Failed to create WhatsApp client")
+			return response.InternalServerError(c, "Failed to create WhatsApp client")
+		}
+	}
+
+	deviceJID, err := h.waManager.ImportSession(c.Context(), instance.ID, req.Bundle, req.Passphrase)
+	if err != nil {
+		if errors.Is(err, whatsapp.ErrInvalidSessionBundle) {
+			return response.BadRequest(c, err.Error())
+		}
+		if errors.Is(err, whatsapp.ErrSessionInUse) {
+			return response.Conflict(c, err.Error())
+		}
+		h.logger.WithError(err).WithField("instance", instance.Name).Error("Failed to import session")
+		if deviceJID == "" {
+			return response.InternalServerError(c, "Failed to import session")
+		}
+	}
+
+	status := "connecting"
+	if h.waManager.IsConnected(instance.ID) {
+		status = "connected"
+	} else if err != nil {
+		status = "disconnected"
+	}
+
+	return response.Success(c, dto.ImportSessionResponse{
+		Name:      instance.Name,
+		DeviceJID: deviceJID,
+		Status:    status,
+	})
+}
+
+// GetProxy gets the proxy of an instance
+func (h *InstanceHandler) GetProxy(c *fiber.Ctx) error {
+	instance, err := h.getInstanceByName(c, "Failed to get instance proxy")
+	if err != nil || instance == nil {
+		return err
+	}
+
+	return response.Success(c, dto.ProxyResponse{
+		Name:     instance.Name,
+		Enabled:  instance.ProxyURL != "",
+		ProxyURL: whatsapp.RedactProxyURL(instance.ProxyURL),
+	})
+}
+
+// SetProxy sets the proxy of an instance and reconnects it through the proxy
+func (h *InstanceHandler) SetProxy(c *fiber.Ctx) error {
+	var req dto.SetProxyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+	if _, err := whatsapp.ParseProxyURL(req.ProxyURL); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	instance, err := h.getInstanceByName(c, "Failed to set instance proxy")
+	if err != nil || instance == nil {
+		return err
+	}
+
+	return h.saveProxy(c, instance, req.ProxyURL)
+}
+
+// ClearProxy removes the proxy of an instance
+func (h *InstanceHandler) ClearProxy(c *fiber.Ctx) error {
+	instance, err := h.getInstanceByName(c, "Failed to clear instance proxy")
+	if err != nil || instance == nil {
+		return err
+	}
+
+	return h.saveProxy(c, instance, "")
+}
+
+// saveProxy stores the proxy of an instance and applies it to the running client
+func (h *InstanceHandler) saveProxy(c *fiber.Ctx, instance *entity.Instance, proxyURL string) error {
+	if err := h.instanceRepo.UpdateProxy(c.Context(), instance.ID, proxyURL); err != nil {
+		h.logger.WithError(err).Error("Failed to update instance proxy")
+		return response.InternalServerError(c, "Failed to update instance proxy")
+	}
+
+	if err := h.waManager.SetProxy(c.Context(), instance.ID, proxyURL); err != nil {
+		h.logger.WithError(err).WithField("instance", instance.Name).Warn("Failed to apply proxy to running instance")
+	}
+
+	return response.Success(c, dto.ProxyResponse{
+		Name:     instance.Name,
+		Enabled:  proxyURL != "",
+		ProxyURL: whatsapp.RedactProxyURL(proxyURL),
+	})
+}
+
+// TestProxy checks that WhatsApp can be reached through a proxy. It makes the server connect to an
+// arbitrary host, so it is only available to the global admin.
+func (h *InstanceHandler) TestProxy(c *fiber.Ctx) error {
+	var req dto.TestProxyRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body")
+		}
+	}
+
+	instance, err := h.getInstanceByName(c, "Failed to test instance proxy")
+	if err != nil || instance == nil {
+		return err
+	}
+
+	proxyURL := req.ProxyURL
+	if proxyURL == "" {
+		proxyURL = instance.ProxyURL
+	}
+	if proxyURL == "" {
+		return response.BadRequest(c, "Instance has no proxy configured")
+	}
+	if _, err := whatsapp.ParseProxyURL(proxyURL); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	result := dto.TestProxyResponse{ProxyURL: whatsapp.RedactProxyURL(proxyURL)}
+	latency, err := whatsapp.TestProxy(c.Context(), proxyURL)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Reachable = true
+		result.LatencyMs = latency.Milliseconds()
+	}
+
+	return response.Success(c, result)
+}
+
+// maxConnectionHistoryWindow bounds the window of the connection history endpoint
+const maxConnectionHistoryWindow = 90 * 24 * time.Hour
+
+// GetConnectionHistory gets the connection timeline and uptime of an instance over a window
+func (h *InstanceHandler) GetConnectionHistory(c *fiber.Ctx) error {
+	var query dto.ConnectionHistoryQuery
+	if err := c.QueryParser(&query); err != nil {
+		return response.BadRequest(c, "Invalid query parameters")
+	}
+
+	until := time.Now()
+	if query.Until != "" {
+		parsed, err := parseTimeParam(query.Until)
+		if err != nil {
+			return response.BadRequest(c, "Invalid until, use a unix timestamp or RFC3339")
+		}
+		if parsed.Before(until) {
+			until = parsed
+		}
+	}
+	since := until.Add(-24 * time.Hour)
+	if query.Since != "" {
+		parsed, err := parseTimeParam(query.Since)
+		if err != nil {
+			return response.BadRequest(c, "Invalid since, use a unix timestamp or RFC3339")
+		}
+		since = parsed
+	}
+	if !since.Before(until) {
+		return response.BadRequest(c, "since must be before until")
+	}
+	if until.Sub(since) > maxConnectionHistoryWindow {
+		return response.BadRequest(c, "The history window must not exceed 90 days")
+	}
+
+	instance, err := h.getInstanceByName(c, "Failed to get connection history")
+	if err != nil || instance == nil {
+		return err
+	}
+
+	initial, err := h.connEventRepo.GetLastBefore(c.Context(), instance.ID, since)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get connection history")
+		return response.InternalServerError(c, "Failed to get connection history")
+	}
+	events, err := h.connEventRepo.List(c.Context(), instance.ID, since, until)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get connection history")
+		return response.InternalServerError(c, "Failed to get connection history")
+	}
+
+	return response.Success(c, dto.ToConnectionHistoryResponse(instance.Name, initial, events, since, until))
+}
@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"bufio"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -14,32 +19,36 @@ import (
 	"github.com/jonadableite/turbozap-api/internal/application/dto"
 	"github.com/jonadableite/turbozap-api/internal/domain/entity"
 	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+	"github.com/jonadableite/turbozap-api/internal/infrastructure/storage"
 	"github.com/jonadableite/turbozap-api/internal/infrastructure/whatsapp"
 	"github.com/jonadableite/turbozap-api/internal/interface/response"
 	"github.com/jonadableite/turbozap-api/pkg/validator"
 	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
 )
 
 // MessageHandler handles message-related requests
 type MessageHandler struct {
 	instanceRepo repository.InstanceRepository
 	messageRepo  repository.MessageRepository
+	pollRepo     repository.PollRepository
 	waManager    *whatsapp.Manager
 	logger       *logrus.Logger
 }
 
 // NewMessageHandler creates a new message handler
-func NewMessageHandler(instanceRepo repository.InstanceRepository, messageRepo repository.MessageRepository, waManager *whatsapp.Manager, logger *logrus.Logger) *MessageHandler {
+func NewMessageHandler(instanceRepo repository.InstanceRepository, messageRepo repository.MessageRepository, pollRepo repository.PollRepository, waManager *whatsapp.Manager, logger *logrus.Logger) *MessageHandler {
 	return &MessageHandler{
 		instanceRepo: instanceRepo,
 		messageRepo:  messageRepo,
+		pollRepo:     pollRepo,
 		waManager:    waManager,
 		logger:       logger,
 	}
 }
 
-// getInstanceAndValidate gets instance and validates connection
-func (h *MessageHandler) getInstanceAndValidate(c *fiber.Ctx) (*entity.Instance, error) {
+// getInstance gets the instance and authorizes access without requiring a connection
+func (h *MessageHandler) getInstance(c *fiber.Ctx) (*entity.Instance, error) {
 	instanceName := c.Params("instance")
 	if instanceName == "" {
 		return nil, response.BadRequest(c, "Instance name is required")
@@ -58,6 +67,16 @@ func (h *MessageHandler) getInstanceAndValidate(c *fiber.Ctx) (*entity.Instance,
 		return nil, err
 	}
 
+	return instance, nil
+}
+
+// getInstanceAndValidate gets instance and validates connection
+func (h *MessageHandler) getInstanceAndValidate(c *fiber.Ctx) (*entity.Instance, error) {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return nil, err
+	}
+
 	// Check if connected
 	if !h.waManager.IsConnected(instance.ID) {
 		return nil, response.BadRequest(c, "Instance is not connected to WhatsApp")
@@ -94,6 +113,65 @@ func decodeBase64(data string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(data)
 }
 
+const (
+	// MaxMediaUploadSize is the largest file accepted in a multipart/form-data media upload
+	MaxMediaUploadSize = 100 * 1024 * 1024
+	// mediaUploadField is the multipart/form-data field carrying the file of a media message
+	mediaUploadField = "file"
+)
+
+// mediaUpload is a file received in a multipart/form-data request, read as a stream
+type mediaUpload struct {
+	io.Reader
+	file     multipart.File
+	fileName string
+	mimeType string
+}
+
+// Close closes the uploaded file
+func (u *mediaUpload) Close() error {
+	return u.file.Close()
+}
+
+// openMediaUpload opens the file of a multipart/form-data request, nil when the request has none.
+// Large files are spooled to disk while the form is parsed, so they are never fully held in memory.
+func openMediaUpload(c *fiber.Ctx) (*mediaUpload, error) {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		return nil, nil
+	}
+
+	header, err := c.FormFile(mediaUploadField)
+	if err != nil {
+		if errors.Is(err, fasthttp.ErrMissingFile) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid multipart upload: %w", err)
+	}
+	if header.Size > MaxMediaUploadSize {
+		return nil, fmt.Errorf("file is larger than %d MB", MaxMediaUploadSize>>20)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+
+	// Sniff the content type from the first bytes when the client didn't send a useful one
+	reader := bufio.NewReader(file)
+	mimeType := header.Header.Get(fiber.HeaderContentType)
+	if mimeType == "" || mimeType == fiber.MIMEOctetStream {
+		head, _ := reader.Peek(512)
+		mimeType = http.DetectContentType(head)
+	}
+
+	return &mediaUpload{
+		Reader:   reader,
+		file:     file,
+		fileName: filepath.Base(header.Filename),
+		mimeType: mimeType,
+	}, nil
+}
+
 // SendText sends a text message
 func (h *MessageHandler) SendText(c *fiber.Ctx) error {
 	instance, err := h.getInstanceAndValidate(c)
@@ -167,11 +245,26 @@ func (h *MessageHandler) SendMedia(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid recipient phone number")
 	}
 
+	upload, err := openMediaUpload(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
 	// Get media data
 	var mediaData []byte
 	var mimeType string
+	fileName := req.FileName
 
-	if req.Base64 != "" {
+	if upload != nil {
+		defer upload.Close()
+		mimeType = upload.mimeType
+		if req.MimeType != "" {
+			mimeType = req.MimeType
+		}
+		if fileName == "" {
+			fileName = upload.fileName
+		}
+	} else if req.Base64 != "" {
 		// Decode base64
 		data := req.Base64
 		if idx := strings.Index(data, ","); idx != -1 {
@@ -199,26 +292,30 @@ func (h *MessageHandler) SendMedia(c *fiber.Ctx) error {
 			mimeType = req.MimeType
 		}
 	} else {
-		return response.BadRequest(c, "Either media_url or base64 is required")
+		return response.BadRequest(c, "Either file, media_url or base64 is required")
 	}
 
 	if mimeType == "" {
 		mimeType = http.DetectContentType(mediaData)
 	}
 
+	media := whatsapp.MediaFromBytes(mediaData)
+	if upload != nil {
+		media = whatsapp.MediaFromReader(upload)
+	}
+
 	// Determine media type and send
 	var msgID string
 	switch {
 	case strings.HasPrefix(mimeType, "image/"):
-		msgID, err = h.waManager.SendImage(c.Context(), instance.ID, jid, mediaData, mimeType, req.Caption, req.QuoteID)
+		msgID, err = h.waManager.SendImage(c.Context(), instance.ID, jid, media, mimeType, req.Caption, req.QuoteID)
 	case strings.HasPrefix(mimeType, "video/"):
-		msgID, err = h.waManager.SendVideo(c.Context(), instance.ID, jid, mediaData, mimeType, req.Caption, req.QuoteID)
+		msgID, err = h.waManager.SendVideo(c.Context(), instance.ID, jid, media, mimeType, req.Caption, req.QuoteID)
 	default:
-		fileName := req.FileName
 		if fileName == "" {
 			fileName = "document"
 		}
-		msgID, err = h.waManager.SendDocument(c.Context(), instance.ID, jid, mediaData, mimeType, fileName, req.Caption, req.QuoteID)
+		msgID, err = h.waManager.SendDocument(c.Context(), instance.ID, jid, media, mimeType, fileName, req.Caption, req.QuoteID)
 	}
 
 	if err != nil {
@@ -251,11 +348,21 @@ func (h *MessageHandler) SendAudio(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid recipient phone number")
 	}
 
+	upload, err := openMediaUpload(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
 	// Get audio data
 	var audioData []byte
 	mimeType := "audio/ogg; codecs=opus"
 
-	if req.Base64 != "" {
+	if upload != nil {
+		defer upload.Close()
+		if strings.HasPrefix(upload.mimeType, "audio/") {
+			mimeType = upload.mimeType
+		}
+	} else if req.Base64 != "" {
 		data := req.Base64
 		if idx := strings.Index(data, ","); idx != -1 {
 			data = data[idx+1:]
@@ -279,10 +386,15 @@ func (h *MessageHandler) SendAudio(c *fiber.Ctx) error {
 			mimeType = ct
 		}
 	} else {
-		return response.BadRequest(c, "Either audio_url or base64 is required")
+		return response.BadRequest(c, "Either file, audio_url or base64 is required")
 	}
 
-	msgID, err := h.waManager.SendAudio(c.Context(), instance.ID, jid, audioData, mimeType, req.PTT, req.QuoteID)
+	media := whatsapp.MediaFromBytes(audioData)
+	if upload != nil {
+		media = whatsapp.MediaFromReader(upload)
+	}
+
+	msgID, err := h.waManager.SendAudio(c.Context(), instance.ID, jid, media, mimeType, req.PTT, req.QuoteID)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to send audio message")
 		return response.InternalServerError(c, "Failed to send audio")
@@ -313,11 +425,18 @@ func (h *MessageHandler) SendSticker(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid recipient phone number")
 	}
 
+	upload, err := openMediaUpload(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
 	// Get sticker data
 	var stickerData []byte
 	mimeType := "image/webp"
 
-	if req.Base64 != "" {
+	if upload != nil {
+		defer upload.Close()
+	} else if req.Base64 != "" {
 		data := req.Base64
 		if idx := strings.Index(data, ","); idx != -1 {
 			data = data[idx+1:]
@@ -338,10 +457,15 @@ func (h *MessageHandler) SendSticker(c *fiber.Ctx) error {
 			return response.BadRequest(c, "Failed to read sticker data")
 		}
 	} else {
-		return response.BadRequest(c, "Either sticker_url or base64 is required")
+		return response.BadRequest(c, "Either file, sticker_url or base64 is required")
 	}
 
-	msgID, err := h.waManager.SendSticker(c.Context(), instance.ID, jid, stickerData, mimeType)
+	media := whatsapp.MediaFromBytes(stickerData)
+	if upload != nil {
+		media = whatsapp.MediaFromReader(upload)
+	}
+
+	msgID, err := h.waManager.SendSticker(c.Context(), instance.ID, jid, media, mimeType)
 	if err != nil {
 		h.logger.WithError(err).Error("Failed to send sticker message")
 		return response.InternalServerError(c, "Failed to send sticker")
@@ -740,7 +864,7 @@ func (h *MessageHandler) SendCarousel(c *fiber.Ctx) error {
 				mimeType = "image/jpeg"
 			}
 
-			lastMsgID, _ = h.waManager.SendImage(c.Context(), instance.ID, jid, imageData, mimeType, caption, "")
+			lastMsgID, _ = h.waManager.SendImage(c.Context(), instance.ID, jid, whatsapp.MediaFromBytes(imageData), mimeType, caption, "")
 		}
 	}
 
@@ -769,6 +893,14 @@ func (h *MessageHandler) SendStory(c *fiber.Ctx) error {
 
 	var msgID string
 
+	upload, err := openMediaUpload(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	if upload != nil {
+		defer upload.Close()
+	}
+
 	if req.Text != "" {
 		// Text story
 		msgID, err = h.waManager.SendText(c.Context(), instance.ID, jid, req.Text, "", nil)
@@ -777,7 +909,11 @@ func (h *MessageHandler) SendStory(c *fiber.Ctx) error {
 		var mediaData []byte
 		mimeType := req.MimeType
 
-		if req.Base64 != "" {
+		if upload != nil {
+			if mimeType == "" {
+				mimeType = upload.mimeType
+			}
+		} else if req.Base64 != "" {
 			data := req.Base64
 			if idx := strings.Index(data, ","); idx != -1 {
 				data = data[idx+1:]
@@ -801,18 +937,23 @@ func (h *MessageHandler) SendStory(c *fiber.Ctx) error {
 				mimeType = resp.Header.Get("Content-Type")
 			}
 		} else {
-			return response.BadRequest(c, "Either text, media_url, or base64 is required")
+			return response.BadRequest(c, "Either text, file, media_url, or base64 is required")
 		}
 
 		if mimeType == "" {
 			mimeType = http.DetectContentType(mediaData)
 		}
 
+		media := whatsapp.MediaFromBytes(mediaData)
+		if upload != nil {
+			media = whatsapp.MediaFromReader(upload)
+		}
+
 		switch {
 		case strings.HasPrefix(mimeType, "image/"):
-			msgID, err = h.waManager.SendImage(c.Context(), instance.ID, jid, mediaData, mimeType, req.Caption, "")
+			msgID, err = h.waManager.SendImage(c.Context(), instance.ID, jid, media, mimeType, req.Caption, "")
 		case strings.HasPrefix(mimeType, "video/"):
-			msgID, err = h.waManager.SendVideo(c.Context(), instance.ID, jid, mediaData, mimeType, req.Caption, "")
+			msgID, err = h.waManager.SendVideo(c.Context(), instance.ID, jid, media, mimeType, req.Caption, "")
 		default:
 			return response.BadRequest(c, "Unsupported media type for story")
 		}
@@ -830,3 +971,312 @@ func (h *MessageHandler) SendStory(c *fiber.Ctx) error {
 		Timestamp: time.Now(),
 	})
 }
+
+// EditMessage edits the text or caption of a previously sent message
+func (h *MessageHandler) EditMessage(c *fiber.Ctx) error {
+	instance, err := h.getInstanceAndValidate(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	messageID := c.Params("messageId")
+	if messageID == "" {
+		return response.BadRequest(c, "Message ID is required")
+	}
+
+	var req dto.EditMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if req.Text == "" {
+		return response.BadRequest(c, "Text is required")
+	}
+
+	var stored *entity.Message
+	if h.messageRepo != nil {
+		stored, err = h.messageRepo.GetByMessageID(c.Context(), instance.ID, messageID)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to get message")
+			return response.InternalServerError(c, "Failed to get message")
+		}
+	}
+
+	if stored != nil && !stored.FromMe {
+		return response.BadRequest(c, "Only messages sent by this instance can be edited")
+	}
+
+	// Media messages have their caption edited, messages that are not stored are edited as text
+	msgType := entity.MessageTypeText
+	if stored != nil {
+		msgType = stored.Type
+	}
+	if msgType == entity.MessageTypeAudio || msgType == entity.MessageTypeSticker {
+		return response.BadRequest(c, fmt.Sprintf("%s messages have no text to edit", msgType))
+	}
+
+	// Resolve the chat from the request or fall back to the stored message
+	var jid string
+	if req.To != "" {
+		jid = formatJID(req.To)
+	} else if stored != nil {
+		jid = stored.RemoteJID
+	}
+	if jid == "" {
+		return response.BadRequest(c, "Recipient is required for messages that are not stored")
+	}
+
+	editID, err := h.waManager.EditMessage(c.Context(), instance.ID, jid, messageID, req.Text, msgType)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to edit message")
+		return response.InternalServerError(c, "Failed to edit message")
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"instance":   instance.Name,
+		"message_id": messageID,
+		"edit_id":    editID,
+	}).Info("Message edited")
+
+	return response.Success(c, dto.MessageResponse{
+		ID:        uuid.New(),
+		MessageID: messageID,
+		Status:    "edited",
+		Timestamp: time.Now(),
+	})
+}
+
+// DeleteMessage deletes a message for everyone (revoke) or only for the account
+func (h *MessageHandler) DeleteMessage(c *fiber.Ctx) error {
+	instance, err := h.getInstanceAndValidate(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	messageID := c.Params("messageId")
+	if messageID == "" {
+		return response.BadRequest(c, "Message ID is required")
+	}
+
+	var req dto.DeleteMessageRequest
+	if err := c.QueryParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid query parameters")
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body")
+		}
+	}
+
+	forEveryone := req.ForEveryone == nil || *req.ForEveryone
+
+	var stored *entity.Message
+	if h.messageRepo != nil {
+		stored, err = h.messageRepo.GetByMessageID(c.Context(), instance.ID, messageID)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to get message")
+			return response.InternalServerError(c, "Failed to get message")
+		}
+	}
+
+	// Resolve the chat from the request or fall back to the stored message
+	var jid string
+	if req.To != "" {
+		jid = formatJID(req.To)
+	} else if stored != nil {
+		jid = stored.RemoteJID
+	}
+	if jid == "" {
+		return response.BadRequest(c, "Recipient is required for messages that are not stored")
+	}
+
+	if !forEveryone {
+		if stored == nil {
+			return response.NotFound(c, "Message not found")
+		}
+		if err := h.waManager.DeleteMessageForMe(c.Context(), instance.ID, jid, stored); err != nil {
+			h.logger.WithError(err).Error("Failed to delete message")
+			return response.InternalServerError(c, "Failed to delete message")
+		}
+
+		return response.Success(c, dto.MessageResponse{
+			ID:        stored.ID,
+			MessageID: messageID,
+			Status:    "deleted",
+			Timestamp: time.Now(),
+		})
+	}
+
+	if stored != nil && stored.IsRevoked() {
+		return response.Conflict(c, "Message was already revoked")
+	}
+
+	// Revoking someone else's message is only allowed for group admins. The sender of a stored
+	// message is known, the participant is only required for messages that are not stored.
+	var sender string
+	fromMe := stored == nil || stored.FromMe
+	if !fromMe {
+		sender = stored.SenderJID
+	}
+	if req.Participant != "" && !h.waManager.IsOwnJID(instance.ID, formatJID(req.Participant)) {
+		sender = formatJID(req.Participant)
+		fromMe = false
+	}
+	if !fromMe {
+		if sender == "" {
+			return response.BadRequest(c, "Participant is required to revoke messages sent by others that are not stored")
+		}
+		if !strings.HasSuffix(jid, "@g.us") {
+			return response.Forbidden(c, "Only messages sent by this instance can be revoked in private chats")
+		}
+		isAdmin, err := h.waManager.IsGroupAdmin(c.Context(), instance.ID, jid)
+		if err != nil {
+			h.logger.WithError(err).Error("Failed to check group admin status")
+			return response.InternalServerError(c, "Failed to check group admin status")
+		}
+		if !isAdmin {
+			return response.Forbidden(c, "Instance must be a group admin to revoke messages sent by others")
+		}
+	}
+
+	revokeID, err := h.waManager.RevokeMessage(c.Context(), instance.ID, jid, sender, messageID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to revoke message")
+		return response.InternalServerError(c, "Failed to revoke message")
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"instance":   instance.Name,
+		"message_id": messageID,
+		"revoke_id":  revokeID,
+	}).Info("Message revoked")
+
+	return response.Success(c, dto.MessageResponse{
+		ID:        uuid.New(),
+		MessageID: messageID,
+		Status:    "revoked",
+		Timestamp: time.Now(),
+	})
+}
+
+// GetMedia streams the decrypted media of a stored message
+func (h *MessageHandler) GetMedia(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	messageID := c.Params("messageId")
+	if messageID == "" {
+		return response.BadRequest(c, "Message ID is required")
+	}
+
+	message, err := h.messageRepo.GetByMessageID(c.Context(), instance.ID, messageID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get message")
+		return response.InternalServerError(c, "Failed to get message")
+	}
+	if message == nil {
+		return response.NotFound(c, "Message not found")
+	}
+	if !message.IsMedia() {
+		return response.BadRequest(c, "Message has no media")
+	}
+
+	media, size, err := h.waManager.OpenMessageMedia(c.Context(), instance.ID, message)
+	if err != nil {
+		h.logger.WithError(err).WithFields(logrus.Fields{
+			"instance":   instance.Name,
+			"message_id": messageID,
+		}).Error("Failed to download message media")
+		return response.InternalServerError(c, "Failed to download media")
+	}
+
+	// The response closes the media once it is sent
+	var body io.Reader = media
+	contentType := message.MediaMimeType
+	if contentType == "" {
+		buffered := bufio.NewReaderSize(media, 512)
+		head, _ := buffered.Peek(512)
+		contentType = http.DetectContentType(head)
+		body = buffered
+	}
+
+	fileName := message.MediaFileName
+	if fileName == "" {
+		fileName = messageID + storage.ExtensionFromMimeType(contentType)
+	}
+
+	disposition := "inline"
+	if c.QueryBool("download") {
+		disposition = "attachment"
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": fileName}))
+	return c.SendStream(readCloser{body, media}, int(size))
+}
+
+// readCloser reads from a reader wrapping a stream and closes the stream
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// GetEditHistory returns the edit history of a message
+func (h *MessageHandler) GetEditHistory(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	messageID := c.Params("messageId")
+	if messageID == "" {
+		return response.BadRequest(c, "Message ID is required")
+	}
+
+	edits, err := h.messageRepo.GetEditHistory(c.Context(), instance.ID, messageID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get message edit history")
+		return response.InternalServerError(c, "Failed to get message edit history")
+	}
+	if edits == nil {
+		edits = []*entity.MessageEdit{}
+	}
+
+	return response.Success(c, fiber.Map{
+		"message_id": messageID,
+		"edits":      edits,
+		"total":      len(edits),
+	})
+}
+
+// GetPollResults returns the aggregated votes of a poll
+func (h *MessageHandler) GetPollResults(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	messageID := c.Params("messageId")
+	if messageID == "" {
+		return response.BadRequest(c, "Message ID is required")
+	}
+
+	poll, err := h.pollRepo.GetByMessageID(c.Context(), instance.ID, messageID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get poll")
+		return response.InternalServerError(c, "Failed to get poll results")
+	}
+	if poll == nil {
+		return response.NotFound(c, "Poll not found")
+	}
+
+	votes, err := h.pollRepo.GetVotes(c.Context(), instance.ID, messageID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get poll votes")
+		return response.InternalServerError(c, "Failed to get poll results")
+	}
+
+	return response.Success(c, dto.ToPollResultsResponse(poll, votes))
+}
@@ -1,28 +1,47 @@
 package handler
 
 import (
+	"fmt"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/jonadableite/turbozap-api/internal/application/dto"
 	"github.com/jonadableite/turbozap-api/internal/domain/entity"
 	"github.com/jonadableite/turbozap-api/internal/domain/repository"
+	infraWebhook "github.com/jonadableite/turbozap-api/internal/infrastructure/webhook"
 	"github.com/jonadableite/turbozap-api/internal/interface/response"
 	"github.com/jonadableite/turbozap-api/pkg/validator"
 	"github.com/sirupsen/logrus"
 )
 
+// minWebhookSecretLength is the minimum length of a secret set by the user
+const minWebhookSecretLength = 16
+
+var webhookSecretLengthMessage = fmt.Sprintf("Webhook secret must have at least %d characters", minWebhookSecretLength)
+
+// Page size of the delivery list
+const (
+	defaultDeliveryListLimit = 50
+	maxDeliveryListLimit     = 500
+)
+
 // WebhookHandler handles webhook-related requests
 type WebhookHandler struct {
-	instanceRepo repository.InstanceRepository
-	webhookRepo  repository.WebhookRepository
-	logger       *logrus.Logger
+	instanceRepo    repository.InstanceRepository
+	webhookRepo     repository.WebhookRepository
+	outboxRepo      repository.WebhookOutboxRepository
+	deliveryLogRepo repository.WebhookDeliveryLogRepository
+	logger          *logrus.Logger
 }
 
 // NewWebhookHandler creates a new webhook handler
-func NewWebhookHandler(instanceRepo repository.InstanceRepository, webhookRepo repository.WebhookRepository, logger *logrus.Logger) *WebhookHandler {
+func NewWebhookHandler(instanceRepo repository.InstanceRepository, webhookRepo repository.WebhookRepository, outboxRepo repository.WebhookOutboxRepository, deliveryLogRepo repository.WebhookDeliveryLogRepository, logger *logrus.Logger) *WebhookHandler {
 	return &WebhookHandler{
-		instanceRepo: instanceRepo,
-		webhookRepo:  webhookRepo,
-		logger:       logger,
+		instanceRepo:    instanceRepo,
+		webhookRepo:     webhookRepo,
+		outboxRepo:      outboxRepo,
+		deliveryLogRepo: deliveryLogRepo,
+		logger:          logger,
 	}
 }
 
@@ -56,29 +75,12 @@ func (h *WebhookHandler) SetWebhook(c *fiber.Ctx) error {
 	if !validator.URL(req.URL) {
 		return response.BadRequest(c, "Invalid webhook URL")
 	}
-
-	// Set default events if none specified
-	events := req.Events
-	if len(events) == 0 {
-		events = entity.AllWebhookEvents()
-	} else {
-		// Normalize events (handle aliases)
-		normalizedEvents := make([]entity.WebhookEvent, 0, len(events))
-		for _, e := range events {
-			// Check if it's a known alias
-			if e == "message.sent" {
-				normalizedEvents = append(normalizedEvents, entity.WebhookEventSendMessage)
-				continue
-			}
-			if e == "message.received" {
-				normalizedEvents = append(normalizedEvents, entity.WebhookEventMessagesUpsert)
-				continue
-			}
-			normalizedEvents = append(normalizedEvents, e)
-		}
-		events = normalizedEvents
+	if !validWebhookSecret(req.Secret) {
+		return response.BadRequest(c, webhookSecretLengthMessage)
 	}
 
+	events := normalizeWebhookEvents(req.Events)
+
 	// Set default enabled if not specified
 	enabled := true
 	if req.Enabled != nil {
@@ -96,6 +98,20 @@ func (h *WebhookHandler) SetWebhook(c *fiber.Ctx) error {
 		webhook.UseBase64 = *req.Base64
 	}
 
+	// Keep the secrets of the webhook being replaced
+	current, err := h.webhookRepo.GetByInstance(c.Context(), instance.ID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get webhook")
+		return response.InternalServerError(c, "Failed to get webhook")
+	}
+	if current != nil {
+		webhook.Secret = current.Secret
+		webhook.PreviousSecret = current.PreviousSecret
+	}
+	if req.Secret != nil {
+		webhook.SetSecret(*req.Secret)
+	}
+
 	if err := h.webhookRepo.Upsert(c.Context(), webhook); err != nil {
 		h.logger.WithError(err).Error("Failed to save webhook")
 		return response.InternalServerError(c, "Failed to save webhook configuration")
@@ -144,7 +160,7 @@ func (h *WebhookHandler) GetWebhook(c *fiber.Ctx) error {
 	})
 }
 
-// DeleteWebhook deletes the webhook configuration for an instance
+// DeleteWebhook deletes the first webhook of an instance, the others are managed under /hooks
 func (h *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
 	instanceName := c.Params("instance")
 	if instanceName == "" {
@@ -165,7 +181,17 @@ func (h *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
 		return err
 	}
 
-	if err := h.webhookRepo.DeleteByInstance(c.Context(), instance.ID); err != nil {
+	webhook, err := h.webhookRepo.GetByInstance(c.Context(), instance.ID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get webhook")
+		return response.InternalServerError(c, "Failed to get webhook")
+	}
+
+	if webhook == nil {
+		return response.NotFound(c, "No webhook configured for this instance")
+	}
+
+	if err := h.webhookRepo.Delete(c.Context(), webhook.ID); err != nil {
 		h.logger.WithError(err).Error("Failed to delete webhook")
 		return response.InternalServerError(c, "Failed to delete webhook configuration")
 	}
@@ -269,3 +295,459 @@ func (h *WebhookHandler) ListWebhookEvents(c *fiber.Ctx) error {
 
 	return response.Success(c, eventStrings)
 }
+
+// ListHooks lists all webhooks of an instance
+func (h *WebhookHandler) ListHooks(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	webhooks, err := h.webhookRepo.ListByInstance(c.Context(), instance.ID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list webhooks")
+		return response.InternalServerError(c, "Failed to list webhooks")
+	}
+
+	return response.Success(c, dto.ToListWebhooksResponse(webhooks))
+}
+
+// CreateHook adds a webhook to an instance
+func (h *WebhookHandler) CreateHook(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	var req dto.SetWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if !validator.URL(req.URL) {
+		return response.BadRequest(c, "Invalid webhook URL")
+	}
+	if !validWebhookSecret(req.Secret) {
+		return response.BadRequest(c, webhookSecretLengthMessage)
+	}
+
+	webhook := entity.NewWebhook(instance.ID, req.URL, normalizeWebhookEvents(req.Events))
+	if req.Headers != nil {
+		webhook.Headers = req.Headers
+	}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+	if req.ByEvents != nil {
+		webhook.WebhookByEvents = *req.ByEvents
+	}
+	if req.Base64 != nil {
+		webhook.UseBase64 = *req.Base64
+	}
+	if req.Secret != nil {
+		webhook.SetSecret(*req.Secret)
+	}
+
+	if err := h.webhookRepo.Create(c.Context(), webhook); err != nil {
+		h.logger.WithError(err).Error("Failed to create webhook")
+		return response.InternalServerError(c, "Failed to create webhook")
+	}
+
+	return response.Created(c, dto.ToGetWebhookResponse(webhook))
+}
+
+// GetHook gets a webhook of an instance
+func (h *WebhookHandler) GetHook(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	webhook, err := h.getHook(c, instance)
+	if err != nil || webhook == nil {
+		return err
+	}
+
+	return response.Success(c, dto.ToGetWebhookResponse(webhook))
+}
+
+// UpdateHook changes a webhook of an instance, omitted fields keep their current value
+func (h *WebhookHandler) UpdateHook(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	webhook, err := h.getHook(c, instance)
+	if err != nil || webhook == nil {
+		return err
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+	if !validWebhookSecret(req.Secret) {
+		return response.BadRequest(c, webhookSecretLengthMessage)
+	}
+
+	if req.URL != nil {
+		if !validator.URL(*req.URL) {
+			return response.BadRequest(c, "Invalid webhook URL")
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = normalizeWebhookEvents(req.Events)
+	}
+	if req.Headers != nil {
+		webhook.Headers = req.Headers
+	}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+	if req.ByEvents != nil {
+		webhook.WebhookByEvents = *req.ByEvents
+	}
+	if req.Base64 != nil {
+		webhook.UseBase64 = *req.Base64
+	}
+	if req.Secret != nil {
+		webhook.SetSecret(*req.Secret)
+	}
+
+	if err := h.webhookRepo.Update(c.Context(), webhook); err != nil {
+		h.logger.WithError(err).Error("Failed to update webhook")
+		return response.InternalServerError(c, "Failed to update webhook")
+	}
+
+	return response.Success(c, dto.ToGetWebhookResponse(webhook))
+}
+
+// DeleteHook removes a webhook from an instance
+func (h *WebhookHandler) DeleteHook(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	webhook, err := h.getHook(c, instance)
+	if err != nil || webhook == nil {
+		return err
+	}
+
+	if err := h.webhookRepo.Delete(c.Context(), webhook.ID); err != nil {
+		h.logger.WithError(err).Error("Failed to delete webhook")
+		return response.InternalServerError(c, "Failed to delete webhook")
+	}
+
+	return response.Success(c, fiber.Map{
+		"message": "Webhook deleted",
+	})
+}
+
+// RotateHookSecret generates a new signing secret for a webhook. The replaced secret keeps signing
+// deliveries until it is revoked, so receivers can switch over without rejecting any of them.
+func (h *WebhookHandler) RotateHookSecret(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	webhook, err := h.getHook(c, instance)
+	if err != nil || webhook == nil {
+		return err
+	}
+
+	secret, err := infraWebhook.GenerateSecret()
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to generate webhook secret")
+		return response.InternalServerError(c, "Failed to generate webhook secret")
+	}
+	webhook.SetSecret(secret)
+
+	if err := h.webhookRepo.Update(c.Context(), webhook); err != nil {
+		h.logger.WithError(err).Error("Failed to update webhook")
+		return response.InternalServerError(c, "Failed to update webhook")
+	}
+
+	return response.Success(c, dto.WebhookSecretResponse{
+		ID:     webhook.ID.String(),
+		Secret: secret,
+	})
+}
+
+// RevokePreviousHookSecret stops signing deliveries with the secret replaced by the last rotation
+func (h *WebhookHandler) RevokePreviousHookSecret(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	webhook, err := h.getHook(c, instance)
+	if err != nil || webhook == nil {
+		return err
+	}
+
+	webhook.PreviousSecret = ""
+	if err := h.webhookRepo.Update(c.Context(), webhook); err != nil {
+		h.logger.WithError(err).Error("Failed to update webhook")
+		return response.InternalServerError(c, "Failed to update webhook")
+	}
+
+	return response.Success(c, dto.ToGetWebhookResponse(webhook))
+}
+
+// ListDeliveries lists the deliveries to the webhooks of an instance, newest first
+func (h *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	var query dto.WebhookDeliveriesQuery
+	if err := c.QueryParser(&query); err != nil {
+		return response.BadRequest(c, "Invalid query parameters")
+	}
+
+	filter := repository.WebhookDeliveryFilter{
+		Event:  entity.WebhookEvent(query.Event),
+		Limit:  query.Limit,
+		Offset: query.Offset,
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultDeliveryListLimit
+	}
+	if filter.Limit > maxDeliveryListLimit {
+		filter.Limit = maxDeliveryListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	switch status := entity.WebhookDeliveryStatus(query.Status); status {
+	case "", entity.WebhookDeliveryPending, entity.WebhookDeliveryDelivered, entity.WebhookDeliveryDeadLetter:
+		filter.Status = status
+	default:
+		return response.BadRequest(c, "status must be pending, delivered or dead_letter")
+	}
+
+	if query.WebhookID != "" {
+		webhookID, err := uuid.Parse(query.WebhookID)
+		if err != nil {
+			return response.BadRequest(c, "Invalid webhook ID")
+		}
+		filter.WebhookID = &webhookID
+	}
+	if query.Since != "" {
+		if filter.Since, err = parseTimeParam(query.Since); err != nil {
+			return response.BadRequest(c, "since must be an RFC3339 or unix timestamp")
+		}
+	}
+	if query.Until != "" {
+		if filter.Until, err = parseTimeParam(query.Until); err != nil {
+			return response.BadRequest(c, "until must be an RFC3339 or unix timestamp")
+		}
+	}
+
+	deliveries, err := h.outboxRepo.List(c.Context(), instance.ID, filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list webhook deliveries")
+		return response.InternalServerError(c, "Failed to list webhook deliveries")
+	}
+
+	total, err := h.outboxRepo.Count(c.Context(), instance.ID, filter)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to count webhook deliveries")
+		return response.InternalServerError(c, "Failed to list webhook deliveries")
+	}
+
+	result := make([]dto.WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		result[i] = dto.ToWebhookDeliveryResponse(delivery)
+	}
+
+	return response.Success(c, dto.ListWebhookDeliveriesResponse{
+		Deliveries: result,
+		Total:      total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+	})
+}
+
+// GetDelivery gets a delivery of an instance with its payload and every attempt made at it
+func (h *WebhookHandler) GetDelivery(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	delivery, err := h.getDelivery(c, instance)
+	if err != nil || delivery == nil {
+		return err
+	}
+
+	logs, err := h.deliveryLogRepo.ListByDelivery(c.Context(), delivery.ID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to list webhook delivery attempts")
+		return response.InternalServerError(c, "Failed to get webhook delivery")
+	}
+
+	return response.Success(c, dto.ToWebhookDeliveryDetailResponse(delivery, logs))
+}
+
+// RedeliverDelivery queues a delivery to be sent again right away, with a fresh set of retries.
+// The event keeps its ID so receivers can tell it apart from a new one.
+func (h *WebhookHandler) RedeliverDelivery(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	delivery, err := h.getDelivery(c, instance)
+	if err != nil || delivery == nil {
+		return err
+	}
+
+	queued, err := h.outboxRepo.Redeliver(c.Context(), delivery.ID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to redeliver webhook delivery")
+		return response.InternalServerError(c, "Failed to redeliver webhook delivery")
+	}
+	if !queued {
+		return response.Conflict(c, "Webhook delivery is already pending")
+	}
+
+	return response.Success(c, fiber.Map{
+		"id":      delivery.ID.String(),
+		"message": "Webhook delivery queued",
+	})
+}
+
+// RedeliverDeadLetters queues every dead-letter delivery of an instance created since a given time
+// to be sent again, to recover the events lost while a webhook was down
+func (h *WebhookHandler) RedeliverDeadLetters(c *fiber.Ctx) error {
+	instance, err := h.getInstance(c)
+	if err != nil || instance == nil {
+		return err
+	}
+
+	var req dto.RedeliverWebhookDeliveriesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body")
+	}
+
+	if req.Since == "" {
+		return response.BadRequest(c, "since is required")
+	}
+	since, err := parseTimeParam(req.Since)
+	if err != nil {
+		return response.BadRequest(c, "since must be an RFC3339 or unix timestamp")
+	}
+
+	var webhookID *uuid.UUID
+	if req.WebhookID != "" {
+		id, err := uuid.Parse(req.WebhookID)
+		if err != nil {
+			return response.BadRequest(c, "Invalid webhook ID")
+		}
+		webhookID = &id
+	}
+
+	queued, err := h.outboxRepo.RedeliverDeadLetters(c.Context(), instance.ID, since, webhookID)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to redeliver webhook deliveries")
+		return response.InternalServerError(c, "Failed to redeliver webhook deliveries")
+	}
+
+	return response.Success(c, fiber.Map{
+		"queued":  queued,
+		"message": fmt.Sprintf("%d webhook deliveries queued", queued),
+	})
+}
+
+// getInstance gets the instance from the route and checks access to it
+func (h *WebhookHandler) getInstance(c *fiber.Ctx) (*entity.Instance, error) {
+	instanceName := c.Params("instance")
+	if instanceName == "" {
+		return nil, response.BadRequest(c, "Instance name is required")
+	}
+
+	instance, err := h.instanceRepo.GetByName(c.Context(), instanceName)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get instance")
+		return nil, response.InternalServerError(c, "Failed to get instance")
+	}
+	if instance == nil {
+		return nil, response.NotFound(c, "Instance not found")
+	}
+
+	if err := AuthorizeInstanceAccess(c, instance); err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// getHook gets the webhook from the route, making sure it belongs to the instance
+func (h *WebhookHandler) getHook(c *fiber.Ctx, instance *entity.Instance) (*entity.Webhook, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, response.BadRequest(c, "Invalid webhook ID")
+	}
+
+	webhook, err := h.webhookRepo.GetByID(c.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get webhook")
+		return nil, response.InternalServerError(c, "Failed to get webhook")
+	}
+	if webhook == nil || webhook.InstanceID != instance.ID {
+		return nil, response.NotFound(c, "Webhook not found")
+	}
+
+	return webhook, nil
+}
+
+// getDelivery gets the delivery from the route, making sure it went to a webhook of the instance
+func (h *WebhookHandler) getDelivery(c *fiber.Ctx, instance *entity.Instance) (*entity.WebhookDelivery, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, response.BadRequest(c, "Invalid delivery ID")
+	}
+
+	delivery, err := h.outboxRepo.GetByID(c.Context(), id)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to get webhook delivery")
+		return nil, response.InternalServerError(c, "Failed to get webhook delivery")
+	}
+	if delivery == nil || delivery.InstanceID != instance.ID || delivery.WebhookID == nil {
+		return nil, response.NotFound(c, "Webhook delivery not found")
+	}
+
+	return delivery, nil
+}
+
+// validWebhookSecret returns false if a secret is set but too short to be safe
+func validWebhookSecret(secret *string) bool {
+	return secret == nil || *secret == "" || len(*secret) >= minWebhookSecretLength
+}
+
+// normalizeWebhookEvents resolves event aliases, subscribing to all events when none is given
+func normalizeWebhookEvents(events []entity.WebhookEvent) []entity.WebhookEvent {
+	if len(events) == 0 {
+		return entity.AllWebhookEvents()
+	}
+
+	normalized := make([]entity.WebhookEvent, 0, len(events))
+	for _, e := range events {
+		switch e {
+		case "message.sent":
+			normalized = append(normalized, entity.WebhookEventSendMessage)
+		case "message.received":
+			normalized = append(normalized, entity.WebhookEventMessagesUpsert)
+		default:
+			normalized = append(normalized, e)
+		}
+	}
+	return normalized
+}
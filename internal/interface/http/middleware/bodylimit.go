@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jonadableite/turbozap-api/internal/interface/response"
+)
+
+// BodyLimit rejects requests whose body is too large. Request bodies are streamed, so the server
+// doesn't cap them by itself: multipart/form-data uploads get their own, larger limit than the
+// other bodies, and bodies of unknown length are read up to the limit before reaching a handler.
+func BodyLimit(limit, uploadLimit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		max := limit
+		multipart := strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm)
+		if multipart {
+			max = uploadLimit
+		}
+
+		req := c.Request()
+		if req.Header.ContentLength() > max {
+			return bodyTooLarge(c, max)
+		}
+
+		// Chunked bodies don't declare their length
+		if req.Header.ContentLength() < 0 && req.IsBodyStream() {
+			// Uploads are spooled to disk as they are parsed, they must declare their length
+			if multipart {
+				c.Context().SetConnectionClose()
+				return response.Error(c, fiber.StatusLengthRequired, "Content-Length is required for uploads")
+			}
+
+			body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(max)+1))
+			if err != nil {
+				c.Context().SetConnectionClose()
+				return response.BadRequest(c, "Failed to read request body")
+			}
+			if len(body) > max {
+				return bodyTooLarge(c, max)
+			}
+			req.SetBody(body)
+			req.Header.SetContentLength(len(body))
+		}
+
+		return c.Next()
+	}
+}
+
+// bodyTooLarge rejects a request, closing the connection since the rest of its body is not read
+func bodyTooLarge(c *fiber.Ctx, max int) error {
+	c.Context().SetConnectionClose()
+	return response.Error(c, fiber.StatusRequestEntityTooLarge, fmt.Sprintf("Request body is larger than %d MB", max>>20))
+}
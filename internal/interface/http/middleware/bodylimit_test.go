@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// chunked hides the length of a body so the request is sent with chunked transfer encoding
+type chunked struct {
+	io.Reader
+}
+
+func newBodyLimitApp(limit, uploadLimit int) *fiber.App {
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    limit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	app.Use(BodyLimit(limit, uploadLimit))
+	app.Post("/", func(c *fiber.Ctx) error {
+		return c.SendString(string(c.Body()))
+	})
+	return app
+}
+
+func TestBodyLimit(t *testing.T) {
+	const limit = 1024
+	const uploadLimit = 4096
+
+	tests := []struct {
+		name        string
+		body        io.Reader
+		contentType string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:       "declared body within the limit",
+			body:       strings.NewReader(`{"ok":true}`),
+			wantStatus: fiber.StatusOK,
+			wantBody:   `{"ok":true}`,
+		},
+		{
+			name:       "declared body over the limit",
+			body:       bytes.NewReader(make([]byte, limit+1)),
+			wantStatus: fiber.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "chunked body within the limit",
+			body:       chunked{strings.NewReader(`{"ok":true}`)},
+			wantStatus: fiber.StatusOK,
+			wantBody:   `{"ok":true}`,
+		},
+		{
+			name:       "chunked body exactly at the limit",
+			body:       chunked{strings.NewReader(strings.Repeat("a", limit))},
+			wantStatus: fiber.StatusOK,
+			wantBody:   strings.Repeat("a", limit),
+		},
+		{
+			name:       "chunked body over the limit",
+			body:       chunked{bytes.NewReader(make([]byte, 64*limit))},
+			wantStatus: fiber.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "declared upload over the body limit but within the upload limit",
+			body:        bytes.NewReader(make([]byte, 2*limit)),
+			contentType: fiber.MIMEMultipartForm + "; boundary=x",
+			wantStatus:  fiber.StatusOK,
+		},
+		{
+			name:        "declared upload over the upload limit",
+			body:        bytes.NewReader(make([]byte, uploadLimit+1)),
+			contentType: fiber.MIMEMultipartForm + "; boundary=x",
+			wantStatus:  fiber.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "chunked upload",
+			body:        chunked{bytes.NewReader(make([]byte, limit))},
+			contentType: fiber.MIMEMultipartForm + "; boundary=x",
+			wantStatus:  fiber.StatusLengthRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newBodyLimitApp(limit, uploadLimit)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/", tt.body)
+			if _, ok := tt.body.(chunked); ok {
+				req.ContentLength = 0
+				req.TransferEncoding = []string{"chunked"}
+			}
+			if tt.contentType != "" {
+				req.Header.Set(fiber.HeaderContentType, tt.contentType)
+			}
+
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantBody != "" {
+				body, _ := io.ReadAll(resp.Body)
+				if string(body) != tt.wantBody {
+					t.Errorf("body = %q, want %q", body, tt.wantBody)
+				}
+			}
+		})
+	}
+}
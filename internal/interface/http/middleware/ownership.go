@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/proxy"
+	"github.com/jonadableite/turbozap-api/internal/interface/response"
+	"github.com/sirupsen/logrus"
+)
+
+// forwardedHeader marks requests forwarded by another node so they are never forwarded twice
+const forwardedHeader = "X-TurboZap-Forwarded"
+
+// InstanceOwnerResolver finds the node owning an instance
+type InstanceOwnerResolver interface {
+	InstanceOwner(ctx context.Context, name string) (address string, local bool, err error)
+}
+
+// instanceRouteGroups are the route groups whose second path segment is an instance name
+var instanceRouteGroups = map[string]bool{
+	"instance": true,
+	"message":  true,
+	"chat":     true,
+	"group":    true,
+	"contact":  true,
+	"presence": true,
+	"profile":  true,
+	"call":     true,
+}
+
+// InstanceOwnership forwards requests for an instance owned by another node to that node. It runs after
+// authentication, so only authenticated requests reach the instance name lookup and the other nodes
+func InstanceOwnership(resolver InstanceOwnerResolver, nodeID string, logger *logrus.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := instanceFromPath(c.Path())
+		if name == "" {
+			return c.Next()
+		}
+
+		address, local, err := resolver.InstanceOwner(c.UserContext(), name)
+		if err != nil {
+			logger.WithError(err).WithField("instance", name).Warn("Failed to resolve instance owner")
+			return response.InternalServerError(c, "Failed to resolve instance owner")
+		}
+		if local {
+			return c.Next()
+		}
+
+		if address == "" || c.Get(forwardedHeader) != "" {
+			return response.ServiceUnavailable(c, "Instance is owned by another node that cannot be reached")
+		}
+
+		c.Request().Header.Set(forwardedHeader, nodeID)
+		if err := proxy.Do(c, address+c.OriginalURL()); err != nil {
+			logger.WithError(err).WithFields(logrus.Fields{
+				"instance": name,
+				"owner":    address,
+			}).Warn("Failed to forward request to instance owner")
+			return response.ServiceUnavailable(c, "Failed to reach the node owning the instance")
+		}
+		return nil
+	}
+}
+
+// instanceFromPath returns the instance name of a request path, empty when the route is not bound to an instance
+func instanceFromPath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 0 && segments[0] == "api" {
+		segments = segments[1:]
+	}
+	if len(segments) < 2 || !instanceRouteGroups[segments[0]] {
+		return ""
+	}
+	if segments[0] == "instance" && (segments[1] == "create" || segments[1] == "list") {
+		return ""
+	}
+	return segments[1]
+}
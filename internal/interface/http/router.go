@@ -14,6 +14,13 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// bodyLimit is the largest JSON body accepted, base64 media included
+	bodyLimit = 50 * 1024 * 1024
+	// multipartOverhead leaves room for the form fields and boundaries around an uploaded file
+	multipartOverhead = 1024 * 1024
+)
+
 // NewRouter creates a new Fiber router with all routes configured
 func NewRouter(
 	cfg *config.Config,
@@ -25,12 +32,14 @@ func NewRouter(
 ) *fiber.App {
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		AppName:       "TurboZap API",
-		ServerHeader:  "TurboZap",
-		CaseSensitive: true,
-		StrictRouting: false,
-		BodyLimit:     50 * 1024 * 1024, // 50MB for media uploads
-		ErrorHandler:  errorHandler,
+		AppName:                      "TurboZap API",
+		ServerHeader:                 "TurboZap",
+		CaseSensitive:                true,
+		StrictRouting:                false,
+		BodyLimit:                    bodyLimit,
+		StreamRequestBody:            true, // Multipart uploads are parsed from the stream, spooling files to disk
+		DisablePreParseMultipartForm: true, // Parsed once middleware.BodyLimit checked their size
+		ErrorHandler:                 errorHandler,
 	})
 
 	// Global middlewares
@@ -38,6 +47,7 @@ func NewRouter(
 	app.Use(compress.New())
 	app.Use(middleware.CORSMiddleware())
 	app.Use(middleware.LoggerMiddleware(logger))
+	app.Use(middleware.BodyLimit(bodyLimit, handler.MaxMediaUploadSize+multipartOverhead))
 
 	// Health check (public)
 	app.Get("/health", healthCheck)
@@ -46,24 +56,38 @@ func NewRouter(
 	// Initialize message repository for stats
 	messageRepo := infraRepo.NewMessagePostgresRepository(pool)
 	apiKeyRepo := infraRepo.NewApiKeyPostgresRepository(pool)
+	chatRepo := infraRepo.NewChatPostgresRepository(pool)
+	pollRepo := infraRepo.NewPollPostgresRepository(pool)
+	connEventRepo := infraRepo.NewConnectionEventPostgresRepository(pool)
+	webhookOutboxRepo := infraRepo.NewWebhookOutboxPostgresRepository(pool)
+	webhookDeliveryLogRepo := infraRepo.NewWebhookDeliveryLogPostgresRepository(pool)
 
 	// Create handlers
-	instanceHandler := handler.NewInstanceHandler(instanceRepo, waManager, logger)
-	messageHandler := handler.NewMessageHandler(instanceRepo, messageRepo, waManager, logger)
+	instanceHandler := handler.NewInstanceHandler(instanceRepo, connEventRepo, waManager, logger)
+	messageHandler := handler.NewMessageHandler(instanceRepo, messageRepo, pollRepo, waManager, logger)
 	apiKeyHandler := handler.NewApiKeyHandler(apiKeyRepo, logger)
 	groupHandler := handler.NewGroupHandler(instanceRepo, waManager, logger)
 	contactHandler := handler.NewContactHandler(instanceRepo, waManager, logger)
 	presenceHandler := handler.NewPresenceHandler(instanceRepo, waManager, logger)
-	webhookHandler := handler.NewWebhookHandler(instanceRepo, webhookRepo, logger)
+	webhookHandler := handler.NewWebhookHandler(instanceRepo, webhookRepo, webhookOutboxRepo, webhookDeliveryLogRepo, logger)
 	profileHandler := handler.NewProfileHandler(instanceRepo, waManager, logger)
 	statsHandler := handler.NewStatsHandler(messageRepo, instanceRepo, logger)
+	chatHandler := handler.NewChatHandler(instanceRepo, messageRepo, chatRepo, waManager, logger)
 
 	// Create SSE hub and handler
 	sseHub := handler.NewSSEHub(logger)
 	sseHandler := handler.NewSSEHandler(instanceRepo, logger, sseHub)
 
+	// Middlewares of the authenticated routes
+	authenticated := []fiber.Handler{middleware.AuthMiddleware(cfg, instanceRepo, apiKeyRepo)}
+
+	// Multi-node: requests for an instance are served by the node owning it, once authenticated
+	if cfg.Cluster.Enabled {
+		authenticated = append(authenticated, middleware.InstanceOwnership(waManager, cfg.Cluster.NodeID, logger))
+	}
+
 	// API routes (authenticated)
-	api := app.Group("/api", middleware.AuthMiddleware(cfg, instanceRepo, apiKeyRepo))
+	api := app.Group("/api", authenticated...)
 
 	// API Keys (user-owned)
 	apiKeys := api.Group("/user/apikeys")
@@ -78,12 +102,22 @@ func NewRouter(
 	instance.Get("/list", instanceHandler.List)
 	instance.Get("/:name", instanceHandler.Get)
 	instance.Get("/:name/status", instanceHandler.GetStatus)
+	instance.Get("/:name/history", instanceHandler.GetConnectionHistory)
 	instance.Get("/:name/qrcode", instanceHandler.GetQRCode)
+	instance.Post("/:name/pair-code", instanceHandler.PairCode)
 	instance.Post("/:name/connect", instanceHandler.Connect)
 	instance.Put("/:name/restart", instanceHandler.Restart)
 	instance.Post("/:name/logout", instanceHandler.Logout)
 	instance.Delete("/:name", instanceHandler.Delete)
 	instance.Put("/:name/name", instanceHandler.UpdateName) // Update instance name
+	instance.Get("/:name/settings", instanceHandler.GetSettings)
+	instance.Put("/:name/settings", instanceHandler.UpdateSettings)
+	instance.Get("/:name/proxy", instanceHandler.GetProxy)
+	instance.Put("/:name/proxy", instanceHandler.SetProxy)
+	instance.Post("/:name/proxy/test", middleware.GlobalAdminMiddleware(cfg), instanceHandler.TestProxy)
+	instance.Delete("/:name/proxy", instanceHandler.ClearProxy)
+	instance.Post("/:name/session/export", middleware.GlobalAdminMiddleware(cfg), instanceHandler.ExportSession)
+	instance.Post("/:name/session/import", middleware.GlobalAdminMiddleware(cfg), instanceHandler.ImportSession)
 
 	// Message routes
 	message := api.Group("/message/:instance")
@@ -99,6 +133,24 @@ func NewRouter(
 	message.Post("/list", messageHandler.SendList)
 	message.Post("/carousel", messageHandler.SendCarousel)
 	message.Post("/story", messageHandler.SendStory)
+	message.Put("/:messageId", messageHandler.EditMessage)
+	message.Delete("/:messageId", messageHandler.DeleteMessage)
+	message.Get("/:messageId/edits", messageHandler.GetEditHistory)
+	message.Get("/:messageId/media", messageHandler.GetMedia)
+	message.Get("/poll/:messageId/results", messageHandler.GetPollResults)
+
+	// Chat routes
+	chat := api.Group("/chat/:instance")
+	chat.Get("/list", chatHandler.ListChats)
+	chat.Get("/:jid/messages", chatHandler.GetMessages)
+	chat.Post("/:jid/archive", chatHandler.ArchiveChat)
+	chat.Post("/:jid/unarchive", chatHandler.UnarchiveChat)
+	chat.Post("/:jid/pin", chatHandler.PinChat)
+	chat.Post("/:jid/unpin", chatHandler.UnpinChat)
+	chat.Post("/:jid/mute", chatHandler.MuteChat)
+	chat.Post("/:jid/unmute", chatHandler.UnmuteChat)
+	chat.Post("/:jid/read", chatHandler.MarkChatRead)
+	chat.Post("/:jid/unread", chatHandler.MarkChatUnread)
 
 	// Group routes
 	group := api.Group("/group/:instance")
@@ -146,6 +198,17 @@ func NewRouter(
 	webhook.Delete("/", webhookHandler.DeleteWebhook)
 	webhook.Post("/enable", webhookHandler.EnableWebhook)
 	webhook.Post("/disable", webhookHandler.DisableWebhook)
+	webhook.Get("/hooks", webhookHandler.ListHooks)
+	webhook.Post("/hooks", webhookHandler.CreateHook)
+	webhook.Get("/hooks/:id", webhookHandler.GetHook)
+	webhook.Put("/hooks/:id", webhookHandler.UpdateHook)
+	webhook.Delete("/hooks/:id", webhookHandler.DeleteHook)
+	webhook.Post("/hooks/:id/secret/rotate", webhookHandler.RotateHookSecret)
+	webhook.Delete("/hooks/:id/secret/previous", webhookHandler.RevokePreviousHookSecret)
+	webhook.Get("/deliveries", webhookHandler.ListDeliveries)
+	webhook.Post("/deliveries/redeliver", webhookHandler.RedeliverDeadLetters)
+	webhook.Get("/deliveries/:id", webhookHandler.GetDelivery)
+	webhook.Post("/deliveries/:id/redeliver", webhookHandler.RedeliverDelivery)
 
 	// Webhook events list (public info)
 	api.Get("/webhook/events", webhookHandler.ListWebhookEvents)
@@ -161,20 +224,30 @@ func NewRouter(
 	stats.Get("/messages", statsHandler.GetMessageStats) // Get message statistics
 
 	// Legacy routes (without /api prefix) for backwards compatibility and easier manual testing
-	legacy := app.Group("/instance", middleware.AuthMiddleware(cfg, instanceRepo, apiKeyRepo))
+	legacy := app.Group("/instance", authenticated...)
 	legacy.Post("/create", instanceHandler.Create)
 	legacy.Get("/list", instanceHandler.List)
 	legacy.Get("/:name", instanceHandler.Get)
 	legacy.Get("/:name/status", instanceHandler.GetStatus)
+	legacy.Get("/:name/history", instanceHandler.GetConnectionHistory)
 	legacy.Get("/:name/qrcode", instanceHandler.GetQRCode)
+	legacy.Post("/:name/pair-code", instanceHandler.PairCode)
 	legacy.Post("/:name/connect", instanceHandler.Connect)
 	legacy.Put("/:name/restart", instanceHandler.Restart)
 	legacy.Post("/:name/logout", instanceHandler.Logout)
 	legacy.Delete("/:name", instanceHandler.Delete)
 	legacy.Put("/:name/name", instanceHandler.UpdateName)
+	legacy.Get("/:name/settings", instanceHandler.GetSettings)
+	legacy.Put("/:name/settings", instanceHandler.UpdateSettings)
+	legacy.Get("/:name/proxy", instanceHandler.GetProxy)
+	legacy.Put("/:name/proxy", instanceHandler.SetProxy)
+	legacy.Post("/:name/proxy/test", middleware.GlobalAdminMiddleware(cfg), instanceHandler.TestProxy)
+	legacy.Delete("/:name/proxy", instanceHandler.ClearProxy)
+	legacy.Post("/:name/session/export", middleware.GlobalAdminMiddleware(cfg), instanceHandler.ExportSession)
+	legacy.Post("/:name/session/import", middleware.GlobalAdminMiddleware(cfg), instanceHandler.ImportSession)
 
 	// Legacy message routes (without /api prefix)
-	legacyMessage := app.Group("/message/:instance", middleware.AuthMiddleware(cfg, instanceRepo, apiKeyRepo))
+	legacyMessage := app.Group("/message/:instance", authenticated...)
 	legacyMessage.Post("/text", messageHandler.SendText)
 	legacyMessage.Post("/media", messageHandler.SendMedia)
 	legacyMessage.Post("/audio", messageHandler.SendAudio)
@@ -187,24 +260,42 @@ func NewRouter(
 	legacyMessage.Post("/list", messageHandler.SendList)
 	legacyMessage.Post("/carousel", messageHandler.SendCarousel)
 	legacyMessage.Post("/story", messageHandler.SendStory)
+	legacyMessage.Put("/:messageId", messageHandler.EditMessage)
+	legacyMessage.Delete("/:messageId", messageHandler.DeleteMessage)
+	legacyMessage.Get("/:messageId/edits", messageHandler.GetEditHistory)
+	legacyMessage.Get("/:messageId/media", messageHandler.GetMedia)
+	legacyMessage.Get("/poll/:messageId/results", messageHandler.GetPollResults)
+
+	// Legacy chat routes (without /api prefix)
+	legacyChat := app.Group("/chat/:instance", authenticated...)
+	legacyChat.Get("/list", chatHandler.ListChats)
+	legacyChat.Get("/:jid/messages", chatHandler.GetMessages)
+	legacyChat.Post("/:jid/archive", chatHandler.ArchiveChat)
+	legacyChat.Post("/:jid/unarchive", chatHandler.UnarchiveChat)
+	legacyChat.Post("/:jid/pin", chatHandler.PinChat)
+	legacyChat.Post("/:jid/unpin", chatHandler.UnpinChat)
+	legacyChat.Post("/:jid/mute", chatHandler.MuteChat)
+	legacyChat.Post("/:jid/unmute", chatHandler.UnmuteChat)
+	legacyChat.Post("/:jid/read", chatHandler.MarkChatRead)
+	legacyChat.Post("/:jid/unread", chatHandler.MarkChatUnread)
 
 	// Legacy profile routes (without /api prefix)
-	legacyProfile := app.Group("/profile/:instance", middleware.AuthMiddleware(cfg, instanceRepo, apiKeyRepo))
+	legacyProfile := app.Group("/profile/:instance", authenticated...)
 	legacyProfile.Get("/privacy", profileHandler.GetPrivacySettings)
 	legacyProfile.Post("/privacy", profileHandler.SetPrivacySetting)
 	legacyProfile.Post("/status", profileHandler.SetProfileStatus)
 
 	// Legacy call routes (without /api prefix)
-	legacyCall := app.Group("/call/:instance", middleware.AuthMiddleware(cfg, instanceRepo, apiKeyRepo))
+	legacyCall := app.Group("/call/:instance", authenticated...)
 	legacyCall.Post("/reject", profileHandler.RejectCall)
 
 	// Legacy SSE routes (without /api prefix)
-	legacySSE := app.Group("/sse", middleware.AuthMiddleware(cfg, instanceRepo, apiKeyRepo))
+	legacySSE := app.Group("/sse", authenticated...)
 	legacySSE.Get("/:instance", sseHandler.Stream)
 	legacySSE.Get("/", sseHandler.StreamAll)
 
 	// Legacy stats routes (without /api prefix)
-	legacyStats := app.Group("/stats", middleware.AuthMiddleware(cfg, instanceRepo, apiKeyRepo))
+	legacyStats := app.Group("/stats", authenticated...)
 	legacyStats.Get("/messages", statsHandler.GetMessageStats)
 
 	return app
@@ -19,6 +19,7 @@ type Config struct {
 	RabbitMQ RabbitMQConfig
 	Redis    RedisConfig
 	MinIO    MinIOConfig
+	Cluster  ClusterConfig
 }
 
 // AppConfig holds general application metadata
@@ -46,9 +47,23 @@ type DatabaseConfig struct {
 
 // WhatsAppConfig holds WhatsApp-related configuration
 type WhatsAppConfig struct {
-	Debug             bool
-	AutoReconnect     bool
-	ReconnectInterval int
+	Debug                bool
+	AutoReconnect        bool
+	ReconnectInterval    int    // Base delay in seconds, doubled on every failed attempt
+	ReconnectMaxInterval int    // Upper bound of the delay between attempts in seconds
+	ReconnectMaxAttempts int    // Attempts before giving up (0 = never give up)
+	WatchdogInterval     int    // Seconds between connection health checks (0 = disabled)
+	KeepAliveTimeout     int    // Seconds of failing keepalives before a connection is considered dead
+	StoreDriver          string // Device store backend: postgres or sqlite
+	StoreURL             string // Postgres URL or SQLite file of the device store, the main database when empty
+}
+
+// ClusterConfig holds multi-node configuration
+type ClusterConfig struct {
+	Enabled      bool   // Share instances between several API nodes using leases
+	NodeID       string // Unique name of this node
+	AdvertiseURL string // Base URL other nodes use to forward requests to this node
+	LeaseTTL     int    // Seconds an instance lease stays valid without being renewed
 }
 
 // WebhookConfig holds webhook-related configuration
@@ -60,6 +75,14 @@ type WebhookConfig struct {
 	GlobalWebhookByEvents bool
 	GlobalBase64          bool
 	GlobalEvents          map[string]bool
+	GlobalSecret          string // Signs global webhook deliveries, empty disables signing
+	GlobalPreviousSecret  string // Secret being rotated out, still signs deliveries
+	Workers               int    // Workers delivering the outbox on this node
+	DeliveryRetention     int    // Hours delivered events stay in the outbox (0 = forever)
+	DeadLetterRetention   int    // Hours dead-lettered events stay in the outbox after they were created (0 = forever)
+	BreakerThreshold      int    // Consecutive failures opening the circuit of a URL (0 = no circuit breaker)
+	BreakerProbeInterval  int    // Seconds between probe deliveries while a circuit is open
+	DisableAfter          int    // Minutes the circuit of a webhook can stay open before it is disabled (0 = never)
 }
 
 // LogConfig holds logging-related configuration
@@ -91,11 +114,14 @@ type RedisConfig struct {
 
 // MinIOConfig holds MinIO-related configuration
 type MinIOConfig struct {
-	Endpoint        string
-	AccessKeyID     string
-	SecretAccessKey string
-	BucketName      string
-	UseSSL          bool
+	Enabled            bool
+	Endpoint           string
+	AccessKeyID        string
+	SecretAccessKey    string
+	BucketName         string
+	UseSSL             bool
+	PublicURL          string
+	PresignedURLExpiry int // seconds
 }
 
 // Load loads configuration from environment variables
@@ -122,9 +148,15 @@ func Load() (*Config, error) {
 			Name:     getEnv("DATABASE_NAME", "turbozap"),
 		},
 		WhatsApp: WhatsAppConfig{
-			Debug:             getEnvBool("WHATSAPP_DEBUG", false),
-			AutoReconnect:     getEnvBool("WHATSAPP_AUTO_RECONNECT", true),
-			ReconnectInterval: getEnvInt("WHATSAPP_RECONNECT_INTERVAL", 5),
+			Debug:                getEnvBool("WHATSAPP_DEBUG", false),
+			AutoReconnect:        getEnvBool("WHATSAPP_AUTO_RECONNECT", true),
+			ReconnectInterval:    getEnvInt("WHATSAPP_RECONNECT_INTERVAL", 5),
+			ReconnectMaxInterval: getEnvInt("WHATSAPP_RECONNECT_MAX_INTERVAL", 300),
+			ReconnectMaxAttempts: getEnvInt("WHATSAPP_RECONNECT_MAX_ATTEMPTS", 20),
+			WatchdogInterval:     getEnvInt("WHATSAPP_WATCHDOG_INTERVAL", 30),
+			KeepAliveTimeout:     getEnvInt("WHATSAPP_KEEPALIVE_TIMEOUT", 90),
+			StoreDriver:          getEnv("WHATSAPP_STORE_DRIVER", "postgres"),
+			StoreURL:             getEnv("WHATSAPP_STORE_URL", ""),
 		},
 		Webhook: WebhookConfig{
 			Timeout:               getEnvInt("WEBHOOK_TIMEOUT", 30),
@@ -134,6 +166,14 @@ func Load() (*Config, error) {
 			GlobalWebhookByEvents: getEnvBool("WEBHOOK_GLOBAL_WEBHOOK_BY_EVENTS", false),
 			GlobalBase64:          getEnvBool("WEBHOOK_GLOBAL_BASE64", false),
 			GlobalEvents:          loadWebhookEventToggles(),
+			GlobalSecret:          getEnv("WEBHOOK_GLOBAL_SECRET", ""),
+			GlobalPreviousSecret:  getEnv("WEBHOOK_GLOBAL_PREVIOUS_SECRET", ""),
+			Workers:               getEnvInt("WEBHOOK_WORKERS", 4),
+			DeliveryRetention:     getEnvInt("WEBHOOK_DELIVERY_RETENTION", 168),
+			DeadLetterRetention:   getEnvInt("WEBHOOK_DEAD_LETTER_RETENTION", 720),
+			BreakerThreshold:      getEnvInt("WEBHOOK_BREAKER_THRESHOLD", 5),
+			BreakerProbeInterval:  getEnvInt("WEBHOOK_BREAKER_PROBE_INTERVAL", 30),
+			DisableAfter:          getEnvInt("WEBHOOK_DISABLE_AFTER", 60),
 		},
 		Log: LogConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
@@ -157,11 +197,20 @@ func Load() (*Config, error) {
 			RateLimitRPM: getEnvInt("REDIS_RATE_LIMIT_RPM", 60),
 		},
 		MinIO: MinIOConfig{
-			Endpoint:        getEnv("MINIO_ENDPOINT", "localhost:9000"),
-			AccessKeyID:     getEnv("MINIO_ACCESS_KEY", "minioadmin"),
-			SecretAccessKey: getEnv("MINIO_SECRET_KEY", "minioadmin"),
-			BucketName:      getEnv("MINIO_BUCKET", "turbozap-media"),
-			UseSSL:          getEnvBool("MINIO_USE_SSL", false),
+			Enabled:            getEnvBool("MINIO_ENABLED", false),
+			Endpoint:           getEnv("MINIO_ENDPOINT", "localhost:9000"),
+			AccessKeyID:        getEnv("MINIO_ACCESS_KEY", "minioadmin"),
+			SecretAccessKey:    getEnv("MINIO_SECRET_KEY", "minioadmin"),
+			BucketName:         getEnv("MINIO_BUCKET", "turbozap-media"),
+			UseSSL:             getEnvBool("MINIO_USE_SSL", false),
+			PublicURL:          getEnv("MINIO_PUBLIC_URL", ""),
+			PresignedURLExpiry: getEnvInt("MINIO_PRESIGNED_URL_EXPIRY", 86400),
+		},
+		Cluster: ClusterConfig{
+			Enabled:      getEnvBool("CLUSTER_ENABLED", false),
+			NodeID:       getEnv("NODE_ID", defaultNodeID()),
+			AdvertiseURL: strings.TrimSuffix(getEnv("NODE_ADVERTISE_URL", ""), "/"),
+			LeaseTTL:     getEnvInt("INSTANCE_LEASE_TTL", 30),
 		},
 	}
 
@@ -174,6 +223,15 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// defaultNodeID names the node after its host
+func defaultNodeID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "turbozap"
+	}
+	return hostname
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value